@@ -0,0 +1,186 @@
+// Package blob stores uploaded files such as avatars, message images and emoji.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"chat-room/config"
+)
+
+// AvatarPrefix starts the object names of avatars, the only publicly readable objects.
+const AvatarPrefix = "user-"
+
+// ErrNotFound is returned when a requested object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Name         string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// Part is an uploaded part of a multipart upload.
+type Part struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+}
+
+// BlobStore defines operations for storing objects by name.
+type BlobStore interface {
+	// Put stores data as the object, replacing an existing object.
+	Put(ctx context.Context, objectName string, data []byte, contentType string) error
+
+	// Get opens the object for reading. The caller must close it.
+	// Returns ErrNotFound if the object doesn't exist.
+	Get(ctx context.Context, objectName string) (io.ReadCloser, error)
+
+	// Delete removes the object. Removing a missing object is not an error.
+	Delete(ctx context.Context, objectName string) error
+
+	// Stat returns the description of the object.
+	// Returns ErrNotFound if the object doesn't exist.
+	Stat(ctx context.Context, objectName string) (*ObjectInfo, error)
+
+	// List returns the objects whose name starts with prefix, in no
+	// particular order. The content type of listed objects is not set.
+	List(ctx context.Context, prefix string) ([]*ObjectInfo, error)
+
+	// URL returns a URL to read the object. URLs of avatars are located under
+	// the configured MediaPublicURL and don't expire, URLs of other objects
+	// expire after the configured MediaURLTTL.
+	URL(ctx context.Context, objectName string) (string, error)
+
+	// UploadURL returns a URL accepting the object with a PUT request until ttl expires.
+	UploadURL(ctx context.Context, objectName string, ttl time.Duration) (string, error)
+
+	// CreateMultipart starts a multipart upload of the object and returns its ID.
+	// The object is only created once the upload is completed.
+	CreateMultipart(ctx context.Context, objectName, contentType string) (string, error)
+
+	// PutPart uploads a part of a multipart upload. Parts are numbered from 1;
+	// all parts but the last must have at least MinPartSize bytes.
+	PutPart(ctx context.Context, objectName, uploadID string, number int, data []byte) (Part, error)
+
+	// CompleteMultipart creates the object from the parts, in the order of their numbers.
+	CompleteMultipart(ctx context.Context, objectName, uploadID string, parts []Part) error
+
+	// AbortMultipart discards a multipart upload and its parts.
+	AbortMultipart(ctx context.Context, objectName, uploadID string) error
+}
+
+// MinPartSize is the minimum size of the parts of a multipart upload but the last.
+const MinPartSize = 5 << 20
+
+var defaultStore BlobStore
+
+// Initialize creates the blob store selected by the configured BlobBackend
+// and makes it the store used by the package level functions.
+func Initialize(cfg *config.Config) (BlobStore, error) {
+	var err error
+	switch cfg.BlobBackend {
+	case "minio":
+		defaultStore, err = NewMinioStore(cfg)
+	case "local":
+		defaultStore, err = NewLocalStore(cfg.BlobLocalDir, cfg.BlobLocalURL, cfg.MediaPublicURL, []byte(cfg.JWTSecret), cfg.MediaURLTTL)
+	default:
+		err = fmt.Errorf("unknown blob backend %q", cfg.BlobBackend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return defaultStore, nil
+}
+
+// GetStore returns the initialized blob store.
+func GetStore() BlobStore {
+	return defaultStore
+}
+
+// Put stores data as the object in the initialized blob store.
+func Put(ctx context.Context, objectName string, data []byte, contentType string) error {
+	return defaultStore.Put(ctx, objectName, data, contentType)
+}
+
+// Get opens the object of the initialized blob store for reading.
+func Get(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	return defaultStore.Get(ctx, objectName)
+}
+
+// Delete removes the object from the initialized blob store.
+func Delete(ctx context.Context, objectName string) error {
+	return defaultStore.Delete(ctx, objectName)
+}
+
+// Stat describes the object of the initialized blob store.
+func Stat(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	return defaultStore.Stat(ctx, objectName)
+}
+
+// List returns the objects of the initialized blob store starting with prefix.
+func List(ctx context.Context, prefix string) ([]*ObjectInfo, error) {
+	return defaultStore.List(ctx, prefix)
+}
+
+// URL returns a URL to read the object of the initialized blob store.
+func URL(ctx context.Context, objectName string) (string, error) {
+	return defaultStore.URL(ctx, objectName)
+}
+
+// UploadURL returns a URL to upload the object to the initialized blob store.
+func UploadURL(ctx context.Context, objectName string, ttl time.Duration) (string, error) {
+	return defaultStore.UploadURL(ctx, objectName, ttl)
+}
+
+// CreateMultipart starts a multipart upload in the initialized blob store.
+func CreateMultipart(ctx context.Context, objectName, contentType string) (string, error) {
+	return defaultStore.CreateMultipart(ctx, objectName, contentType)
+}
+
+// PutPart uploads a part of a multipart upload to the initialized blob store.
+func PutPart(ctx context.Context, objectName, uploadID string, number int, data []byte) (Part, error) {
+	return defaultStore.PutPart(ctx, objectName, uploadID, number, data)
+}
+
+// CompleteMultipart completes a multipart upload of the initialized blob store.
+func CompleteMultipart(ctx context.Context, objectName, uploadID string, parts []Part) error {
+	return defaultStore.CompleteMultipart(ctx, objectName, uploadID, parts)
+}
+
+// AbortMultipart discards a multipart upload of the initialized blob store.
+func AbortMultipart(ctx context.Context, objectName, uploadID string) error {
+	return defaultStore.AbortMultipart(ctx, objectName, uploadID)
+}
+
+// IsPublic reports whether the object can be read without a signed URL.
+func IsPublic(objectName string) bool {
+	return strings.HasPrefix(objectName, AvatarPrefix)
+}
+
+// ObjectNameFromURL extracts the object name from a public URL of an object
+// in the configured bucket. It returns false if the URL does not point into the bucket.
+func ObjectNameFromURL(url string) (string, bool) {
+	marker := "/" + config.GetConfig().MinioBucketName + "/"
+	idx := strings.Index(url, marker)
+	if idx < 0 {
+		return "", false
+	}
+	objectName := url[idx+len(marker):]
+	return objectName, objectName != ""
+}
+
+// ObjectNameOf returns the object name referenced by the content of an image
+// message, which is the object name itself or, for messages stored while the
+// bucket was public, the public URL of the object.
+func ObjectNameOf(content string) string {
+	if objectName, ok := ObjectNameFromURL(content); ok {
+		return objectName
+	}
+	return content
+}
@@ -0,0 +1,481 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLocalUploadBytes limits the files accepted through upload URLs. Uploads
+// are verified when they are completed, this only protects the disk.
+const maxLocalUploadBytes = 32 << 20
+
+// LocalStore stores objects as files in a directory and serves them itself.
+// URLs of private objects and upload URLs are signed with an HMAC and expire.
+//
+// Object data is kept below objects/ and the content type below meta/, so
+// object names cannot collide with the metadata files. Parts of multipart
+// uploads are kept below multipart/<upload ID>/ until they are completed.
+type LocalStore struct {
+	dir       string
+	baseURL   string
+	publicURL string
+	path      string
+	secret    []byte
+	urlTTL    time.Duration
+}
+
+// localMeta is the metadata stored next to an object.
+type localMeta struct {
+	ContentType string `json:"content_type"`
+}
+
+// NewLocalStore creates a store keeping its files in dir, served under
+// baseURL by the store's ServeHTTP. URLs of public objects are located under
+// publicURL, e.g. a CDN forwarding to baseURL, or baseURL if it is empty.
+func NewLocalStore(dir, baseURL, publicURL string, secret []byte, urlTTL time.Duration) (*LocalStore, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob URL: %w", err)
+	}
+	for _, sub := range []string{"objects", "meta", "multipart"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("error creating blob directory: %w", err)
+		}
+	}
+	if publicURL == "" {
+		publicURL = baseURL
+	}
+	return &LocalStore{
+		dir:       dir,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		path:      strings.TrimSuffix(u.Path, "/"),
+		secret:    secret,
+		urlTTL:    urlTTL,
+	}, nil
+}
+
+// Path returns the URL path under which the store serves its objects.
+func (s *LocalStore) Path() string {
+	return s.path
+}
+
+// validObjectName reports whether the name stays inside the store's directory.
+func validObjectName(objectName string) bool {
+	return objectName != "" &&
+		!strings.Contains(objectName, "\\") &&
+		path.Clean("/"+objectName) == "/"+objectName
+}
+
+func (s *LocalStore) objectPath(objectName string) string {
+	return filepath.Join(s.dir, "objects", filepath.FromSlash(objectName))
+}
+
+func (s *LocalStore) metaPath(objectName string) string {
+	return filepath.Join(s.dir, "meta", filepath.FromSlash(objectName)+".json")
+}
+
+// Put writes the object and its metadata. The data is written to a temporary
+// file first, so readers never see a partial object.
+func (s *LocalStore) Put(ctx context.Context, objectName string, data []byte, contentType string) error {
+	if !validObjectName(objectName) {
+		return fmt.Errorf("invalid object name %q", objectName)
+	}
+	meta, err := json.Marshal(localMeta{ContentType: contentType})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.metaPath(objectName), meta); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.objectPath(objectName), data)
+}
+
+func writeFileAtomic(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Get opens the object's file.
+func (s *LocalStore) Get(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	if !validObjectName(objectName) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(s.objectPath(objectName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Delete removes the object and its metadata.
+func (s *LocalStore) Delete(ctx context.Context, objectName string) error {
+	if !validObjectName(objectName) {
+		return nil
+	}
+	for _, name := range []string{s.objectPath(objectName), s.metaPath(objectName)} {
+		if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stat returns the description of the object.
+func (s *LocalStore) Stat(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	if !validObjectName(objectName) {
+		return nil, ErrNotFound
+	}
+	fi, err := os.Stat(s.objectPath(objectName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var meta localMeta
+	data, err := os.ReadFile(s.metaPath(objectName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ObjectInfo{
+		Name:         objectName,
+		Size:         fi.Size(),
+		ContentType:  meta.ContentType,
+		LastModified: fi.ModTime(),
+	}, nil
+}
+
+// List walks the object files starting with prefix.
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]*ObjectInfo, error) {
+	root := filepath.Join(s.dir, "objects")
+	var objects []*ObjectInfo
+	err := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Temporary files are renamed once they are complete
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		objectName := filepath.ToSlash(rel)
+		if !strings.HasPrefix(objectName, prefix) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, &ObjectInfo{
+			Name:         objectName,
+			Size:         fi.Size(),
+			LastModified: fi.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// multipartDir returns the directory of a multipart upload of the object. The
+// upload ID is checked to be hex, so it cannot point outside the directory.
+func (s *LocalStore) multipartDir(objectName, uploadID string) (string, error) {
+	if !validObjectName(objectName) {
+		return "", fmt.Errorf("invalid object name %q", objectName)
+	}
+	if _, err := hex.DecodeString(uploadID); err != nil || uploadID == "" {
+		return "", ErrNotFound
+	}
+	dir := filepath.Join(s.dir, "multipart", uploadID)
+	data, err := os.ReadFile(filepath.Join(dir, "upload.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	var meta localMultipartMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return "", err
+	}
+	if meta.ObjectName != objectName {
+		return "", ErrNotFound
+	}
+	return dir, nil
+}
+
+// localMultipartMeta is the metadata stored with the parts of an upload.
+type localMultipartMeta struct {
+	ObjectName  string `json:"object_name"`
+	ContentType string `json:"content_type"`
+}
+
+// CreateMultipart creates the directory of a new multipart upload.
+func (s *LocalStore) CreateMultipart(ctx context.Context, objectName, contentType string) (string, error) {
+	if !validObjectName(objectName) {
+		return "", fmt.Errorf("invalid object name %q", objectName)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(id)
+	meta, err := json.Marshal(localMultipartMeta{ObjectName: objectName, ContentType: contentType})
+	if err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(filepath.Join(s.dir, "multipart", uploadID, "upload.json"), meta); err != nil {
+		return "", err
+	}
+	return uploadID, nil
+}
+
+// PutPart writes a part of a multipart upload, replacing an earlier part with
+// the same number. The ETag is the SHA-256 of the data.
+func (s *LocalStore) PutPart(ctx context.Context, objectName, uploadID string, number int, data []byte) (Part, error) {
+	dir, err := s.multipartDir(objectName, uploadID)
+	if err != nil {
+		return Part{}, err
+	}
+	if number < 1 {
+		return Part{}, fmt.Errorf("invalid part number %d", number)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, strconv.Itoa(number)), data); err != nil {
+		return Part{}, err
+	}
+	sum := sha256.Sum256(data)
+	return Part{Number: number, ETag: hex.EncodeToString(sum[:])}, nil
+}
+
+// CompleteMultipart concatenates the parts into the object and removes the
+// upload's directory.
+func (s *LocalStore) CompleteMultipart(ctx context.Context, objectName, uploadID string, parts []Part) error {
+	dir, err := s.multipartDir(objectName, uploadID)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "upload.json"))
+	if err != nil {
+		return err
+	}
+	var meta localMultipartMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return err
+	}
+
+	target := s.objectPath(objectName)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	for _, part := range parts {
+		if err := appendPart(tmp, filepath.Join(dir, strconv.Itoa(part.Number)), part.ETag); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	metaData, err := json.Marshal(localMeta{ContentType: meta.ContentType})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.metaPath(objectName), metaData); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// appendPart copies the part's file to w, checking it against the ETag.
+func appendPart(w io.Writer, name, etag string) error {
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("missing part %s", filepath.Base(name))
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), f); err != nil {
+		return err
+	}
+	if hex.EncodeToString(h.Sum(nil)) != etag {
+		return fmt.Errorf("part %s does not match its ETag", filepath.Base(name))
+	}
+	return nil
+}
+
+// AbortMultipart removes the upload's directory. Unknown uploads are ignored.
+func (s *LocalStore) AbortMultipart(ctx context.Context, objectName, uploadID string) error {
+	dir, err := s.multipartDir(objectName, uploadID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// URL returns the plain URL of avatars and a signed URL of other objects.
+func (s *LocalStore) URL(ctx context.Context, objectName string) (string, error) {
+	if IsPublic(objectName) {
+		return s.publicURL + "/" + objectName, nil
+	}
+	return s.signedURL(http.MethodGet, objectName, s.urlTTL), nil
+}
+
+// UploadURL returns a signed URL to PUT the object.
+func (s *LocalStore) UploadURL(ctx context.Context, objectName string, ttl time.Duration) (string, error) {
+	if !validObjectName(objectName) {
+		return "", fmt.Errorf("invalid object name %q", objectName)
+	}
+	return s.signedURL(http.MethodPut, objectName, ttl), nil
+}
+
+func (s *LocalStore) signedURL(method, objectName string, ttl time.Duration) string {
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(method, objectName, expires))
+	return s.baseURL + "/" + objectName + "?" + query.Encode()
+}
+
+func (s *LocalStore) sign(method, objectName, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(method + "\n" + objectName + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature and expiry of a request for the object.
+func (s *LocalStore) verify(r *http.Request, method, objectName string) bool {
+	query := r.URL.Query()
+	expires := query.Get("expires")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+	signature, err := hex.DecodeString(query.Get("signature"))
+	if err != nil {
+		return false
+	}
+	expected, _ := hex.DecodeString(s.sign(method, objectName, expires))
+	return hmac.Equal(signature, expected)
+}
+
+// ServeHTTP serves the objects for GET and HEAD requests and accepts uploads
+// for PUT requests to the URLs issued by the store.
+func (s *LocalStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	objectName := strings.TrimPrefix(r.URL.Path, s.path+"/")
+	if !validObjectName(objectName) {
+		http.Error(w, "Object not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if !IsPublic(objectName) && !s.verify(r, http.MethodGet, objectName) {
+			http.Error(w, "Invalid or expired signature", http.StatusForbidden)
+			return
+		}
+		s.serveObject(w, r, objectName)
+	case http.MethodPut:
+		if !s.verify(r, http.MethodPut, objectName) {
+			http.Error(w, "Invalid or expired signature", http.StatusForbidden)
+			return
+		}
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLocalUploadBytes))
+		if err != nil {
+			http.Error(w, "File is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := s.Put(r.Context(), objectName, data, r.Header.Get("Content-Type")); err != nil {
+			http.Error(w, "Failed to store file", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *LocalStore) serveObject(w http.ResponseWriter, r *http.Request, objectName string) {
+	info, err := s.Stat(r.Context(), objectName)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "Object not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to read object", http.StatusInternalServerError)
+		return
+	}
+	f, err := os.Open(s.objectPath(objectName))
+	if err != nil {
+		http.Error(w, "Failed to read object", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	if info.ContentType != "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.LastModified, f)
+}
@@ -0,0 +1,209 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLocalStore(t *testing.T) *LocalStore {
+	t.Helper()
+	s, err := NewLocalStore(t.TempDir(), "http://example.com/blobs", "", []byte("secret"), time.Minute)
+	require.NoError(t, err)
+	return s
+}
+
+// serve requests a URL issued by the store from its handler.
+func serve(s *LocalStore, method, rawURL string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, strings.TrimPrefix(rawURL, "http://example.com"), bytes.NewReader(body))
+	req.Header.Set("Content-Type", "image/png")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLocalStoreObjects(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+
+	require.NoError(t, s.Put(ctx, "messages/a.png", []byte("data"), "image/png"))
+
+	info, err := s.Stat(ctx, "messages/a.png")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), info.Size)
+	assert.Equal(t, "image/png", info.ContentType)
+
+	r, err := s.Get(ctx, "messages/a.png")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	require.NoError(t, s.Delete(ctx, "messages/a.png"))
+	_, err = s.Stat(ctx, "messages/a.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Get(ctx, "messages/a.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, s.Delete(ctx, "messages/a.png"))
+}
+
+func TestLocalStoreRejectsEscapingNames(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+
+	for _, name := range []string{"", "../x", "a/../../x", "/abs", "a//b", `a\b`} {
+		assert.Error(t, s.Put(ctx, name, []byte("x"), "text/plain"), name)
+		_, err := s.Get(ctx, name)
+		assert.ErrorIs(t, err, ErrNotFound, name)
+	}
+}
+
+func TestLocalStoreServesSignedURLs(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+	require.NoError(t, s.Put(ctx, "messages/a.png", []byte("data"), "image/png"))
+	require.NoError(t, s.Put(ctx, AvatarPrefix+"1/a.png", []byte("avatar"), "image/png"))
+
+	url, err := s.URL(ctx, "messages/a.png")
+	require.NoError(t, err)
+	rec := serve(s, http.MethodGet, url, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "data", rec.Body.String())
+	assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+
+	// Private objects need a signature, avatars don't
+	rec = serve(s, http.MethodGet, "/blobs/messages/a.png", nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = serve(s, http.MethodGet, strings.Replace(url, "a.png", "b.png", 1), nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	url, err = s.URL(ctx, AvatarPrefix+"1/a.png")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/blobs/"+AvatarPrefix+"1/a.png", url)
+	rec = serve(s, http.MethodGet, url, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "avatar", rec.Body.String())
+}
+
+func TestLocalStoreAcceptsSignedUploads(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+
+	url, err := s.UploadURL(ctx, "uploads/1", time.Minute)
+	require.NoError(t, err)
+	rec := serve(s, http.MethodPut, url, []byte("upload"))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	info, err := s.Stat(ctx, "uploads/1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), info.Size)
+	assert.Equal(t, "image/png", info.ContentType)
+
+	// A read URL does not allow uploads
+	url, err = s.URL(ctx, "uploads/1")
+	require.NoError(t, err)
+	rec = serve(s, http.MethodPut, url, []byte("other"))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Expired upload URLs are rejected
+	url, err = s.UploadURL(ctx, "uploads/2", -time.Minute)
+	require.NoError(t, err)
+	rec = serve(s, http.MethodPut, url, []byte("upload"))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestLocalStorePublicURL(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStore(t.TempDir(), "http://example.com/blobs", "https://cdn.example.com/media/", []byte("secret"), time.Minute)
+	require.NoError(t, err)
+
+	url, err := s.URL(ctx, AvatarPrefix+"1/a.png")
+	require.NoError(t, err)
+	assert.Equal(t, "https://cdn.example.com/media/"+AvatarPrefix+"1/a.png", url)
+
+	// Signed URLs are verified by the store itself
+	url, err = s.URL(ctx, "messages/a.png")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(url, "http://example.com/blobs/messages/a.png?"))
+}
+
+func TestLocalStoreList(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+	require.NoError(t, s.Put(ctx, "messages/a.png", []byte("a"), "image/png"))
+	require.NoError(t, s.Put(ctx, "messages/b.png", []byte("bb"), "image/png"))
+	require.NoError(t, s.Put(ctx, "emojis/s/c.png", []byte("c"), "image/png"))
+
+	objects, err := s.List(ctx, "messages/")
+	require.NoError(t, err)
+	sizes := map[string]int64{}
+	for _, object := range objects {
+		sizes[object.Name] = object.Size
+	}
+	assert.Equal(t, map[string]int64{"messages/a.png": 1, "messages/b.png": 2}, sizes)
+}
+
+func TestLocalStoreMultipart(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+
+	uploadID, err := s.CreateMultipart(ctx, "files/a", "application/zip")
+	require.NoError(t, err)
+
+	// Parts may be uploaded again, e.g. after a failed request
+	second, err := s.PutPart(ctx, "files/a", uploadID, 2, []byte("wrong"))
+	require.NoError(t, err)
+	second, err = s.PutPart(ctx, "files/a", uploadID, 2, []byte("world"))
+	require.NoError(t, err)
+	first, err := s.PutPart(ctx, "files/a", uploadID, 1, []byte("hello "))
+	require.NoError(t, err)
+
+	_, err = s.PutPart(ctx, "files/b", uploadID, 3, []byte("x"))
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Stat(ctx, "files/a")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, s.CompleteMultipart(ctx, "files/a", uploadID, []Part{first, second}))
+
+	info, err := s.Stat(ctx, "files/a")
+	require.NoError(t, err)
+	assert.Equal(t, "application/zip", info.ContentType)
+	r, err := s.Get(ctx, "files/a")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(data))
+
+	_, err = s.PutPart(ctx, "files/a", uploadID, 3, []byte("x"))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestLocalStoreAbortMultipart(t *testing.T) {
+	ctx := context.Background()
+	s := newTestLocalStore(t)
+
+	uploadID, err := s.CreateMultipart(ctx, "files/a", "application/zip")
+	require.NoError(t, err)
+	part, err := s.PutPart(ctx, "files/a", uploadID, 1, []byte("data"))
+	require.NoError(t, err)
+
+	// A part that changed since it was uploaded is rejected
+	bad := part
+	bad.ETag = "00"
+	assert.Error(t, s.CompleteMultipart(ctx, "files/a", uploadID, []Part{bad}))
+
+	require.NoError(t, s.AbortMultipart(ctx, "files/a", uploadID))
+	assert.ErrorIs(t, s.CompleteMultipart(ctx, "files/a", uploadID, []Part{part}), ErrNotFound)
+	assert.NoError(t, s.AbortMultipart(ctx, "files/a", uploadID))
+	assert.NoError(t, s.AbortMultipart(ctx, "files/a", "../objects"))
+}
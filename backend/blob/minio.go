@@ -0,0 +1,216 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"chat-room/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// MinioStore stores objects in a MinIO bucket.
+type MinioStore struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+	urlTTL    time.Duration
+}
+
+// NewMinioStore connects to the configured MinIO server and creates the
+// bucket if it doesn't exist.
+func NewMinioStore(cfg *config.Config) (*MinioStore, error) {
+	client, err := minio.New(cfg.MinioEndpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.MinioAccessKey, cfg.MinioSecretKey, ""),
+		Secure: cfg.MinioUseSSL,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MinIO client: %w", err)
+	}
+
+	// Without a public media URL, avatars are read from MinIO directly
+	publicURL := cfg.MediaPublicURL
+	if publicURL == "" {
+		scheme := "http"
+		if cfg.MinioUseSSL {
+			scheme = "https"
+		}
+		publicURL = fmt.Sprintf("%s://%s/%s", scheme, cfg.MinioEndpoint, cfg.MinioBucketName)
+	}
+
+	s := &MinioStore{
+		client:    client,
+		bucket:    cfg.MinioBucketName,
+		publicURL: strings.TrimSuffix(publicURL, "/"),
+		urlTTL:    cfg.MediaURLTTL,
+	}
+
+	// Create bucket if it doesn't exist
+	err = s.createBucketIfNotExists(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error creating bucket: %v", err)
+	}
+
+	return s, nil
+}
+
+func (s *MinioStore) createBucketIfNotExists(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return fmt.Errorf("error checking bucket existence: %v", err)
+	}
+
+	if !exists {
+		err = s.client.MakeBucket(ctx, s.bucket, minio.MakeBucketOptions{})
+		if err != nil {
+			return fmt.Errorf("error creating bucket: %v", err)
+		}
+	}
+
+	// Only avatars are publicly readable; message images and emoji are read
+	// through presigned URLs. The policy is also applied to existing buckets,
+	// which used to be readable as a whole.
+	policy := `{
+		"Version": "2012-10-17",
+		"Statement": [
+			{
+				"Effect": "Allow",
+				"Principal": {"AWS": ["*"]},
+				"Action": ["s3:GetObject"],
+				"Resource": ["arn:aws:s3:::` + s.bucket + `/` + AvatarPrefix + `*"]
+			}
+		]
+	}`
+
+	err = s.client.SetBucketPolicy(ctx, s.bucket, policy)
+	if err != nil {
+		return fmt.Errorf("error setting bucket policy: %v", err)
+	}
+
+	return nil
+}
+
+// Put uploads data to the bucket.
+func (s *MinioStore) Put(ctx context.Context, objectName string, data []byte, contentType string) error {
+	opts := minio.PutObjectOptions{
+		ContentType: contentType,
+	}
+	_, err := s.client.PutObject(ctx, s.bucket, objectName, bytes.NewReader(data), int64(len(data)), opts)
+	return err
+}
+
+// Get opens the object for reading.
+func (s *MinioStore) Get(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, mapMinioError(err)
+	}
+	// GetObject is lazy, a missing object is only reported once it is accessed
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, mapMinioError(err)
+	}
+	return object, nil
+}
+
+// Delete removes the object from the bucket.
+func (s *MinioStore) Delete(ctx context.Context, objectName string) error {
+	return s.client.RemoveObject(ctx, s.bucket, objectName, minio.RemoveObjectOptions{})
+}
+
+// Stat returns the description of the object.
+func (s *MinioStore) Stat(ctx context.Context, objectName string) (*ObjectInfo, error) {
+	stat, err := s.client.StatObject(ctx, s.bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, mapMinioError(err)
+	}
+	return &ObjectInfo{
+		Name:         objectName,
+		Size:         stat.Size,
+		ContentType:  stat.ContentType,
+		LastModified: stat.LastModified,
+	}, nil
+}
+
+// List returns the objects of the bucket starting with prefix.
+func (s *MinioStore) List(ctx context.Context, prefix string) ([]*ObjectInfo, error) {
+	var objects []*ObjectInfo
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		objects = append(objects, &ObjectInfo{
+			Name:         object.Key,
+			Size:         object.Size,
+			LastModified: object.LastModified,
+		})
+	}
+	return objects, nil
+}
+
+// URL returns the public URL of avatars and a presigned URL of other objects.
+func (s *MinioStore) URL(ctx context.Context, objectName string) (string, error) {
+	if IsPublic(objectName) {
+		return s.publicURL + "/" + objectName, nil
+	}
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, objectName, s.urlTTL, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// UploadURL returns a presigned URL to PUT the object.
+func (s *MinioStore) UploadURL(ctx context.Context, objectName string, ttl time.Duration) (string, error) {
+	u, err := s.client.PresignedPutObject(ctx, s.bucket, objectName, ttl)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// CreateMultipart starts a multipart upload in the bucket.
+func (s *MinioStore) CreateMultipart(ctx context.Context, objectName, contentType string) (string, error) {
+	core := minio.Core{Client: s.client}
+	return core.NewMultipartUpload(ctx, s.bucket, objectName, minio.PutObjectOptions{ContentType: contentType})
+}
+
+// PutPart uploads a part of a multipart upload.
+func (s *MinioStore) PutPart(ctx context.Context, objectName, uploadID string, number int, data []byte) (Part, error) {
+	core := minio.Core{Client: s.client}
+	part, err := core.PutObjectPart(ctx, s.bucket, objectName, uploadID, number, bytes.NewReader(data), int64(len(data)), minio.PutObjectPartOptions{})
+	if err != nil {
+		return Part{}, err
+	}
+	return Part{Number: number, ETag: part.ETag}, nil
+}
+
+// CompleteMultipart creates the object from the uploaded parts.
+func (s *MinioStore) CompleteMultipart(ctx context.Context, objectName, uploadID string, parts []Part) error {
+	core := minio.Core{Client: s.client}
+	completeParts := make([]minio.CompletePart, len(parts))
+	for i, part := range parts {
+		completeParts[i] = minio.CompletePart{PartNumber: part.Number, ETag: part.ETag}
+	}
+	_, err := core.CompleteMultipartUpload(ctx, s.bucket, objectName, uploadID, completeParts, minio.PutObjectOptions{})
+	return err
+}
+
+// AbortMultipart discards a multipart upload.
+func (s *MinioStore) AbortMultipart(ctx context.Context, objectName, uploadID string) error {
+	core := minio.Core{Client: s.client}
+	return core.AbortMultipartUpload(ctx, s.bucket, objectName, uploadID)
+}
+
+// mapMinioError translates missing objects into ErrNotFound.
+func mapMinioError(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+	return err
+}
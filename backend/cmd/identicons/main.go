@@ -0,0 +1,96 @@
+// Command identicons backfills the identicon avatars of users created before
+// identicons were stored on signup.
+//
+// Usage:
+//
+//	identicons [-batch 100]
+//
+// Users whose identicon cannot be stored are reported and skipped. The
+// command can be interrupted and run again; it resumes with the users still
+// lacking an avatar.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"chat-room/blob"
+	"chat-room/config"
+	"chat-room/media"
+	"chat-room/store/cache"
+	"chat-room/store/postgres"
+
+	"github.com/google/uuid"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 100, "number of users loaded at once")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBHost,
+		cfg.DBPort,
+		cfg.DBName,
+	)
+
+	ctx := context.Background()
+
+	pgStore, err := postgres.New(ctx, dbURL)
+	if err != nil {
+		log.Fatal("Failed to initialize PostgreSQL store:", err)
+	}
+	defer pgStore.Close()
+
+	if err := pgStore.Migrate(ctx); err != nil {
+		log.Fatal("Failed to apply migrations:", err)
+	}
+
+	// Write through the cache layer so cached users stay valid
+	store, err := cache.New(cfg, pgStore)
+	if err != nil {
+		log.Fatal("Failed to initialize Redis cache:", err)
+	}
+	defer store.Close()
+
+	if _, err := blob.Initialize(cfg); err != nil {
+		log.Fatal("Failed to initialize blob storage:", err)
+	}
+
+	var processed, skipped int
+	afterID := uuid.Nil
+	for {
+		ids, err := store.GetUserIDsWithoutAvatar(ctx, afterID, *batchSize)
+		if err != nil {
+			log.Fatal("Failed to load users:", err)
+		}
+		if len(ids) == 0 {
+			break
+		}
+		afterID = ids[len(ids)-1]
+
+		for _, id := range ids {
+			objectName, err := media.StoreIdenticon(ctx, blob.AvatarPrefix, id, blob.Put)
+			if err == nil {
+				// An avatar uploaded meanwhile is kept
+				err = store.SetDefaultAvatar(ctx, id, objectName)
+			}
+			if err != nil {
+				log.Printf("Skipping user %s: %v", id, err)
+				skipped++
+				continue
+			}
+			processed++
+		}
+	}
+
+	fmt.Printf("users: %d backfilled, %d skipped\n", processed, skipped)
+}
@@ -0,0 +1,155 @@
+// Command imagevariants backfills the thumbnail and medium variants of image
+// messages uploaded before variants were generated on upload.
+//
+// Usage:
+//
+//	imagevariants [-batch 100]
+//
+// Messages whose image cannot be read or decoded are reported and skipped.
+// The command can be interrupted and run again; it resumes with the messages
+// still lacking variants.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+
+	"chat-room/blob"
+	"chat-room/config"
+	"chat-room/media"
+	"chat-room/models"
+	"chat-room/store"
+	"chat-room/store/cache"
+	"chat-room/store/postgres"
+
+	"github.com/google/uuid"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 100, "number of messages loaded at once")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBHost,
+		cfg.DBPort,
+		cfg.DBName,
+	)
+
+	ctx := context.Background()
+
+	pgStore, err := postgres.New(ctx, dbURL)
+	if err != nil {
+		log.Fatal("Failed to initialize PostgreSQL store:", err)
+	}
+	defer pgStore.Close()
+
+	if err := pgStore.Migrate(ctx); err != nil {
+		log.Fatal("Failed to apply migrations:", err)
+	}
+
+	// Write through the cache layer so cached messages stay valid
+	store, err := cache.New(cfg, pgStore)
+	if err != nil {
+		log.Fatal("Failed to initialize Redis cache:", err)
+	}
+	defer store.Close()
+
+	if _, err := blob.Initialize(cfg); err != nil {
+		log.Fatal("Failed to initialize blob storage:", err)
+	}
+
+	var processed, skipped int
+	afterID := uuid.Nil
+	for {
+		messages, err := store.GetImageMessagesWithoutMedia(ctx, afterID, *batchSize)
+		if err != nil {
+			log.Fatal("Failed to load messages:", err)
+		}
+		if len(messages) == 0 {
+			break
+		}
+		afterID = messages[len(messages)-1].ID
+
+		for _, message := range messages {
+			if err := backfill(ctx, store, message, cfg); err != nil {
+				log.Printf("Skipping message %s: %v", message.ID, err)
+				skipped++
+				continue
+			}
+			processed++
+		}
+	}
+
+	fmt.Printf("messages: %d backfilled, %d skipped\n", processed, skipped)
+}
+
+// backfill stores the variants of the image of message and records them.
+func backfill(ctx context.Context, st store.Store, message *models.Message, cfg *config.Config) error {
+	objectName := blob.ObjectNameOf(message.Content)
+	object, err := blob.Get(ctx, objectName)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return err
+	}
+
+	info, err := media.Inspect(data, media.Limits{
+		MaxDimension: cfg.MediaMaxImageDimension,
+		MaxPixels:    cfg.MediaMaxImagePixels,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The variants count towards the storage usage of the message's author and session
+	put := func(ctx context.Context, key string, data []byte, contentType string) error {
+		if err := blob.Put(ctx, key, data, contentType); err != nil {
+			return err
+		}
+		return st.CreateMediaObject(ctx, &models.MediaObject{
+			ObjectName: key,
+			UserID:     message.UserID,
+			SessionID:  &message.SessionID,
+			Size:       int64(len(data)),
+		})
+	}
+	messageMedia, err := info.StoreVariants(ctx, objectName, put)
+	if err == nil {
+		err = st.UpdateMessageMedia(ctx, message.ID, messageMedia)
+	}
+	if err != nil && messageMedia != nil {
+		deleteVariants(ctx, st, messageMedia.Variants)
+	}
+	return err
+}
+
+// deleteVariants removes the stored variants of a message that could not be
+// backfilled, so they do not count towards the storage usage.
+func deleteVariants(ctx context.Context, st store.Store, variants []models.ImageVariant) {
+	var keys []string
+	for _, variant := range variants {
+		if err := blob.Delete(ctx, variant.Key); err != nil {
+			log.Printf("Failed to remove variant %s: %v", variant.Key, err)
+		}
+		keys = append(keys, variant.Key)
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := st.DeleteMediaObjects(ctx, keys); err != nil {
+		log.Printf("Failed to remove storage records of %d variants: %v", len(keys), err)
+	}
+}
@@ -0,0 +1,82 @@
+// Command mediagc deletes the media objects no longer referenced by any user,
+// message, custom emoji or pending upload, like the collector run by the
+// backend every MEDIA_GC_INTERVAL.
+//
+// Usage:
+//
+//	mediagc [-dry-run] [-grace 24h]
+//
+// A dry run lists the objects that would be deleted without deleting anything.
+// Objects younger than the grace period are kept.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"chat-room/blob"
+	"chat-room/config"
+	"chat-room/mediagc"
+	"chat-room/store/postgres"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only list the objects that would be deleted")
+	grace := flag.Duration("grace", 0, "minimum age of deleted objects (default MEDIA_GC_GRACE_PERIOD)")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *grace <= 0 {
+		*grace = cfg.MediaGCGracePeriod
+	}
+
+	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBHost,
+		cfg.DBPort,
+		cfg.DBName,
+	)
+
+	ctx := context.Background()
+
+	pgStore, err := postgres.New(ctx, dbURL)
+	if err != nil {
+		log.Fatal("Failed to initialize PostgreSQL store:", err)
+	}
+	defer pgStore.Close()
+
+	if err := pgStore.Migrate(ctx); err != nil {
+		log.Fatal("Failed to apply migrations:", err)
+	}
+
+	blobStore, err := blob.Initialize(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize blob storage:", err)
+	}
+
+	collector := mediagc.NewCollector(pgStore, blobStore, mediagc.Options{GracePeriod: *grace})
+	report, err := collector.Collect(ctx, *dryRun)
+	if err != nil {
+		log.Fatal("Failed to collect orphaned media:", err)
+	}
+
+	for _, object := range report.Orphans {
+		fmt.Printf("%s\t%d\t%s\n", object.LastModified.Format("2006-01-02T15:04:05Z07:00"), object.Size, object.Name)
+	}
+	verb := "deleted"
+	if report.DryRun {
+		verb = "would be deleted"
+	}
+	fmt.Printf("objects: %d scanned, %d referenced, %d within grace period, %d %s (%d failed)\n",
+		report.Scanned, report.Referenced, report.Recent, len(report.Orphans), verb, report.Failed)
+	fmt.Printf("reclaimed: %d bytes\n", report.ReclaimedBytes)
+	if !report.DryRun {
+		fmt.Printf("uploads: %d expired records removed\n", report.ExpiredUploads)
+	}
+}
@@ -0,0 +1,127 @@
+// Command slackimport imports a Slack workspace export into the chat room.
+//
+// Usage:
+//
+//	slackimport -archive export.zip [-owner username] [-accounts accounts.json]
+//
+// Slack users are merged into existing accounts only when the accounts file,
+// a JSON object mapping Slack user IDs to usernames, lists them; the others
+// are created as placeholder users that cannot log in, with their identicon
+// as avatar. Every channel becomes a session. The import can be run again on
+// the same export without creating duplicates.
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"chat-room/blob"
+	"chat-room/config"
+	"chat-room/models"
+	"chat-room/slackimport"
+	"chat-room/store/cache"
+	"chat-room/store/postgres"
+)
+
+func main() {
+	archivePath := flag.String("archive", "", "path of the Slack export zip file")
+	ownerName := flag.String("owner", "", "username owning channels whose Slack creator is unknown")
+	accountsPath := flag.String("accounts", "", "path of a JSON file mapping Slack user IDs to the usernames of existing accounts")
+	flag.Parse()
+
+	if *archivePath == "" {
+		flag.Usage()
+		log.Fatal("-archive is required")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dbURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBHost,
+		cfg.DBPort,
+		cfg.DBName,
+	)
+
+	ctx := context.Background()
+
+	pgStore, err := postgres.New(ctx, dbURL)
+	if err != nil {
+		log.Fatal("Failed to initialize PostgreSQL store:", err)
+	}
+	defer pgStore.Close()
+
+	if err := pgStore.Migrate(ctx); err != nil {
+		log.Fatal("Failed to apply migrations:", err)
+	}
+
+	// Write through the cache layer so cached sessions and members stay valid
+	store, err := cache.New(cfg, pgStore)
+	if err != nil {
+		log.Fatal("Failed to initialize Redis cache:", err)
+	}
+	defer store.Close()
+
+	// Placeholder users get their identicon as avatar
+	if _, err := blob.Initialize(cfg); err != nil {
+		log.Fatal("Failed to initialize blob storage:", err)
+	}
+
+	var owner *models.User
+	if *ownerName != "" {
+		if owner, err = store.GetUserByUsername(ctx, *ownerName); err != nil {
+			log.Fatalf("Failed to find owner %q: %v", *ownerName, err)
+		}
+	}
+
+	var accounts map[string]string
+	if *accountsPath != "" {
+		if accounts, err = readAccounts(*accountsPath); err != nil {
+			log.Fatal("Failed to read accounts file:", err)
+		}
+	}
+
+	zr, err := zip.OpenReader(*archivePath)
+	if err != nil {
+		log.Fatal("Failed to open archive:", err)
+	}
+	defer zr.Close()
+
+	archive, err := slackimport.Open(&zr.Reader)
+	if err != nil {
+		log.Fatal("Failed to read archive:", err)
+	}
+
+	stats, err := slackimport.NewImporter(store, owner, accounts, blob.Put).Import(ctx, archive)
+	if err != nil {
+		log.Fatal("Import failed:", err)
+	}
+
+	fmt.Printf("users:       %d matched, %d created\n", stats.UsersMatched, stats.UsersCreated)
+	fmt.Printf("sessions:    %d created, %d already imported\n", stats.SessionsCreated, stats.SessionsExisting)
+	fmt.Printf("memberships: %d added\n", stats.MembershipsAdded)
+	fmt.Printf("messages:    %d imported, %d already imported, %d skipped\n",
+		stats.MessagesImported, stats.MessagesExisting, stats.MessagesSkipped)
+}
+
+// readAccounts reads a JSON object mapping Slack user IDs to usernames.
+func readAccounts(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var accounts map[string]string
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -18,6 +21,14 @@ type Config struct {
 	// Server configuration
 	Port string
 
+	// Blob storage configuration
+	// BlobBackend selects where files are stored: "minio" or "local".
+	BlobBackend string
+	// BlobLocalDir is the directory of the local backend.
+	BlobLocalDir string
+	// BlobLocalURL is the URL under which the backend serves the files of the local backend.
+	BlobLocalURL string
+
 	// MinIO configuration
 	MinioEndpoint   string
 	MinioAccessKey  string
@@ -30,6 +41,47 @@ type Config struct {
 	RedisPort     string
 	RedisPassword string
 	RedisDB       int
+
+	// Outgoing webhook configuration
+	WebhookMaxAttempts    int
+	WebhookRequestTimeout time.Duration
+	// WebhookAllowInternal permits outgoing webhooks to loopback, private and
+	// link-local addresses, for receivers of development setups.
+	WebhookAllowInternal bool
+
+	// Moderation configuration
+	ModerationMaxMessageLength int
+
+	// Custom emoji configuration
+	EmojiMaxPerSession int
+	EmojiMaxBytes      int
+
+	// Uploaded image configuration
+	MediaMaxImageDimension int
+	MediaMaxImagePixels    int
+	// MediaPreserveMetadataSessions lists the IDs of trusted sessions whose
+	// message images keep their EXIF and other metadata.
+	MediaPreserveMetadataSessions []string
+	// MediaURLTTL is the lifetime of the presigned URLs of message images and emoji.
+	MediaURLTTL time.Duration
+	// MediaUploadTTL is how long a direct upload slot accepts the file.
+	MediaUploadTTL time.Duration
+	// MediaResumableTTL is how long a resumable upload accepts chunks.
+	MediaResumableTTL time.Duration
+	// MediaMaxFileBytes limits the size of files shared in messages.
+	MediaMaxFileBytes int64
+	// MediaPublicURL is the origin and path prefix, e.g. of a CDN, under which
+	// the public objects of the blob store are served. Defaults to the blob
+	// backend's own URL.
+	MediaPublicURL string
+	// MediaGCInterval is how often orphaned media is deleted; 0 disables the collector.
+	MediaGCInterval time.Duration
+	// MediaGCGracePeriod is the minimum age of deleted orphaned media.
+	MediaGCGracePeriod time.Duration
+
+	// Storage quota configuration, in bytes; 0 disables a quota
+	QuotaUserBytes    int64
+	QuotaSessionBytes int64
 }
 
 var globalConfig *Config
@@ -53,6 +105,11 @@ func LoadConfig() (*Config, error) {
 		// Server configuration
 		Port: getEnv("PORT", "8080"),
 
+		// Blob storage configuration
+		BlobBackend:  getEnv("BLOB_BACKEND", "minio"),
+		BlobLocalDir: getEnv("BLOB_LOCAL_DIR", "data/blobs"),
+		BlobLocalURL: getEnv("BLOB_LOCAL_URL", "http://localhost:8080/blobs"),
+
 		// MinIO configuration
 		MinioEndpoint:   getEnv("MINIO_ENDPOINT", "localhost:9000"),
 		MinioAccessKey:  getEnv("MINIO_ACCESS_KEY", "minioadmin"),
@@ -65,6 +122,34 @@ func LoadConfig() (*Config, error) {
 		RedisPort:     getEnv("REDIS_PORT", "6379"),
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisDB:       0,
+
+		// Outgoing webhook configuration
+		WebhookMaxAttempts:    getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRequestTimeout: getEnvDuration("WEBHOOK_REQUEST_TIMEOUT", 10*time.Second),
+		WebhookAllowInternal:  getEnv("WEBHOOK_ALLOW_INTERNAL", "false") == "true",
+
+		// Moderation configuration
+		ModerationMaxMessageLength: getEnvInt("MODERATION_MAX_MESSAGE_LENGTH", 4000),
+
+		// Custom emoji configuration
+		EmojiMaxPerSession: getEnvInt("EMOJI_MAX_PER_SESSION", 100),
+		EmojiMaxBytes:      getEnvInt("EMOJI_MAX_BYTES", 256<<10),
+
+		// Uploaded image configuration
+		MediaMaxImageDimension:        getEnvInt("MEDIA_MAX_IMAGE_DIMENSION", 8192),
+		MediaMaxImagePixels:           getEnvInt("MEDIA_MAX_IMAGE_PIXELS", 40_000_000),
+		MediaPreserveMetadataSessions: getEnvList("MEDIA_PRESERVE_METADATA_SESSIONS"),
+		MediaURLTTL:                   getEnvDuration("MEDIA_URL_TTL", 15*time.Minute),
+		MediaUploadTTL:                getEnvDuration("MEDIA_UPLOAD_TTL", 15*time.Minute),
+		MediaResumableTTL:             getEnvDuration("MEDIA_RESUMABLE_TTL", 24*time.Hour),
+		MediaMaxFileBytes:             int64(getEnvInt("MEDIA_MAX_FILE_BYTES", 100<<20)),
+		MediaPublicURL:                getEnv("MEDIA_PUBLIC_URL", ""),
+		MediaGCInterval:               getEnvDuration("MEDIA_GC_INTERVAL", 24*time.Hour),
+		MediaGCGracePeriod:            getEnvDuration("MEDIA_GC_GRACE_PERIOD", 24*time.Hour),
+
+		// Storage quota configuration
+		QuotaUserBytes:    int64(getEnvInt("QUOTA_USER_BYTES", 100<<20)),
+		QuotaSessionBytes: int64(getEnvInt("QUOTA_SESSION_BYTES", 1<<30)),
 	}
 
 	return globalConfig, nil
@@ -78,6 +163,33 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvList returns the non-empty entries of a comma separated variable.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func SetConfig(cfg *Config) {
 	globalConfig = cfg
 }
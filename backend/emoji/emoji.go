@@ -0,0 +1,107 @@
+// Package emoji resolves the :shortcode: references of custom emoji in messages.
+package emoji
+
+import (
+	"errors"
+	"regexp"
+	"unicode/utf8"
+
+	"chat-room/models"
+)
+
+// Shortcodes are lowercase letters, digits, '_', '+' and '-'.
+const (
+	MinShortcodeLength = 2
+	MaxShortcodeLength = 32
+)
+
+var (
+	shortcodePattern = regexp.MustCompile(`^[a-z0-9_+-]+$`)
+	referencePattern = regexp.MustCompile(`:([a-z0-9_+-]{2,32}):`)
+)
+
+// ValidateShortcode checks a shortcode, given without colons.
+func ValidateShortcode(shortcode string) error {
+	if len(shortcode) < MinShortcodeLength || len(shortcode) > MaxShortcodeLength {
+		return errors.New("shortcode must be between 2 and 32 characters long")
+	}
+	if !shortcodePattern.MatchString(shortcode) {
+		return errors.New("shortcode may only contain lowercase letters, digits, '_', '+' and '-'")
+	}
+	return nil
+}
+
+// Catalog maps shortcodes to the custom emoji of a session.
+type Catalog map[string]*models.CustomEmoji
+
+// NewCatalog indexes emojis by shortcode.
+func NewCatalog(emojis []*models.CustomEmoji) Catalog {
+	catalog := make(Catalog, len(emojis))
+	for _, e := range emojis {
+		catalog[e.Shortcode] = e
+	}
+	return catalog
+}
+
+// Entities returns an entity for every :shortcode: of content that names an
+// emoji of the catalog, in order of appearance.
+func (c Catalog) Entities(content string) []models.MessageEntity {
+	if len(c) == 0 {
+		return nil
+	}
+
+	var entities []models.MessageEntity
+	// Offsets are converted incrementally from bytes to UTF-16 code units
+	scanned, units := 0, 0
+	advance := func(to int) int {
+		for scanned < to {
+			// Invalid bytes decode to U+FFFD, as they are encoded in JSON
+			r, size := utf8.DecodeRuneInString(content[scanned:])
+			if r > 0xFFFF {
+				units += 2 // surrogate pair
+			} else {
+				units++
+			}
+			scanned += size
+		}
+		return units
+	}
+
+	for start := 0; start < len(content); {
+		loc := referencePattern.FindStringSubmatchIndex(content[start:])
+		if loc == nil {
+			break
+		}
+		matchStart, matchEnd := start+loc[0], start+loc[1]
+		shortcode := content[start+loc[2] : start+loc[3]]
+
+		e, ok := c[shortcode]
+		if !ok {
+			// The closing colon may open the next reference, as in ":a::smile:"
+			start = matchEnd - 1
+			continue
+		}
+
+		offset := advance(matchStart)
+		id := e.ID
+		entities = append(entities, models.MessageEntity{
+			Type:      models.EntityTypeCustomEmoji,
+			Offset:    offset,
+			Length:    advance(matchEnd) - offset,
+			Shortcode: shortcode,
+			EmojiID:   &id,
+			URL:       e.URL,
+		})
+		start = matchEnd
+	}
+	return entities
+}
+
+// Annotate sets the entities of the text messages.
+func (c Catalog) Annotate(messages ...*models.Message) {
+	for _, message := range messages {
+		if message.Type == models.MessageTypeText {
+			message.Entities = c.Entities(message.Content)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package emoji
+
+import (
+	"testing"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateShortcode(t *testing.T) {
+	assert.NoError(t, ValidateShortcode("party_parrot"))
+	assert.NoError(t, ValidateShortcode("+1"))
+	assert.Error(t, ValidateShortcode("a"))
+	assert.Error(t, ValidateShortcode("PartyParrot"))
+	assert.Error(t, ValidateShortcode("party parrot"))
+	assert.Error(t, ValidateShortcode(":parrot:"))
+}
+
+func TestEntities(t *testing.T) {
+	parrot := &models.CustomEmoji{ID: uuid.New(), Shortcode: "parrot", URL: "http://cdn/parrot.gif"}
+	catalog := NewCatalog([]*models.CustomEmoji{parrot})
+
+	entities := catalog.Entities("hi :parrot: and :unknown::parrot:")
+	require.Len(t, entities, 2)
+	assert.Equal(t, models.MessageEntity{
+		Type:      models.EntityTypeCustomEmoji,
+		Offset:    3,
+		Length:    8,
+		Shortcode: "parrot",
+		EmojiID:   &parrot.ID,
+		URL:       "http://cdn/parrot.gif",
+	}, entities[0])
+	assert.Equal(t, 25, entities[1].Offset)
+
+	assert.Nil(t, catalog.Entities("no emoji here: just colons"))
+	assert.Nil(t, Catalog{}.Entities(":parrot:"))
+}
+
+func TestEntitiesUTF16Offsets(t *testing.T) {
+	catalog := NewCatalog([]*models.CustomEmoji{{ID: uuid.New(), Shortcode: "ok"}})
+
+	// "é" is one UTF-16 code unit, "😀" is two
+	entities := catalog.Entities("é😀 :ok:")
+	require.Len(t, entities, 1)
+	assert.Equal(t, 4, entities[0].Offset)
+	assert.Equal(t, 4, entities[0].Length)
+}
+
+func TestAnnotate(t *testing.T) {
+	catalog := NewCatalog([]*models.CustomEmoji{{ID: uuid.New(), Shortcode: "ok"}})
+	text := &models.Message{Type: models.MessageTypeText, Content: ":ok:"}
+	image := &models.Message{Type: models.MessageTypeImage, Content: "http://x/:ok:"}
+
+	catalog.Annotate(text, image)
+	assert.Len(t, text.Entities, 1)
+	assert.Nil(t, image.Entities)
+}
@@ -0,0 +1,269 @@
+// Package export renders session transcripts in downloadable formats.
+//
+// Writers are streaming: entries are written as they are produced, so an
+// export never needs to hold the full history of a session in memory.
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+)
+
+// Format identifies a transcript format.
+type Format string
+
+const (
+	FormatJSONLines Format = "jsonl"
+	FormatHTML      Format = "html"
+	FormatText      Format = "text"
+)
+
+// ParseFormat validates a format name. An empty name defaults to JSON Lines.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", FormatJSONLines:
+		return FormatJSONLines, nil
+	case FormatHTML, FormatText:
+		return Format(name), nil
+	}
+	return "", fmt.Errorf("unknown transcript format %q", name)
+}
+
+// Extension returns the file extension used for the format.
+func (f Format) Extension() string {
+	switch f {
+	case FormatHTML:
+		return "html"
+	case FormatText:
+		return "txt"
+	}
+	return "jsonl"
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatText:
+		return "text/plain; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Entry is a single message of a transcript.
+type Entry struct {
+	Message *models.Message
+	// Nickname is the author's nickname at export time.
+	Nickname string
+	// ImageLink is where the image of an image message or the file of a file
+	// message can be found: its URL, or the path of the bundled file when
+	// attachments are exported.
+	ImageLink string
+	// Summary describes the event of a system message, see DescribeSystemEvent.
+	Summary string
+}
+
+// AttachmentPath returns the path of a stored object in an archive bundling
+// the attachments of a transcript: the base name of the object, which is
+// content addressed for images, with the extension of contentType if the name
+// has none. It depends only on the object, so messages sharing an object
+// refer to the same file.
+func AttachmentPath(objectName, contentType string) string {
+	base := path.Base(objectName)
+	if path.Ext(base) == "" {
+		if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+			base += exts[0]
+		}
+	}
+	return "attachments/" + base
+}
+
+// DescribeSystemEvent renders a system event as a sentence, e.g.
+// "Alice joined via invite link". nickname resolves user IDs to names.
+func DescribeSystemEvent(event *models.SystemEvent, nickname func(uuid.UUID) string) string {
+	actor := nickname(event.ActorID)
+	target := ""
+	if event.TargetID != nil {
+		target = nickname(*event.TargetID)
+	}
+
+	switch event.Action {
+	case models.SystemActionMemberJoined:
+		if event.Via == "" {
+			return actor + " joined"
+		}
+		return fmt.Sprintf("%s joined via %s", actor, strings.ReplaceAll(event.Via, "_", " "))
+	case models.SystemActionMemberLeft:
+		return actor + " left"
+	case models.SystemActionMemberKicked:
+		return fmt.Sprintf("%s removed %s", actor, target)
+	case models.SystemActionSessionRenamed:
+		return fmt.Sprintf("%s renamed the session from %q to %q", actor, event.OldName, event.NewName)
+	case models.SystemActionRoleChanged:
+		return fmt.Sprintf("%s changed the role of %s from %s to %s", actor, target, event.OldRole, event.NewRole)
+	case models.SystemActionMemberMuted:
+		if event.MutedUntil == nil {
+			return fmt.Sprintf("%s muted %s", actor, target)
+		}
+		return fmt.Sprintf("%s muted %s until %s UTC", actor, target, event.MutedUntil.UTC().Format("2006-01-02 15:04"))
+	case models.SystemActionMessageRemoved:
+		return fmt.Sprintf("%s removed a message by %s", actor, target)
+	}
+	return fmt.Sprintf("%s: %s", actor, event.Action)
+}
+
+// Writer writes a transcript entry by entry.
+type Writer interface {
+	// WriteEntry appends a message to the transcript.
+	// Entries must be written in chronological order.
+	WriteEntry(entry Entry) error
+
+	// Close finishes the transcript. It does not close the underlying io.Writer.
+	Close() error
+}
+
+// NewWriter creates a transcript writer for the session and writes any header the format needs.
+func NewWriter(format Format, w io.Writer, session *models.Session) (Writer, error) {
+	switch format {
+	case FormatJSONLines:
+		return &jsonLinesWriter{enc: json.NewEncoder(w)}, nil
+	case FormatHTML:
+		return newHTMLWriter(w, session)
+	case FormatText:
+		return newTextWriter(w, session)
+	}
+	return nil, fmt.Errorf("unknown transcript format %q", format)
+}
+
+// jsonLinesRecord is the JSON object written for each message.
+type jsonLinesRecord struct {
+	ID        uuid.UUID          `json:"id"`
+	Type      models.MessageType `json:"type"`
+	Content   string             `json:"content"`
+	UserID    uuid.UUID          `json:"user_id"`
+	Nickname  string             `json:"nickname"`
+	Timestamp time.Time          `json:"timestamp"`
+	ImageLink string             `json:"image_link,omitempty"`
+	Summary   string             `json:"summary,omitempty"`
+}
+
+type jsonLinesWriter struct {
+	enc *json.Encoder
+}
+
+func (w *jsonLinesWriter) WriteEntry(entry Entry) error {
+	return w.enc.Encode(jsonLinesRecord{
+		ID:        entry.Message.ID,
+		Type:      entry.Message.Type,
+		Content:   entry.Message.Content,
+		UserID:    entry.Message.UserID,
+		Nickname:  entry.Nickname,
+		Timestamp: entry.Message.Timestamp,
+		ImageLink: entry.ImageLink,
+		Summary:   entry.Summary,
+	})
+}
+
+func (w *jsonLinesWriter) Close() error {
+	return nil
+}
+
+type textWriter struct {
+	w io.Writer
+}
+
+func newTextWriter(w io.Writer, session *models.Session) (*textWriter, error) {
+	title := fmt.Sprintf("Transcript of %s", session.Name)
+	_, err := fmt.Fprintf(w, "%s\n%s\n\n", title, strings.Repeat("=", len([]rune(title))))
+	if err != nil {
+		return nil, err
+	}
+	return &textWriter{w: w}, nil
+}
+
+func (w *textWriter) WriteEntry(entry Entry) error {
+	timestamp := entry.Message.Timestamp.UTC().Format("2006-01-02 15:04:05")
+	if entry.Message.Type == models.MessageTypeSystem {
+		_, err := fmt.Fprintf(w.w, "[%s] * %s\n", timestamp, entry.Summary)
+		return err
+	}
+
+	content := entry.Message.Content
+	switch entry.Message.Type {
+	case models.MessageTypeImage:
+		content = "[image] " + entry.ImageLink
+	case models.MessageTypeFile:
+		content = "[file] " + entry.ImageLink
+		if entry.Message.Media != nil {
+			content = fmt.Sprintf("[file] %s %s", entry.Message.Media.Name, entry.ImageLink)
+		}
+	}
+	_, err := fmt.Fprintf(w.w, "[%s] %s: %s\n", timestamp, entry.Nickname, content)
+	return err
+}
+
+func (w *textWriter) Close() error {
+	return nil
+}
+
+var htmlTemplates = template.Must(template.New("header").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Transcript of {{.Name}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 48rem; margin: 2rem auto; color: #1f2937; }
+h1 { font-size: 1.5rem; border-bottom: 1px solid #e5e7eb; padding-bottom: .5rem; }
+.message { padding: .5rem 0; border-bottom: 1px solid #f3f4f6; }
+.meta { font-size: .8rem; color: #6b7280; }
+.author { font-weight: 600; color: #111827; margin-right: .5rem; }
+.content { white-space: pre-wrap; word-wrap: break-word; margin-top: .25rem; }
+.content img { max-width: 100%; max-height: 24rem; border-radius: .25rem; }
+.system { font-size: .85rem; font-style: italic; color: #6b7280; }
+</style>
+</head>
+<body>
+<h1>Transcript of {{.Name}}</h1>
+`))
+
+func init() {
+	template.Must(htmlTemplates.New("entry").Parse(`{{if eq .Message.Type "system"}}<div class="message system" id="m-{{.Message.ID}}">{{.Summary}} <time datetime="{{.Message.Timestamp.UTC.Format "2006-01-02T15:04:05Z07:00"}}">{{.Message.Timestamp.UTC.Format "2006-01-02 15:04:05"}} UTC</time></div>
+{{else}}<div class="message" id="m-{{.Message.ID}}">
+<div class="meta"><span class="author">{{.Nickname}}</span><time datetime="{{.Message.Timestamp.UTC.Format "2006-01-02T15:04:05Z07:00"}}">{{.Message.Timestamp.UTC.Format "2006-01-02 15:04:05"}} UTC</time></div>
+<div class="content">{{if eq .Message.Type "image"}}<a href="{{.ImageLink}}"><img src="{{.ImageLink}}" alt="Image"></a>{{else if eq .Message.Type "file"}}<a href="{{.ImageLink}}" download>{{if .Message.Media}}{{.Message.Media.Name}}{{else}}File{{end}}</a>{{else}}{{.Message.Content}}{{end}}</div>
+</div>
+{{end}}`))
+	template.Must(htmlTemplates.New("footer").Parse(`</body>
+</html>
+`))
+}
+
+type htmlWriter struct {
+	w io.Writer
+}
+
+func newHTMLWriter(w io.Writer, session *models.Session) (*htmlWriter, error) {
+	if err := htmlTemplates.ExecuteTemplate(w, "header", session); err != nil {
+		return nil, err
+	}
+	return &htmlWriter{w: w}, nil
+}
+
+func (w *htmlWriter) WriteEntry(entry Entry) error {
+	return htmlTemplates.ExecuteTemplate(w.w, "entry", entry)
+}
+
+func (w *htmlWriter) Close() error {
+	return htmlTemplates.ExecuteTemplate(w.w, "footer", nil)
+}
@@ -0,0 +1,215 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEntries() []Entry {
+	timestamp := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	return []Entry{
+		{
+			Message: &models.Message{
+				ID:        uuid.New(),
+				Type:      models.MessageTypeText,
+				Content:   "<b>hello</b> & welcome",
+				UserID:    uuid.New(),
+				Timestamp: timestamp,
+			},
+			Nickname: "Alice",
+		},
+		{
+			Message: &models.Message{
+				ID:        uuid.New(),
+				Type:      models.MessageTypeImage,
+				Content:   "http://localhost:9000/chatroom/messages/cat.png",
+				UserID:    uuid.New(),
+				Timestamp: timestamp.Add(time.Minute),
+			},
+			Nickname:  "Bob",
+			ImageLink: "attachments/cat.png",
+		},
+	}
+}
+
+func writeTranscript(t *testing.T, format Format) string {
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf, &models.Session{Name: "Team <chat>"})
+	require.NoError(t, err)
+	for _, entry := range testEntries() {
+		require.NoError(t, w.WriteEntry(entry))
+	}
+	require.NoError(t, w.Close())
+	return buf.String()
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSONLines, format)
+
+	format, err = ParseFormat("html")
+	require.NoError(t, err)
+	assert.Equal(t, "html", format.Extension())
+
+	_, err = ParseFormat("pdf")
+	assert.Error(t, err)
+}
+
+func TestJSONLinesWriter(t *testing.T) {
+	output := writeTranscript(t, FormatJSONLines)
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	require.Len(t, lines, 2)
+
+	var record jsonLinesRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "Alice", record.Nickname)
+	assert.Equal(t, "<b>hello</b> & welcome", record.Content)
+	assert.Empty(t, record.ImageLink)
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.Equal(t, models.MessageTypeImage, record.Type)
+	assert.Equal(t, "attachments/cat.png", record.ImageLink)
+}
+
+func TestHTMLWriter(t *testing.T) {
+	output := writeTranscript(t, FormatHTML)
+
+	assert.True(t, strings.HasPrefix(output, "<!DOCTYPE html>"))
+	assert.Contains(t, output, "Transcript of Team &lt;chat&gt;")
+	assert.Contains(t, output, "&lt;b&gt;hello&lt;/b&gt; &amp; welcome")
+	assert.Contains(t, output, `<img src="attachments/cat.png"`)
+	assert.True(t, strings.HasSuffix(output, "</html>\n"))
+}
+
+func TestTextWriter(t *testing.T) {
+	output := writeTranscript(t, FormatText)
+
+	assert.Contains(t, output, "Transcript of Team <chat>\n")
+	assert.Contains(t, output, "[2024-03-01 09:30:00] Alice: <b>hello</b> & welcome\n")
+	assert.Contains(t, output, "[2024-03-01 09:31:00] Bob: [image] attachments/cat.png\n")
+}
+
+func TestDescribeSystemEvent(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	names := map[uuid.UUID]string{alice: "Alice", bob: "Bob"}
+	mutedUntil := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	nickname := func(id uuid.UUID) string { return names[id] }
+
+	tests := []struct {
+		event models.SystemEvent
+		want  string
+	}{
+		{models.SystemEvent{Action: models.SystemActionMemberJoined, ActorID: alice, Via: "invite_link"}, "Alice joined via invite link"},
+		{models.SystemEvent{Action: models.SystemActionMemberLeft, ActorID: bob}, "Bob left"},
+		{models.SystemEvent{Action: models.SystemActionMemberKicked, ActorID: alice, TargetID: &bob}, "Alice removed Bob"},
+		{models.SystemEvent{Action: models.SystemActionSessionRenamed, ActorID: alice, OldName: "a", NewName: "b"}, `Alice renamed the session from "a" to "b"`},
+		{models.SystemEvent{Action: models.SystemActionRoleChanged, ActorID: alice, TargetID: &bob, OldRole: "member", NewRole: "creator"}, "Alice changed the role of Bob from member to creator"},
+		{models.SystemEvent{Action: models.SystemActionMemberMuted, ActorID: alice, TargetID: &bob, MutedUntil: &mutedUntil}, "Alice muted Bob until 2024-03-01 12:30 UTC"},
+		{models.SystemEvent{Action: models.SystemActionMessageRemoved, ActorID: alice, TargetID: &bob}, "Alice removed a message by Bob"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, DescribeSystemEvent(&tt.event, nickname))
+	}
+}
+
+func TestSystemEntry(t *testing.T) {
+	message, err := models.NewSystemMessage(uuid.New(), models.SystemEvent{
+		Action:  models.SystemActionMemberLeft,
+		ActorID: uuid.New(),
+	})
+	require.NoError(t, err)
+	message.Timestamp = time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	event, err := message.SystemEvent()
+	require.NoError(t, err)
+	assert.Equal(t, models.SystemActionMemberLeft, event.Action)
+
+	entry := Entry{Message: message, Nickname: "Carol", Summary: "Carol left"}
+
+	var text bytes.Buffer
+	w, err := NewWriter(FormatText, &text, &models.Session{Name: "Team"})
+	require.NoError(t, err)
+	require.NoError(t, w.WriteEntry(entry))
+	assert.Contains(t, text.String(), "[2024-03-01 10:00:00] * Carol left\n")
+
+	var html bytes.Buffer
+	w, err = NewWriter(FormatHTML, &html, &models.Session{Name: "Team"})
+	require.NoError(t, err)
+	require.NoError(t, w.WriteEntry(entry))
+	require.NoError(t, w.Close())
+	assert.Contains(t, html.String(), `<div class="message system"`)
+	assert.Contains(t, html.String(), "Carol left")
+}
+
+func TestFileEntry(t *testing.T) {
+	entry := Entry{
+		Message: &models.Message{
+			ID:        uuid.New(),
+			Type:      models.MessageTypeFile,
+			Content:   "files/1",
+			UserID:    uuid.New(),
+			Timestamp: time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+			Media:     &models.MessageMedia{Key: "files/1", Name: "<notes>.pdf", Size: 1024},
+		},
+		Nickname:  "Carol",
+		ImageLink: "attachments/1.pdf",
+	}
+
+	for format, expected := range map[Format]string{
+		FormatText: "Carol: [file] <notes>.pdf attachments/1.pdf\n",
+		FormatHTML: `<a href="attachments/1.pdf" download>&lt;notes&gt;.pdf</a>`,
+	} {
+		var buf bytes.Buffer
+		w, err := NewWriter(format, &buf, &models.Session{Name: "Team"})
+		require.NoError(t, err)
+		require.NoError(t, w.WriteEntry(entry))
+		require.NoError(t, w.Close())
+		assert.Contains(t, buf.String(), expected, format)
+	}
+}
+
+func TestAttachmentPath(t *testing.T) {
+	assert.Equal(t, "attachments/9f86d081.png", AttachmentPath("messages/9f86d081.png", "image/png"))
+	assert.Equal(t, "attachments/1.pdf", AttachmentPath("uploads/1", "application/pdf"))
+	assert.Equal(t, "attachments/1", AttachmentPath("uploads/1", ""))
+}
+
+func TestAttachmentPathOfSharedObject(t *testing.T) {
+	timestamp := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	shared := func(name string) *models.Message {
+		return &models.Message{
+			ID:        uuid.New(),
+			Type:      models.MessageTypeFile,
+			Content:   "messages/9f86d081",
+			UserID:    uuid.New(),
+			Timestamp: timestamp,
+			Media:     &models.MessageMedia{Key: "messages/9f86d081", ContentType: "application/pdf", Name: name},
+		}
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatText, &buf, &models.Session{Name: "Team"})
+	require.NoError(t, err)
+	attachments := make(map[string]string)
+	for _, message := range []*models.Message{shared("plan.pdf"), shared("plan-final.txt")} {
+		link := AttachmentPath(message.Media.Key, message.Media.ContentType)
+		attachments[message.Media.Key] = link
+		require.NoError(t, w.WriteEntry(Entry{Message: message, Nickname: "Carol", ImageLink: link}))
+	}
+	require.NoError(t, w.Close())
+
+	// Both messages link the one bundled file
+	assert.Equal(t, map[string]string{"messages/9f86d081": "attachments/9f86d081.pdf"}, attachments)
+	assert.Contains(t, buf.String(), "[file] plan.pdf attachments/9f86d081.pdf\n")
+	assert.Contains(t, buf.String(), "[file] plan-final.txt attachments/9f86d081.pdf\n")
+}
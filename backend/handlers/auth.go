@@ -114,6 +114,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		Password: string(hashedPassword),
 		Nickname: req.Nickname,
 	}
+	user.AvatarURL = identiconAvatar(r.Context(), user.ID)
 
 	log.Printf("Attempting to create user with username: %s, nickname: %s", user.Username, user.Nickname)
 
@@ -2,17 +2,19 @@ package handlers
 
 import (
 	"chat-room/auth"
-	"chat-room/config"
-	"chat-room/s3"
+	"chat-room/blob"
+	"chat-room/media"
 	"chat-room/store"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"log"
 	"net/http"
-	"path"
+	"strconv"
 
 	"github.com/google/uuid"
-	"github.com/minio/minio-go/v7"
 )
 
 type AvatarHandler struct {
@@ -23,7 +25,29 @@ func NewAvatarHandler(store store.Store) *AvatarHandler {
 	return &AvatarHandler{store: store}
 }
 
-// UploadAvatar handles direct file upload
+// CropRect selects the part of an image used as avatar, in pixels of the
+// image as displayed. Rectangles that are not square are reduced to their
+// centered square.
+type CropRect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+func (c *CropRect) rect() *image.Rectangle {
+	if c == nil {
+		return nil
+	}
+	rect := image.Rect(c.X, c.Y, c.X+c.Width, c.Y+c.Height)
+	return &rect
+}
+
+// UploadAvatar handles direct file upload. The optional form fields crop_x,
+// crop_y, crop_width and crop_height select the part of the image used;
+// the avatar is stored as a square in every media.AvatarSizes.
+// Route: POST /api/avatar
+// Response: {"avatarUrl": "...", "avatarUrls": {"64": "...", "128": "...", "256": "..."}, "message": "..."}
 func (h *AvatarHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	userID := auth.GetUserIDFromContext(r)
 	if userID == uuid.Nil {
@@ -32,44 +56,94 @@ func (h *AvatarHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse multipart form
-	err := r.ParseMultipartForm(10 << 20) // 10 MB max
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageUploadBytes+(1<<20))
+	err := r.ParseMultipartForm(maxImageUploadBytes)
 	if err != nil {
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
 
-	file, header, err := r.FormFile("avatar")
+	crop, err := parseCropForm(r)
 	if err != nil {
-		http.Error(w, "Failed to get file", http.StatusBadRequest)
+		http.Error(w, "Invalid crop rectangle", http.StatusBadRequest)
 		return
 	}
-	defer file.Close()
 
-	// Generate a unique filename
-	filename := fmt.Sprintf("%s%s", uuid.New().String(), path.Ext(header.Filename))
-	objectName := fmt.Sprintf("user-%s/%s", userID.String(), filename)
+	// The client's file name and content type are not trusted
+	data, info, ok := readImageUpload(w, r, "avatar", maxImageUploadBytes, imageLimits())
+	if !ok {
+		return
+	}
+
+	saveAvatar(w, r, h.store, userID, data, info, crop)
+}
 
-	cfg := config.GetConfig()
-	minioClient := s3.GetClient()
+// parseCropForm reads the crop rectangle from the form fields. All fields
+// or none must be set.
+func parseCropForm(r *http.Request) (*CropRect, error) {
+	fields := []string{"crop_x", "crop_y", "crop_width", "crop_height"}
+	values := make([]int, len(fields))
+	set := 0
+	for i, field := range fields {
+		value := r.FormValue(field)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = n
+		set++
+	}
+	switch set {
+	case 0:
+		return nil, nil
+	case len(fields):
+		return &CropRect{X: values[0], Y: values[1], Width: values[2], Height: values[3]}, nil
+	default:
+		return nil, fmt.Errorf("incomplete crop rectangle")
+	}
+}
 
-	// Upload the file to MinIO
-	opts := minio.PutObjectOptions{
-		ContentType: header.Header.Get("Content-Type"),
+// saveAvatar crops a validated image to a square, stores it in every avatar
+// size as the user's avatar and writes the avatar URLs as response. Resizing
+// re-encodes the image, which drops its metadata. The avatar counts towards
+// the storage quota of the user.
+func saveAvatar(w http.ResponseWriter, r *http.Request, st store.Store, userID uuid.UUID, data []byte, info *media.Info, crop *CropRect) {
+	avatars, err := info.Avatars(crop.rect())
+	if errors.Is(err, media.ErrInvalidCrop) {
+		http.Error(w, "Crop rectangle must lie within the image", http.StatusBadRequest)
+		return
 	}
-	_, err = minioClient.PutObject(context.Background(), cfg.MinioBucketName, objectName, file, header.Size, opts)
 	if err != nil {
-		http.Error(w, "Failed to upload file", http.StatusInternalServerError)
+		log.Printf("Failed to resize avatar of user %s: %v", userID, err)
+		http.Error(w, "Failed to process image", http.StatusInternalServerError)
 		return
 	}
 
-	// Generate the public URL
-	publicURL := fmt.Sprintf("http://localhost:9000/%s/%s",
-		cfg.MinioBucketName,
-		objectName,
-	)
+	var size int64
+	for _, avatar := range avatars {
+		size += int64(len(avatar.Data))
+	}
+	if !checkQuota(w, r, st, userID, nil, size) {
+		return
+	}
+
+	// All sizes share a unique base name
+	base := fmt.Sprintf("%s%s/%s", blob.AvatarPrefix, userID.String(), uuid.New().String())
+	put := recordingPut(st, userID, nil)
+	var objectName string
+	for i, avatar := range avatars {
+		objectName = media.AvatarObjectName(base, media.AvatarSizes[i], avatar.Ext)
+		if err := put(context.Background(), objectName, avatar.Data, avatar.ContentType); err != nil {
+			http.Error(w, "Failed to upload file", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	// Get current user
-	users, err := h.store.GetUsersByIDs(r.Context(), []uuid.UUID{userID})
+	users, err := st.GetUsersByIDs(r.Context(), []uuid.UUID{userID})
 	if err != nil {
 		http.Error(w, "Failed to get user", http.StatusInternalServerError)
 		return
@@ -80,16 +154,19 @@ func (h *AvatarHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
 	}
 	user := users[0]
 
-	// Update user's avatar URL
-	user.AvatarURL = publicURL
-	if err := h.store.UpdateUser(r.Context(), user); err != nil {
+	// The avatar's URLs are generated when the user is served; the largest
+	// size is referenced
+	user.AvatarURL = objectName
+	if err := st.UpdateUser(r.Context(), user); err != nil {
 		http.Error(w, "Failed to update avatar URL", http.StatusInternalServerError)
 		return
 	}
 
+	url, urls := avatarURLs(r.Context(), objectName)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"avatarUrl": publicURL,
-		"message":   "Avatar uploaded successfully",
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"avatarUrl":  url,
+		"avatarUrls": urls,
+		"message":    "Avatar uploaded successfully",
 	})
 }
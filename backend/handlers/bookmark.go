@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"chat-room/auth"
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxBookmarkNoteLength and maxBookmarkFolderLength limit the fields of a bookmark.
+	maxBookmarkNoteLength   = 1000
+	maxBookmarkFolderLength = 64
+)
+
+// BookmarkHandler manages HTTP requests for the messages saved by a user.
+type BookmarkHandler struct {
+	store store.Store
+}
+
+// NewBookmarkHandler creates a new bookmark handler with the given store.
+func NewBookmarkHandler(store store.Store) *BookmarkHandler {
+	return &BookmarkHandler{store: store}
+}
+
+// Request/Response types
+type (
+	// SaveBookmarkRequest represents the request body for saving a message.
+	SaveBookmarkRequest struct {
+		Note   string `json:"note"`
+		Folder string `json:"folder"`
+	}
+
+	// BookmarkListResponse represents a page of saved messages.
+	BookmarkListResponse struct {
+		Bookmarks []*models.Bookmark `json:"bookmarks"`
+		Users     []*models.User     `json:"users"`
+		// NextBefore is passed as before to load the next page; empty on the last page.
+		NextBefore string `json:"next_before,omitempty"`
+	}
+)
+
+// SaveBookmark saves a message of one of the user's sessions, or updates the
+// note and folder of a saved message.
+// Route: PUT /api/bookmarks/{messageId}
+// Request: {"note": "Deploy steps", "folder": "ops"}
+// Response: {"user_id": "uuid", "message_id": "uuid", "session_id": "uuid", "note": "...", "folder": "...", "created_at": "..."}
+func (h *BookmarkHandler) SaveBookmark(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r)
+
+	messageID, err := uuid.Parse(chi.URLParam(r, "messageId"))
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SaveBookmarkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	req.Folder = strings.TrimSpace(req.Folder)
+	if len([]rune(req.Note)) > maxBookmarkNoteLength {
+		http.Error(w, "Note is too long", http.StatusBadRequest)
+		return
+	}
+	if len([]rune(req.Folder)) > maxBookmarkFolderLength {
+		http.Error(w, "Folder name is too long", http.StatusBadRequest)
+		return
+	}
+
+	// Only messages of sessions the user belongs to can be saved
+	message, err := h.store.GetMessageByID(r.Context(), messageID)
+	if err != nil {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	}
+	userSessions, err := h.store.GetUserSessionsBySessionIDAndUserIDs(r.Context(), message.SessionID, []uuid.UUID{userID})
+	if err != nil || len(userSessions) == 0 {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	}
+
+	bookmark := &models.Bookmark{
+		UserID:    userID,
+		MessageID: message.ID,
+		SessionID: message.SessionID,
+		Note:      req.Note,
+		Folder:    req.Folder,
+	}
+	if err := h.store.SaveBookmark(r.Context(), bookmark); err != nil {
+		log.Printf("Failed to save message %s for user %s: %v", message.ID, userID, err)
+		http.Error(w, "Failed to save message", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bookmark)
+}
+
+// DeleteBookmark removes a saved message.
+// Route: DELETE /api/bookmarks/{messageId}
+func (h *BookmarkHandler) DeleteBookmark(w http.ResponseWriter, r *http.Request) {
+	messageID, err := uuid.Parse(chi.URLParam(r, "messageId"))
+	if err != nil {
+		http.Error(w, "Invalid message ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.DeleteBookmark(r.Context(), auth.GetUserIDFromContext(r), messageID); err != nil {
+		http.Error(w, "Failed to remove saved message", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListBookmarks returns the user's saved messages, newest first, together with
+// their senders. Messages of sessions the user has left are hidden, and shown
+// again if the user rejoins.
+// Route: GET /api/bookmarks
+// Query parameters:
+//   - folder: only return bookmarks of this folder
+//   - before: next_before of the previous page
+//   - limit: maximum number of bookmarks (default 50, max 200)
+//
+// Response: {"bookmarks": [{"message_id": "uuid", "message": {...}, "session_name": "...", ...}], "users": [...], "next_before": "..."}
+func (h *BookmarkHandler) ListBookmarks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	userID := auth.GetUserIDFromContext(r)
+
+	var before *store.MessageCursor
+	if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
+		cursor, err := store.DecodeMessageCursor(beforeStr)
+		if err != nil {
+			http.Error(w, "Invalid before parameter", http.StatusBadRequest)
+			return
+		}
+		before = &cursor
+	}
+
+	limit := parsePaginationLimit(r, 50, 200)
+
+	bookmarks, err := h.store.GetBookmarksByUserID(r.Context(), userID, r.URL.Query().Get("folder"), before, limit)
+	if err != nil {
+		http.Error(w, "Error fetching saved messages", http.StatusInternalServerError)
+		return
+	}
+
+	response := BookmarkListResponse{
+		Bookmarks: []*models.Bookmark{},
+		Users:     []*models.User{},
+	}
+	if len(bookmarks) == 0 {
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	response.Bookmarks = bookmarks
+	if len(bookmarks) == limit {
+		response.NextBefore = store.BookmarkCursorOf(bookmarks[len(bookmarks)-1]).Encode()
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var senderIDs []uuid.UUID
+	messages := make([]*models.Message, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		messages = append(messages, bookmark.Message)
+		if id := bookmark.Message.UserID; !seen[id] {
+			seen[id] = true
+			senderIDs = append(senderIDs, id)
+		}
+	}
+	prepareMessages(r.Context(), h.store, messages)
+	users, err := h.store.GetUsersByIDs(r.Context(), senderIDs)
+	if err != nil {
+		http.Error(w, "Error fetching saved messages", http.StatusInternalServerError)
+		return
+	}
+	resolveAvatars(r.Context(), users)
+	response.Users = append(response.Users, users...)
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ListBookmarkFolders returns the folders of the user's saved messages.
+// Route: GET /api/bookmarks/folders
+// Response: {"folders": [{"name": "ops", "count": 3}]}
+func (h *BookmarkHandler) ListBookmarkFolders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	folders, err := h.store.GetBookmarkFoldersByUserID(r.Context(), auth.GetUserIDFromContext(r))
+	if err != nil {
+		http.Error(w, "Error fetching folders", http.StatusInternalServerError)
+		return
+	}
+	if folders == nil {
+		folders = []*models.BookmarkFolder{}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"folders": folders,
+	})
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"chat-room/auth"
+	"chat-room/middleware"
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxDraftLength limits the size of a draft.
+	maxDraftLength = 4000
+	// maxDraftClockSkew is how far ahead of the server a client timestamp may be.
+	// Later timestamps are clamped, so that a client with a fast clock cannot
+	// win every future write.
+	maxDraftClockSkew = time.Minute
+)
+
+// WebSocket events sent to the other connections of a user when a draft changes.
+const (
+	EventDraftUpdated = "draft.updated"
+	EventDraftDeleted = "draft.deleted"
+)
+
+// DraftHandler manages HTTP requests for the unsent messages of a user.
+type DraftHandler struct {
+	store store.Store
+	hub   *WebSocketHandler
+}
+
+// NewDraftHandler creates a new draft handler with the given store.
+// Draft changes are pushed to the user's connections through hub.
+func NewDraftHandler(store store.Store, hub *WebSocketHandler) *DraftHandler {
+	return &DraftHandler{store: store, hub: hub}
+}
+
+// Request/Response types
+type (
+	// SaveDraftRequest represents the request body for saving a draft.
+	// UpdatedAt is when the user last edited the draft on the client.
+	SaveDraftRequest struct {
+		Content   string     `json:"content"`
+		ReplyToID *uuid.UUID `json:"reply_to_id"`
+		UpdatedAt time.Time  `json:"updated_at"`
+	}
+
+	// DraftDeletedEvent is the data of a draft.deleted event.
+	DraftDeletedEvent struct {
+		SessionID uuid.UUID `json:"session_id"`
+		DeletedAt time.Time `json:"deleted_at"`
+	}
+)
+
+// GetDraft returns the user's draft in the session.
+// Route: GET /api/sessions/draft
+// Response: {"session_id": "uuid", "content": "...", "reply_to_id": "uuid", "updated_at": "..."}, or 204 without a draft
+func (h *DraftHandler) GetDraft(w http.ResponseWriter, r *http.Request) {
+	draft, err := h.store.GetDraft(r.Context(), auth.GetUserIDFromContext(r), middleware.GetSessionID(r))
+	if errors.Is(err, store.ErrNotFound) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get draft", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draft)
+}
+
+// SaveDraft stores the user's draft in the session. The write is ignored if a
+// draft with a later updated_at is stored, or the draft was deleted later;
+// either way the stored draft is returned, with empty content if deleted.
+// Route: PUT /api/sessions/draft
+// Request: {"content": "...", "reply_to_id": "uuid", "updated_at": "2024-03-01T10:00:00.123Z"}
+// Response: {"session_id": "uuid", "content": "...", "reply_to_id": "uuid", "updated_at": "..."}
+func (h *DraftHandler) SaveDraft(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r)
+	sessionID := middleware.GetSessionID(r)
+
+	var req SaveDraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if len([]rune(req.Content)) > maxDraftLength {
+		http.Error(w, "Draft is too long", http.StatusBadRequest)
+		return
+	}
+
+	if req.ReplyToID != nil {
+		message, err := h.store.GetMessageByID(r.Context(), *req.ReplyToID)
+		if err != nil || message.SessionID != sessionID {
+			http.Error(w, "Reply target not found", http.StatusBadRequest)
+			return
+		}
+	}
+
+	draft := &models.Draft{
+		UserID:    userID,
+		SessionID: sessionID,
+		Content:   req.Content,
+		ReplyToID: req.ReplyToID,
+		UpdatedAt: clampClientTime(req.UpdatedAt),
+	}
+	applied, err := h.store.SaveDraft(r.Context(), draft)
+	if err != nil {
+		log.Printf("Failed to save draft of user %s in session %s: %v", userID, sessionID, err)
+		http.Error(w, "Failed to save draft", http.StatusInternalServerError)
+		return
+	}
+
+	if applied {
+		h.hub.sendToUser(sessionID, userID, WebSocketEvent{Event: EventDraftUpdated, Data: draft})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draft)
+}
+
+// DeleteDraft removes the user's draft in the session, typically after the
+// message was sent. A draft edited after deleted_at is kept, and edits from
+// before deleted_at arriving later are ignored.
+// Route: DELETE /api/sessions/draft
+// Query parameters:
+//   - deleted_at: when the draft was discarded on the client (RFC 3339, default now)
+func (h *DraftHandler) DeleteDraft(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r)
+	sessionID := middleware.GetSessionID(r)
+
+	var deletedAt time.Time
+	if deletedAtStr := r.URL.Query().Get("deleted_at"); deletedAtStr != "" {
+		t, err := time.Parse(time.RFC3339Nano, deletedAtStr)
+		if err != nil {
+			http.Error(w, "Invalid deleted_at parameter", http.StatusBadRequest)
+			return
+		}
+		deletedAt = t
+	}
+	deletedAt = clampClientTime(deletedAt)
+
+	if err := h.store.DeleteDraft(r.Context(), userID, sessionID, deletedAt); err != nil {
+		http.Error(w, "Failed to delete draft", http.StatusInternalServerError)
+		return
+	}
+
+	h.hub.sendToUser(sessionID, userID, WebSocketEvent{
+		Event: EventDraftDeleted,
+		Data:  DraftDeletedEvent{SessionID: sessionID, DeletedAt: deletedAt},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clampClientTime returns t in UTC, or now if t is zero or too far in the future.
+func clampClientTime(t time.Time) time.Time {
+	now := time.Now().UTC()
+	if t.IsZero() || t.After(now.Add(maxDraftClockSkew)) {
+		return now
+	}
+	return t.UTC()
+}
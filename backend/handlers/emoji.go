@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"chat-room/auth"
+	"chat-room/config"
+	"chat-room/emoji"
+	"chat-room/media"
+	"chat-room/middleware"
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// maxEmojiDimension limits the width and height of custom emoji images, in pixels.
+const maxEmojiDimension = 512
+
+// EmojiHandler manages HTTP requests for the custom emoji of a session.
+type EmojiHandler struct {
+	store store.Store
+}
+
+// NewEmojiHandler creates a new emoji handler with the given store.
+func NewEmojiHandler(store store.Store) *EmojiHandler {
+	return &EmojiHandler{store: store}
+}
+
+// UploadEmoji adds a custom emoji to the session (creator only). The image must
+// be a PNG, JPEG, GIF or WebP of at most EMOJI_MAX_BYTES and 512x512 pixels, and a
+// session holds at most EMOJI_MAX_PER_SESSION emoji.
+// Route: POST /api/sessions/emojis
+// Request: multipart form with "shortcode" (without colons) and "image"
+// Response: {"id": "uuid", "session_id": "uuid", "shortcode": "party", "url": "...", ...}
+func (h *EmojiHandler) UploadEmoji(w http.ResponseWriter, r *http.Request) {
+	sessionID := middleware.GetSessionID(r)
+	cfg := config.GetConfig()
+
+	r.Body = http.MaxBytesReader(w, r.Body, int64(cfg.EmojiMaxBytes)+(1<<20))
+	if err := r.ParseMultipartForm(int64(cfg.EmojiMaxBytes)); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	shortcode := strings.Trim(strings.TrimSpace(r.FormValue("shortcode")), ":")
+	if err := emoji.ValidateShortcode(shortcode); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, info, ok := readImageUpload(w, r, "image", int64(cfg.EmojiMaxBytes), media.Limits{MaxDimension: maxEmojiDimension})
+	if !ok {
+		return
+	}
+	if data, ok = stripImageMetadata(w, data, info); !ok {
+		return
+	}
+
+	emojis, err := h.store.GetCustomEmojisBySessionID(r.Context(), sessionID)
+	if err != nil {
+		http.Error(w, "Failed to get emoji", http.StatusInternalServerError)
+		return
+	}
+	if len(emojis) >= cfg.EmojiMaxPerSession {
+		http.Error(w, fmt.Sprintf("Sessions may have at most %d custom emoji", cfg.EmojiMaxPerSession), http.StatusConflict)
+		return
+	}
+	if _, taken := emoji.NewCatalog(emojis)[shortcode]; taken {
+		http.Error(w, "Shortcode is already taken", http.StatusConflict)
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r)
+	if !checkQuota(w, r, h.store, userID, &sessionID, int64(len(data))) {
+		return
+	}
+
+	id := uuid.New()
+	objectName := fmt.Sprintf("emojis/%s/%s%s", sessionID, id, info.Ext)
+	if err := recordingPut(h.store, userID, &sessionID)(r.Context(), objectName, data, info.ContentType); err != nil {
+		http.Error(w, "Failed to upload file", http.StatusInternalServerError)
+		return
+	}
+
+	customEmoji := &models.CustomEmoji{
+		ID:          id,
+		SessionID:   sessionID,
+		Shortcode:   shortcode,
+		ObjectName:  objectName,
+		ContentType: info.ContentType,
+		Size:        int64(len(data)),
+		CreatedBy:   userID,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := h.store.CreateCustomEmoji(r.Context(), customEmoji); err != nil {
+		// Also reached when a concurrent upload took the shortcode
+		deleteObjects(context.Background(), h.store, objectName)
+		log.Printf("Failed to create emoji %s in session %s: %v", shortcode, sessionID, err)
+		http.Error(w, "Failed to create emoji", http.StatusInternalServerError)
+		return
+	}
+	presignEmojis(r.Context(), []*models.CustomEmoji{customEmoji})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(customEmoji)
+}
+
+// ListEmojis returns the custom emoji catalog of the session, ordered by shortcode.
+// Route: GET /api/sessions/emojis
+// Response: {"emojis": [{"id": "uuid", "shortcode": "party", "url": "...", ...}], "max_emojis": 100}
+func (h *EmojiHandler) ListEmojis(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	emojis, err := h.store.GetCustomEmojisBySessionID(r.Context(), middleware.GetSessionID(r))
+	if err != nil {
+		http.Error(w, "Failed to get emoji", http.StatusInternalServerError)
+		return
+	}
+	if emojis == nil {
+		emojis = []*models.CustomEmoji{}
+	}
+	presignEmojis(r.Context(), emojis)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"emojis":     emojis,
+		"max_emojis": config.GetConfig().EmojiMaxPerSession,
+	})
+}
+
+// DeleteEmoji removes a custom emoji of the session and its image (creator only).
+// Messages using its shortcode show the plain text afterwards.
+// Route: DELETE /api/sessions/emojis/{id}
+func (h *EmojiHandler) DeleteEmoji(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid emoji ID", http.StatusBadRequest)
+		return
+	}
+
+	customEmoji, err := h.store.GetCustomEmojiByID(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && customEmoji.SessionID != middleware.GetSessionID(r)) {
+		http.Error(w, "Emoji not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get emoji", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.store.DeleteCustomEmoji(r.Context(), id); err != nil {
+		http.Error(w, "Failed to delete emoji", http.StatusInternalServerError)
+		return
+	}
+
+	deleteObjects(r.Context(), h.store, customEmoji.ObjectName)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// annotateEmoji sets the custom emoji entities of the messages, using the
+// catalog of each message's session. Messages are still served without
+// entities if a catalog cannot be loaded.
+func annotateEmoji(ctx context.Context, st store.Store, messages []*models.Message) {
+	bySession := make(map[uuid.UUID][]*models.Message)
+	for _, message := range messages {
+		if message.Type == models.MessageTypeText {
+			bySession[message.SessionID] = append(bySession[message.SessionID], message)
+		}
+	}
+
+	for sessionID, sessionMessages := range bySession {
+		emojis, err := st.GetCustomEmojisBySessionID(ctx, sessionID)
+		if err != nil {
+			log.Printf("Error loading custom emoji of session %s: %v", sessionID, err)
+			continue
+		}
+		presignEmojis(ctx, emojis)
+		emoji.NewCatalog(emojis).Annotate(sessionMessages...)
+	}
+}
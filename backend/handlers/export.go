@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"chat-room/blob"
+	"chat-room/export"
+	"chat-room/middleware"
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+)
+
+// exportPageSize is the number of messages loaded per store round trip during an export.
+const exportPageSize = 200
+
+// ExportTranscript streams the full history of the session (creator only).
+// Route: GET /api/sessions/export
+// Query parameters:
+//   - format: jsonl (default), html or text
+//   - attachments: if "true", respond with a zip bundling the transcript and all images and files;
+//     otherwise they are linked with presigned URLs, which expire after MEDIA_URL_TTL
+//
+// Response: the transcript file, or a zip archive when attachments are requested
+func (h *SessionHandler) ExportTranscript(w http.ResponseWriter, r *http.Request) {
+	sessionID := middleware.GetSessionID(r)
+
+	format, err := export.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+	withAttachments := r.URL.Query().Get("attachments") == "true"
+
+	session, err := h.store.GetSessionByID(r.Context(), sessionID)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	// The first page is loaded before any output so that a failing store still
+	// yields an error status; the rest is paged while the transcript is written.
+	first, err := h.store.GetMessagesBySessionID(r.Context(), sessionID, nil, store.PageAfter, exportPageSize)
+	if err != nil {
+		http.Error(w, "Error fetching messages", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("transcript-%s", sessionID)
+	if !withAttachments {
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format.Extension()))
+		if _, err := h.writeTranscript(r.Context(), w, format, session, first, false); err != nil {
+			log.Printf("Error exporting session %s: %v", sessionID, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+
+	archive := zip.NewWriter(w)
+	defer archive.Close()
+
+	transcript, err := archive.Create("transcript." + format.Extension())
+	if err != nil {
+		log.Printf("Error exporting session %s: %v", sessionID, err)
+		return
+	}
+	attachments, err := h.writeTranscript(r.Context(), transcript, format, session, first, true)
+	if err != nil {
+		log.Printf("Error exporting session %s: %v", sessionID, err)
+		return
+	}
+
+	if err := bundleAttachments(r.Context(), archive, attachments); err != nil {
+		log.Printf("Error bundling attachments of session %s: %v", sessionID, err)
+	}
+}
+
+// writeTranscript writes the messages of the session to w, oldest first, starting
+// with the already loaded first page and paging forward from there.
+// If bundle is set, image links point into the archive and the returned map
+// holds the archive path of every referenced object, keyed by object name.
+func (h *SessionHandler) writeTranscript(ctx context.Context, w io.Writer, format export.Format, session *models.Session, first []*models.Message, bundle bool) (map[string]string, error) {
+	writer, err := export.NewWriter(format, w, session)
+	if err != nil {
+		return nil, err
+	}
+
+	nicknames := make(map[uuid.UUID]string)
+	attachments := make(map[string]string)
+
+	for messages := first; len(messages) > 0; {
+		if err := h.loadNicknames(ctx, messages, nicknames); err != nil {
+			return nil, err
+		}
+
+		for _, message := range messages {
+			entry := export.Entry{
+				Message:  message,
+				Nickname: nicknames[message.UserID],
+			}
+			if message.Type == models.MessageTypeSystem {
+				if event, err := message.SystemEvent(); err == nil {
+					entry.Summary = export.DescribeSystemEvent(event, func(id uuid.UUID) string {
+						return nicknames[id]
+					})
+				}
+			}
+			if message.Type == models.MessageTypeImage || message.Type == models.MessageTypeFile {
+				objectName := blob.ObjectNameOf(message.Content)
+				if bundle {
+					var contentType string
+					if message.Media != nil {
+						contentType = message.Media.ContentType
+					}
+					entry.ImageLink = export.AttachmentPath(objectName, contentType)
+					attachments[objectName] = entry.ImageLink
+				} else if entry.ImageLink, err = blob.URL(ctx, objectName); err != nil {
+					return nil, err
+				}
+			}
+			if err := writer.WriteEntry(entry); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(messages) < exportPageSize {
+			break
+		}
+		cursor := store.CursorOf(messages[len(messages)-1])
+		if messages, err = h.store.GetMessagesBySessionID(ctx, session.ID, &cursor, store.PageAfter, exportPageSize); err != nil {
+			return nil, err
+		}
+	}
+
+	return attachments, writer.Close()
+}
+
+// loadNicknames adds the nicknames of all authors of messages, and of the
+// targets of system events, missing from nicknames.
+// Users who have deleted their account are exported as "Unknown User".
+func (h *SessionHandler) loadNicknames(ctx context.Context, messages []*models.Message, nicknames map[uuid.UUID]string) error {
+	var missing []uuid.UUID
+	add := func(id uuid.UUID) {
+		if _, ok := nicknames[id]; !ok {
+			nicknames[id] = "Unknown User"
+			missing = append(missing, id)
+		}
+	}
+	for _, message := range messages {
+		add(message.UserID)
+		if message.Type != models.MessageTypeSystem {
+			continue
+		}
+		if event, err := message.SystemEvent(); err == nil && event.TargetID != nil {
+			add(*event.TargetID)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	users, err := h.store.GetUsersByIDs(ctx, missing)
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		nicknames[user.ID] = user.Nickname
+	}
+	return nil
+}
+
+// bundleAttachments copies the referenced objects from the blob store into the archive.
+// Objects that can no longer be read are skipped.
+func bundleAttachments(ctx context.Context, archive *zip.Writer, attachments map[string]string) error {
+	for objectName, archivePath := range attachments {
+		object, err := blob.Get(ctx, objectName)
+		if err != nil {
+			log.Printf("Skipping attachment %s: %v", objectName, err)
+			continue
+		}
+
+		entry, err := archive.Create(archivePath)
+		if err != nil {
+			object.Close()
+			return err
+		}
+		if _, err := io.Copy(entry, object); err != nil {
+			log.Printf("Error copying attachment %s: %v", objectName, err)
+		}
+		object.Close()
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"chat-room/blob"
+	"chat-room/config"
+	"chat-room/media"
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+)
+
+// maxImageUploadBytes limits the size of uploaded message images and avatars.
+const maxImageUploadBytes = 10 << 20
+
+// imageLimits returns the configured dimension limits of uploaded images.
+func imageLimits() media.Limits {
+	cfg := config.GetConfig()
+	return media.Limits{
+		MaxDimension: cfg.MediaMaxImageDimension,
+		MaxPixels:    cfg.MediaMaxImagePixels,
+	}
+}
+
+// prepareMessages readies messages to be sent to clients: the custom emoji of
+// text messages are annotated and the images of image messages and the files
+// of file messages get presigned URLs. Callers must have verified that the user may read the messages.
+func prepareMessages(ctx context.Context, st store.Store, messages []*models.Message) {
+	annotateEmoji(ctx, st, messages)
+	presignMessages(ctx, messages)
+}
+
+// presignMessages replaces the object names of image and file messages, and
+// of image variants, with presigned URLs. Objects that cannot be signed keep
+// their object name, which clients cannot load.
+func presignMessages(ctx context.Context, messages []*models.Message) {
+	for _, message := range messages {
+		if message.Type != models.MessageTypeImage && message.Type != models.MessageTypeFile {
+			continue
+		}
+		url, err := blob.URL(ctx, blob.ObjectNameOf(message.Content))
+		if err != nil {
+			log.Printf("Failed to presign %s of message %s: %v", message.Type, message.ID, err)
+			continue
+		}
+		message.Content = url
+		if message.Media == nil {
+			continue
+		}
+		for i := range message.Media.Variants {
+			variant := &message.Media.Variants[i]
+			if variant.URL, err = blob.URL(ctx, variant.Key); err != nil {
+				log.Printf("Failed to presign %s variant of message %s: %v", variant.Name, message.ID, err)
+			}
+		}
+	}
+}
+
+// presignEmojis sets the presigned URLs of custom emoji.
+func presignEmojis(ctx context.Context, emojis []*models.CustomEmoji) {
+	for _, e := range emojis {
+		url, err := blob.URL(ctx, e.ObjectName)
+		if err != nil {
+			log.Printf("Failed to presign image of emoji %s: %v", e.ID, err)
+			continue
+		}
+		e.URL = url
+	}
+}
+
+// resolveAvatars replaces the avatar object names of the users with the URLs
+// under the public media origin, including the URLs of every avatar size.
+// Avatars linking outside the blob store, such as imported ones, keep their URL.
+func resolveAvatars(ctx context.Context, users []*models.User) {
+	for _, user := range users {
+		user.AvatarURL, user.AvatarURLs = avatarURLs(ctx, user.AvatarURL)
+	}
+}
+
+// avatarURLs returns the URL of a stored avatar reference and, for avatars
+// stored in every size, the URLs keyed by size.
+func avatarURLs(ctx context.Context, avatar string) (string, map[string]string) {
+	if avatar == "" {
+		return "", nil
+	}
+	objectName, ok := blob.ObjectNameFromURL(avatar)
+	if !ok {
+		if strings.HasPrefix(avatar, "http://") || strings.HasPrefix(avatar, "https://") {
+			return avatar, nil
+		}
+		objectName = avatar
+	}
+	url, err := blob.URL(ctx, objectName)
+	if err != nil {
+		log.Printf("Failed to get URL of avatar %s: %v", objectName, err)
+		return "", nil
+	}
+
+	base, _, ext, ok := media.ParseAvatarObjectName(objectName)
+	if !ok {
+		return url, nil
+	}
+	urls := make(map[string]string, len(media.AvatarSizes))
+	for _, size := range media.AvatarSizes {
+		if urls[strconv.Itoa(size)], err = blob.URL(ctx, media.AvatarObjectName(base, size, ext)); err != nil {
+			log.Printf("Failed to get URL of avatar %s: %v", objectName, err)
+			return url, nil
+		}
+	}
+	return url, urls
+}
+
+// identiconAvatar stores the identicon of a new user and returns its
+// reference, to be set as avatar when the user is created. Returns an empty
+// string if it could not be stored; the identicons command stores it later.
+func identiconAvatar(ctx context.Context, userID uuid.UUID) string {
+	objectName, err := media.StoreIdenticon(ctx, blob.AvatarPrefix, userID, blob.Put)
+	if err != nil {
+		log.Printf("Failed to store identicon of user %s: %v", userID, err)
+		return ""
+	}
+	return objectName
+}
+
+// checkQuota writes a 413 response if storing size more bytes would exceed
+// the storage quota of the user or, if sessionID is set, of the session.
+func checkQuota(w http.ResponseWriter, r *http.Request, st store.Store, userID uuid.UUID, sessionID *uuid.UUID, size int64) bool {
+	cfg := config.GetConfig()
+
+	if cfg.QuotaUserBytes > 0 {
+		used, err := st.GetUserStorageUsage(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "Failed to check storage quota", http.StatusInternalServerError)
+			return false
+		}
+		if used+size > cfg.QuotaUserBytes {
+			http.Error(w, fmt.Sprintf("Storage quota exceeded: you use %d of %d bytes", used, cfg.QuotaUserBytes), http.StatusRequestEntityTooLarge)
+			return false
+		}
+	}
+
+	if sessionID != nil && cfg.QuotaSessionBytes > 0 {
+		used, err := st.GetSessionStorageUsage(r.Context(), *sessionID)
+		if err != nil {
+			http.Error(w, "Failed to check storage quota", http.StatusInternalServerError)
+			return false
+		}
+		if used+size > cfg.QuotaSessionBytes {
+			http.Error(w, fmt.Sprintf("Storage quota exceeded: the session uses %d of %d bytes", used, cfg.QuotaSessionBytes), http.StatusRequestEntityTooLarge)
+			return false
+		}
+	}
+
+	return true
+}
+
+// recordingPut returns a media.PutFunc storing objects in the blob store and
+// recording them in the storage usage of the user and the optional session.
+func recordingPut(st store.Store, userID uuid.UUID, sessionID *uuid.UUID) media.PutFunc {
+	return func(ctx context.Context, objectName string, data []byte, contentType string) error {
+		if err := blob.Put(ctx, objectName, data, contentType); err != nil {
+			return err
+		}
+		return st.CreateMediaObject(ctx, &models.MediaObject{
+			ObjectName: objectName,
+			UserID:     userID,
+			SessionID:  sessionID,
+			Size:       int64(len(data)),
+		})
+	}
+}
+
+// sharingPut returns a media.PutFunc for content addressed objects, which
+// are stored once however often they are put: every put takes a reference to
+// the object, and the object is only stored, and recorded in the storage
+// usage of the user and the optional session, if it is not stored yet.
+// Every successful put must be undone with deleteObjects.
+func sharingPut(st store.Store, userID uuid.UUID, sessionID *uuid.UUID) media.PutFunc {
+	put := recordingPut(st, userID, sessionID)
+	return func(ctx context.Context, objectName string, data []byte, contentType string) error {
+		refs, err := st.AcquireMediaBlob(ctx, objectName, int64(len(data)))
+		if err != nil {
+			return err
+		}
+		if refs > 1 {
+			// The object is stored again if the first reference failed to
+			// store it. Referenced objects are not deleted, so it cannot
+			// disappear once found.
+			_, err := blob.Stat(ctx, objectName)
+			if err == nil {
+				return nil
+			}
+			if !errors.Is(err, blob.ErrNotFound) {
+				deleteObjects(ctx, st, objectName)
+				return err
+			}
+		}
+		if err := put(ctx, objectName, data, contentType); err != nil {
+			deleteObjects(ctx, st, objectName)
+			return err
+		}
+		return nil
+	}
+}
+
+// deleteObjects removes objects from the blob store and from the storage
+// usage. Content addressed objects only lose a reference and are removed
+// with their last one. Failures are logged, the garbage collector removes
+// what is left.
+func deleteObjects(ctx context.Context, st store.Store, objectNames ...string) {
+	var deleted []string
+	for _, objectName := range objectNames {
+		err := st.ReleaseMediaBlob(ctx, objectName, func(ctx context.Context) error {
+			return blob.Delete(ctx, objectName)
+		})
+		if errors.Is(err, store.ErrNotFound) {
+			if err := blob.Delete(ctx, objectName); err != nil {
+				log.Printf("Failed to remove object %s: %v", objectName, err)
+			}
+			deleted = append(deleted, objectName)
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to release object %s: %v", objectName, err)
+		}
+	}
+	if len(deleted) == 0 {
+		return
+	}
+	if err := st.DeleteMediaObjects(ctx, deleted); err != nil {
+		log.Printf("Failed to remove storage records of %d objects: %v", len(deleted), err)
+	}
+}
+
+// mediaObjectNames returns the object name of an image or file and the object
+// names of the variants listed by its optional media description.
+func mediaObjectNames(objectName string, messageMedia *models.MessageMedia) []string {
+	objectNames := []string{objectName}
+	if messageMedia != nil {
+		for _, variant := range messageMedia.Variants {
+			objectNames = append(objectNames, variant.Key)
+		}
+	}
+	return objectNames
+}
+
+// preservesMetadata reports whether the images of the session keep their metadata.
+func preservesMetadata(sessionID uuid.UUID) bool {
+	for _, id := range config.GetConfig().MediaPreserveMetadataSessions {
+		if id == sessionID.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// stripImageMetadata removes the metadata of an uploaded image with
+// media.Info.StripMetadata. On failure an error response has been written and
+// ok is false.
+func stripImageMetadata(w http.ResponseWriter, data []byte, info *media.Info) (stripped []byte, ok bool) {
+	stripped, err := info.StripMetadata(data)
+	if err != nil {
+		log.Printf("Failed to strip metadata of %s image: %v", info.ContentType, err)
+		http.Error(w, "Invalid image", http.StatusBadRequest)
+		return nil, false
+	}
+	return stripped, true
+}
+
+// readImageUpload reads the image of a multipart form field and validates it
+// with media.Inspect. The form must already be parsed. On failure an error
+// response has been written and ok is false.
+func readImageUpload(w http.ResponseWriter, r *http.Request, field string, maxBytes int64, limits media.Limits) (data []byte, info *media.Info, ok bool) {
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		http.Error(w, "Failed to get file", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	defer file.Close()
+
+	if header.Size > maxBytes {
+		http.Error(w, fmt.Sprintf("Images may not exceed %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+		return nil, nil, false
+	}
+	data, err = io.ReadAll(io.LimitReader(file, maxBytes))
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	info, ok = inspectImage(w, data, limits)
+	if !ok {
+		return nil, nil, false
+	}
+	return data, info, true
+}
+
+// inspectImage validates an image with media.Inspect. On failure an error
+// response has been written and ok is false.
+func inspectImage(w http.ResponseWriter, data []byte, limits media.Limits) (info *media.Info, ok bool) {
+	info, err := media.Inspect(data, limits)
+	var dimErr *media.DimensionError
+	switch {
+	case errors.Is(err, media.ErrUnsupportedFormat):
+		http.Error(w, "Images must be PNG, JPEG, GIF or WebP", http.StatusUnsupportedMediaType)
+		return nil, false
+	case errors.As(err, &dimErr):
+		http.Error(w, fmt.Sprintf("Image of %dx%d pixels is too large", dimErr.Width, dimErr.Height), http.StatusBadRequest)
+		return nil, false
+	case err != nil:
+		http.Error(w, "Invalid image", http.StatusBadRequest)
+		return nil, false
+	}
+	return info, true
+}
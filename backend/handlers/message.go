@@ -2,19 +2,19 @@ package handlers
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
-	"path"
 	"time"
 
-	"chat-room/config"
+	"chat-room/media"
 	"chat-room/middleware"
 	"chat-room/models"
-	"chat-room/s3"
+	"chat-room/moderation"
 	"chat-room/store"
 
 	"github.com/google/uuid"
-	"github.com/minio/minio-go/v7"
 )
 
 type MessageHandler struct {
@@ -26,66 +26,148 @@ func NewMessageHandler(store store.Store, hub *WebSocketHandler) *MessageHandler
 	return &MessageHandler{store: store, hub: hub}
 }
 
-// UploadMessageImage handles image upload for messages
+// UploadMessageImage handles image upload for messages. Metadata such as GPS
+// coordinates is stripped unless the session is configured to preserve it.
+// Thumbnail and medium variants are stored next to the original and described
+// in the message's media.
+// Route: POST /api/sessions/messages/upload
+// Request: multipart form with "image"
+// Response: the posted message, {"id": "uuid", "type": "image", "content": "url", "media": {"width": 1920, "variants": [...]}, ...}
 func (h *MessageHandler) UploadMessageImage(w http.ResponseWriter, r *http.Request) {
 	sessionClaims := middleware.GetSessionClaims(r)
 
 	userID := middleware.GetUserID(r)
 
 	// Parse multipart form
-	err := r.ParseMultipartForm(10 << 20) // 10 MB max
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageUploadBytes+(1<<20))
+	err := r.ParseMultipartForm(maxImageUploadBytes)
 	if err != nil {
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
 
-	file, header, err := r.FormFile("image")
-	if err != nil {
-		http.Error(w, "Failed to get file", http.StatusBadRequest)
+	// The client's file name and content type are not trusted
+	data, info, ok := readImageUpload(w, r, "image", maxImageUploadBytes, imageLimits())
+	if !ok {
 		return
 	}
-	defer file.Close()
 
-	// Generate a unique filename
-	filename := fmt.Sprintf("%s%s", uuid.New().String(), path.Ext(header.Filename))
-	objectName := fmt.Sprintf("messages/%s", filename)
+	postImageMessage(w, r, h.hub, userID, sessionClaims.GroupID, data, info)
+}
 
-	cfg := config.GetConfig()
-	minioClient := s3.GetClient()
+// postImageMessage stores a validated image with its variants, posts it as a
+// message of the session and writes the message as response. Metadata is
+// stripped unless the session is configured to preserve it. Images are content
+// addressed, so an image posted again shares the objects stored for it first,
+// and only counts towards the storage quotas of the user and the session that
+// stored it first.
+func postImageMessage(w http.ResponseWriter, r *http.Request, hub *WebSocketHandler, userID, sessionID uuid.UUID, data []byte, info *media.Info) {
+	if !preservesMetadata(sessionID) {
+		var ok bool
+		if data, ok = stripImageMetadata(w, data, info); !ok {
+			return
+		}
+	}
 
-	// Upload the file to MinIO
-	opts := minio.PutObjectOptions{
-		ContentType: header.Header.Get("Content-Type"),
+	if !checkQuota(w, r, hub.store, userID, &sessionID, int64(len(data))) {
+		return
 	}
-	_, err = minioClient.PutObject(context.Background(), cfg.MinioBucketName, objectName, file, header.Size, opts)
-	if err != nil {
+
+	// Name the image after its content; its variants are named after it
+	objectName := media.ContentObjectName("messages/", data, info.Ext)
+
+	// Upload the file to the blob store, unless it is stored already
+	put := sharingPut(hub.store, userID, &sessionID)
+	if err := put(context.Background(), objectName, data, info.ContentType); err != nil {
 		http.Error(w, "Failed to upload file", http.StatusInternalServerError)
 		return
 	}
 
-	// Generate the public URL
-	publicURL := fmt.Sprintf("http://localhost:9000/%s/%s",
-		cfg.MinioBucketName,
-		objectName,
-	)
+	// Store the thumbnail and medium variants next to the original
+	messageMedia, err := info.StoreVariants(r.Context(), objectName, put)
+	if err != nil {
+		log.Printf("Failed to store variants of %s: %v", objectName, err)
+		deleteObjects(context.Background(), hub.store, mediaObjectNames(objectName, messageMedia)...)
+		http.Error(w, "Failed to upload file", http.StatusInternalServerError)
+		return
+	}
 
 	// Create and save the message in the database
 	message := &models.Message{
 		ID:        uuid.New(),
 		Type:      models.MessageTypeImage,
-		Content:   publicURL,
+		Content:   objectName,
 		UserID:    userID,
-		SessionID: sessionClaims.GroupID,
+		SessionID: sessionID,
 		Timestamp: time.Now().UTC(),
+		Media:     messageMedia,
 	}
 
-	if err := h.store.CreateMessage(r.Context(), message); err != nil {
+	// Save the message and broadcast it through WebSocket
+	if err := hub.postMessage(r.Context(), message); err != nil {
+		// The message is not stored, so the uploaded objects would be orphaned
+		deleteObjects(context.Background(), hub.store, mediaObjectNames(objectName, messageMedia)...)
+
+		var rejected *moderation.RejectedError
+		if errors.As(err, &rejected) {
+			http.Error(w, rejected.Reason, http.StatusUnprocessableEntity)
+			return
+		}
 		http.Error(w, "Failed to save message", http.StatusInternalServerError)
 		return
 	}
 
-	// Broadcast the message through WebSocket
-	h.hub.broadcast(sessionClaims.GroupID, message)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(message)
+}
+
+// postFileMessage posts a stored file as a message of the session and writes
+// the message as response. The file counts towards the storage quotas of the
+// user and the session; it is removed if it cannot be posted.
+func postFileMessage(w http.ResponseWriter, r *http.Request, hub *WebSocketHandler, userID, sessionID uuid.UUID, objectName, filename, contentType string, size int64) {
+	if !checkQuota(w, r, hub.store, userID, &sessionID, size) {
+		deleteObjects(context.Background(), hub.store, objectName)
+		return
+	}
+	err := hub.store.CreateMediaObject(r.Context(), &models.MediaObject{
+		ObjectName: objectName,
+		UserID:     userID,
+		SessionID:  &sessionID,
+		Size:       size,
+	})
+	if err != nil {
+		deleteObjects(context.Background(), hub.store, objectName)
+		http.Error(w, "Failed to save file", http.StatusInternalServerError)
+		return
+	}
+
+	message := &models.Message{
+		ID:        uuid.New(),
+		Type:      models.MessageTypeFile,
+		Content:   objectName,
+		UserID:    userID,
+		SessionID: sessionID,
+		Timestamp: time.Now().UTC(),
+		Media: &models.MessageMedia{
+			Key:         objectName,
+			ContentType: contentType,
+			Name:        filename,
+			Size:        size,
+		},
+	}
+
+	if err := hub.postMessage(r.Context(), message); err != nil {
+		deleteObjects(context.Background(), hub.store, objectName)
+
+		var rejected *moderation.RejectedError
+		if errors.As(err, &rejected) {
+			http.Error(w, rejected.Reason, http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, "Failed to save message", http.StatusInternalServerError)
+		return
+	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(message)
 }
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"chat-room/auth"
+	"chat-room/middleware"
+	"chat-room/models"
+	"chat-room/moderation"
+	"chat-room/store"
+)
+
+// maxModerationFlags is the number of flags returned by GetFlags.
+const maxModerationFlags = 200
+
+// ModerationHandler manages HTTP requests for the moderation settings of a session.
+type ModerationHandler struct {
+	store store.Store
+}
+
+// NewModerationHandler creates a new moderation handler with the given store.
+func NewModerationHandler(store store.Store) *ModerationHandler {
+	return &ModerationHandler{store: store}
+}
+
+// GetSettings returns the moderation settings of the session (creator only).
+// Sessions without settings get empty settings, which allow every message.
+// Route: GET /api/sessions/moderation
+// Response: {"session_id": "uuid", "blocked_words": [], "word_action": "reject", "patterns": [], ...}
+func (h *ModerationHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	sessionID := middleware.GetSessionID(r)
+
+	settings, err := h.store.GetModerationSettings(r.Context(), sessionID)
+	if errors.Is(err, store.ErrNotFound) {
+		settings = &models.ModerationSettings{
+			SessionID:      sessionID,
+			BlockedWords:   []string{},
+			WordAction:     models.ModerationActionReject,
+			Patterns:       []models.ModerationPattern{},
+			BlockedDomains: []string{},
+		}
+	} else if err != nil {
+		http.Error(w, "Failed to get moderation settings", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(settings)
+}
+
+// UpdateSettings replaces the moderation settings of the session (creator only).
+// Route: PUT /api/sessions/moderation
+// Request: {"blocked_words": ["spam"], "word_action": "redact", "patterns": [{"pattern": "\\d{16}", "action": "reject", "reason": "No card numbers"}], "blocked_domains": ["example.com"], "max_length": 1000, "max_repeated_chars": 20}
+// Response: the stored settings
+func (h *ModerationHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var settings models.ModerationSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	settings.BlockedWords = normalizeList(settings.BlockedWords)
+	settings.BlockedDomains = normalizeList(settings.BlockedDomains)
+	if settings.WordAction == "" {
+		settings.WordAction = models.ModerationActionReject
+	}
+	if err := moderation.Validate(&settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings.SessionID = middleware.GetSessionID(r)
+	settings.UpdatedBy = auth.GetUserIDFromContext(r)
+	settings.UpdatedAt = time.Now().UTC()
+	if err := h.store.UpsertModerationSettings(r.Context(), &settings); err != nil {
+		log.Printf("Failed to update moderation settings of session %s: %v", settings.SessionID, err)
+		http.Error(w, "Failed to update moderation settings", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(settings)
+}
+
+// GetFlags returns the most recent messages flagged by moderation (creator only).
+// Route: GET /api/sessions/moderation/flags
+// Response: [{"id": "uuid", "message_id": "uuid", "filter": "blocked_words", "reason": "...", "created_at": "..."}]
+func (h *ModerationHandler) GetFlags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	flags, err := h.store.GetModerationFlagsBySessionID(r.Context(), middleware.GetSessionID(r), maxModerationFlags)
+	if err != nil {
+		http.Error(w, "Failed to get moderation flags", http.StatusInternalServerError)
+		return
+	}
+	if flags == nil {
+		flags = []*models.ModerationFlag{}
+	}
+
+	json.NewEncoder(w).Encode(flags)
+}
+
+// normalizeList trims and lowercases entries and drops empty ones and duplicates.
+func normalizeList(entries []string) []string {
+	seen := make(map[string]bool, len(entries))
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		result = append(result, entry)
+	}
+	return result
+}
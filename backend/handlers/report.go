@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"chat-room/auth"
+	"chat-room/blob"
+	"chat-room/middleware"
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxReportReasonLength limits the reason a member gives for a report.
+	maxReportReasonLength = 1000
+	// reportContextMessages is the number of messages shown before and after a reported message.
+	reportContextMessages = 2
+	// defaultMuteDuration and maxMuteDuration bound mutes issued from the review queue.
+	defaultMuteDuration = time.Hour
+	maxMuteDuration     = 30 * 24 * time.Hour
+)
+
+// Actions a reviewer can take on a report.
+const (
+	reportActionDismiss       = "dismiss"
+	reportActionDeleteMessage = "delete_message"
+	reportActionMuteAuthor    = "mute_author"
+	reportActionKickAuthor    = "kick_author"
+)
+
+// ReportHandler manages HTTP requests for message reports and their review.
+type ReportHandler struct {
+	store store.Store
+	hub   *WebSocketHandler
+}
+
+// NewReportHandler creates a new report handler with the given store.
+// Review actions are recorded in the session history through hub.
+func NewReportHandler(store store.Store, hub *WebSocketHandler) *ReportHandler {
+	return &ReportHandler{store: store, hub: hub}
+}
+
+// Request/Response types
+type (
+	// ReportMessageRequest represents the request body for reporting a message.
+	ReportMessageRequest struct {
+		MessageID uuid.UUID `json:"message_id"`
+		Reason    string    `json:"reason"`
+	}
+
+	// ResolveReportRequest represents the request body for resolving a report.
+	ResolveReportRequest struct {
+		Action string `json:"action"`
+		// MuteMinutes is the length of the mute for the mute_author action.
+		MuteMinutes int `json:"mute_minutes"`
+	}
+
+	// ReportQueueEntry is a report together with the conversation around the reported message.
+	ReportQueueEntry struct {
+		*models.MessageReport
+		// Context holds the reported message and its neighbours, oldest first.
+		// It is empty once the message has been deleted.
+		Context []*models.Message `json:"context"`
+	}
+
+	// ReportQueueResponse represents the review queue of a session.
+	ReportQueueResponse struct {
+		Reports []*ReportQueueEntry `json:"reports"`
+		Users   []*models.User      `json:"users"`
+	}
+)
+
+// ReportMessage reports a message of the session for review by the session creators.
+// Reporting a message again updates the reason of the open report.
+// Route: POST /api/sessions/reports
+// Request: {"message_id": "uuid", "reason": "Spam"}
+// Response: {"id": "uuid", "message_id": "uuid", "status": "open", ...}
+func (h *ReportHandler) ReportMessage(w http.ResponseWriter, r *http.Request) {
+	sessionID := middleware.GetSessionID(r)
+
+	var req ReportMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		http.Error(w, "Reason is required", http.StatusBadRequest)
+		return
+	}
+	if len([]rune(req.Reason)) > maxReportReasonLength {
+		http.Error(w, "Reason is too long", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.store.GetMessageByID(r.Context(), req.MessageID)
+	if err != nil || message.SessionID != sessionID {
+		http.Error(w, "Message not found", http.StatusNotFound)
+		return
+	}
+	if message.Type == models.MessageTypeSystem {
+		http.Error(w, "System messages cannot be reported", http.StatusBadRequest)
+		return
+	}
+
+	report := &models.MessageReport{
+		MessageID:   message.ID,
+		SessionID:   sessionID,
+		ReporterID:  auth.GetUserIDFromContext(r),
+		AuthorID:    message.UserID,
+		MessageType: message.Type,
+		Content:     message.Content,
+		Reason:      req.Reason,
+	}
+	if err := h.store.CreateMessageReport(r.Context(), report); err != nil {
+		log.Printf("Failed to report message %s: %v", message.ID, err)
+		http.Error(w, "Failed to report message", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetReports returns the review queue of the session (creators and moderators).
+// Route: GET /api/sessions/reports
+// Query parameters:
+//   - status: open (default), resolved or all
+//   - limit: maximum number of reports (default 50, max 200)
+//
+// Response: {"reports": [{"id": "uuid", "reason": "...", "context": [...]}], "users": [...]}
+func (h *ReportHandler) GetReports(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	sessionID := middleware.GetSessionID(r)
+
+	var status models.ReportStatus
+	switch r.URL.Query().Get("status") {
+	case "", string(models.ReportStatusOpen):
+		status = models.ReportStatusOpen
+	case string(models.ReportStatusResolved):
+		status = models.ReportStatusResolved
+	case "all":
+	default:
+		http.Error(w, "Invalid report status", http.StatusBadRequest)
+		return
+	}
+
+	limit := parsePaginationLimit(r, 50, 200)
+
+	reports, err := h.store.GetMessageReportsBySessionID(r.Context(), sessionID, status, limit)
+	if err != nil {
+		http.Error(w, "Error fetching reports", http.StatusInternalServerError)
+		return
+	}
+
+	queue, err := h.buildQueue(r.Context(), sessionID, reports)
+	if err != nil {
+		log.Printf("Failed to load report context of session %s: %v", sessionID, err)
+		http.Error(w, "Error fetching reports", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(queue)
+}
+
+// buildQueue adds the surrounding messages to reports and collects the
+// profiles of reporters, authors and context senders.
+func (h *ReportHandler) buildQueue(ctx context.Context, sessionID uuid.UUID, reports []*models.MessageReport) (*ReportQueueResponse, error) {
+	queue := &ReportQueueResponse{
+		Reports: make([]*ReportQueueEntry, 0, len(reports)),
+		Users:   []*models.User{},
+	}
+	if len(reports) == 0 {
+		return queue, nil
+	}
+
+	messageIDs := make([]uuid.UUID, 0, len(reports))
+	for _, report := range reports {
+		messageIDs = append(messageIDs, report.MessageID)
+	}
+	messages, err := h.store.GetMessagesByIDs(ctx, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	messagesByID := make(map[uuid.UUID]*models.Message, len(messages))
+	for _, message := range messages {
+		messagesByID[message.ID] = message
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var userIDs []uuid.UUID
+	addUser := func(id uuid.UUID) {
+		if !seen[id] {
+			seen[id] = true
+			userIDs = append(userIDs, id)
+		}
+	}
+
+	for _, report := range reports {
+		entry := &ReportQueueEntry{MessageReport: report, Context: []*models.Message{}}
+		addUser(report.ReporterID)
+		addUser(report.AuthorID)
+
+		if message, ok := messagesByID[report.MessageID]; ok {
+			entry.Context, err = h.messageContext(ctx, sessionID, message)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range entry.Context {
+				addUser(m.UserID)
+			}
+		}
+		queue.Reports = append(queue.Reports, entry)
+	}
+
+	var contextMessages []*models.Message
+	for _, entry := range queue.Reports {
+		contextMessages = append(contextMessages, entry.Context...)
+	}
+	prepareMessages(ctx, h.store, contextMessages)
+
+	users, err := h.store.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	resolveAvatars(ctx, users)
+	queue.Users = append(queue.Users, users...)
+	return queue, nil
+}
+
+// messageContext returns message with up to reportContextMessages messages on each side, oldest first.
+func (h *ReportHandler) messageContext(ctx context.Context, sessionID uuid.UUID, message *models.Message) ([]*models.Message, error) {
+	cursor := store.CursorOf(message)
+	before, err := h.store.GetMessagesBySessionID(ctx, sessionID, &cursor, store.PageBefore, reportContextMessages)
+	if err != nil {
+		return nil, err
+	}
+	after, err := h.store.GetMessagesBySessionID(ctx, sessionID, &cursor, store.PageAfter, reportContextMessages)
+	if err != nil {
+		return nil, err
+	}
+
+	reverseMessages(before)
+	return append(append(before, message), after...), nil
+}
+
+// ResolveReport takes action on a report and resolves all open reports of
+// the same message (creators and moderators).
+// Route: POST /api/sessions/reports/{id}/resolve
+// Request: {"action": "dismiss" | "delete_message" | "mute_author" | "kick_author", "mute_minutes": 60}
+// Response: {"id": "uuid", "status": "resolved", "resolution": "author_muted", ...}
+func (h *ReportHandler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	sessionID := middleware.GetSessionID(r)
+	actorID := auth.GetUserIDFromContext(r)
+
+	reportID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid report ID", http.StatusBadRequest)
+		return
+	}
+
+	var req ResolveReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.store.GetMessageReportByID(r.Context(), reportID)
+	if err != nil || report.SessionID != sessionID {
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return
+	}
+	if report.Status != models.ReportStatusOpen {
+		http.Error(w, "Report is already resolved", http.StatusConflict)
+		return
+	}
+
+	var resolution models.ReportResolution
+	switch req.Action {
+	case reportActionDismiss:
+		resolution = models.ReportResolutionDismissed
+	case reportActionDeleteMessage:
+		resolution = models.ReportResolutionMessageDeleted
+		if !h.deleteMessage(w, r, report, actorID) {
+			return
+		}
+	case reportActionMuteAuthor:
+		resolution = models.ReportResolutionAuthorMuted
+		if !h.muteAuthor(w, r, report, actorID, req.MuteMinutes) {
+			return
+		}
+	case reportActionKickAuthor:
+		resolution = models.ReportResolutionAuthorKicked
+		if h.authorMembership(w, r, report) == nil {
+			return
+		}
+		if err := kickMember(r.Context(), h.store, h.hub, sessionID, actorID, report.AuthorID); err != nil {
+			http.Error(w, "Failed to kick member", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Action must be dismiss, delete_message, mute_author or kick_author", http.StatusBadRequest)
+		return
+	}
+
+	resolvedAt := time.Now().UTC()
+	if err := h.store.ResolveMessageReports(r.Context(), report.MessageID, resolution, actorID, resolvedAt); err != nil {
+		log.Printf("Failed to resolve reports of message %s: %v", report.MessageID, err)
+		http.Error(w, "Failed to resolve report", http.StatusInternalServerError)
+		return
+	}
+
+	report.Status = models.ReportStatusResolved
+	report.Resolution = resolution
+	report.ResolvedBy = &actorID
+	report.ResolvedAt = &resolvedAt
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// deleteMessage removes the reported message and its uploaded image, if any.
+// It writes an error response and returns false on failure.
+func (h *ReportHandler) deleteMessage(w http.ResponseWriter, r *http.Request, report *models.MessageReport, actorID uuid.UUID) bool {
+	message, err := h.store.GetMessageByID(r.Context(), report.MessageID)
+	if errors.Is(err, store.ErrNotFound) {
+		// Already deleted through another report
+		return true
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete message", http.StatusInternalServerError)
+		return false
+	}
+
+	if err := h.store.DeleteMessage(r.Context(), message.ID); err != nil {
+		http.Error(w, "Failed to delete message", http.StatusInternalServerError)
+		return false
+	}
+
+	if message.Type == models.MessageTypeImage || message.Type == models.MessageTypeFile {
+		objectNames := mediaObjectNames(blob.ObjectNameOf(message.Content), message.Media)
+		deleteObjects(r.Context(), h.store, objectNames...)
+	}
+
+	h.hub.postSystemEvent(r.Context(), report.SessionID, models.SystemEvent{
+		Action:    models.SystemActionMessageRemoved,
+		ActorID:   actorID,
+		TargetID:  &message.UserID,
+		MessageID: &message.ID,
+	})
+	return true
+}
+
+// muteAuthor mutes the author of the reported message for minutes, or for
+// defaultMuteDuration if minutes is zero. It writes an error response and
+// returns false on failure.
+func (h *ReportHandler) muteAuthor(w http.ResponseWriter, r *http.Request, report *models.MessageReport, actorID uuid.UUID, minutes int) bool {
+	duration := time.Duration(minutes) * time.Minute
+	if minutes == 0 {
+		duration = defaultMuteDuration
+	}
+	if duration <= 0 || duration > maxMuteDuration {
+		http.Error(w, "Mute must last between 1 minute and 30 days", http.StatusBadRequest)
+		return false
+	}
+
+	if h.authorMembership(w, r, report) == nil {
+		return false
+	}
+
+	mutedUntil := time.Now().UTC().Add(duration)
+	if err := h.store.SetUserSessionMutedUntil(r.Context(), report.AuthorID, report.SessionID, &mutedUntil); err != nil {
+		http.Error(w, "Failed to mute member", http.StatusInternalServerError)
+		return false
+	}
+
+	h.hub.postSystemEvent(r.Context(), report.SessionID, models.SystemEvent{
+		Action:     models.SystemActionMemberMuted,
+		ActorID:    actorID,
+		TargetID:   &report.AuthorID,
+		MutedUntil: &mutedUntil,
+	})
+	return true
+}
+
+// authorMembership returns the membership of the reported author, who must
+// still be in the session and must not be a creator. Moderators can only act
+// against other moderators if they are creators themselves. It writes an
+// error response and returns nil otherwise.
+func (h *ReportHandler) authorMembership(w http.ResponseWriter, r *http.Request, report *models.MessageReport) *models.UserSession {
+	userSessions, err := h.store.GetUserSessionsBySessionIDAndUserIDs(r.Context(), report.SessionID, []uuid.UUID{report.AuthorID})
+	if err != nil || len(userSessions) == 0 {
+		http.Error(w, "Author is no longer a member of the session", http.StatusConflict)
+		return nil
+	}
+	if userSessions[0].Role == "creator" {
+		http.Error(w, "Cannot take action against a creator", http.StatusBadRequest)
+		return nil
+	}
+	if userSessions[0].Role == "moderator" && middleware.GetSessionClaims(r).Role != "creator" {
+		http.Error(w, "Cannot take action against a moderator", http.StatusForbidden)
+		return nil
+	}
+	return userSessions[0]
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chat-room/auth"
+	"chat-room/blob"
+	"chat-room/config"
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// Resumable uploads follow the core protocol of tus (https://tus.io): the
+// client requests the progress of an upload with HEAD and continues it with
+// PATCH requests sending the chunk at the upload's offset. Each chunk is
+// stored as a part of a multipart upload of the blob store, so the file is
+// never held by the server as a whole.
+const (
+	// tusVersion is the version of the tus protocol the responses follow.
+	tusVersion = "1.0.0"
+	// resumableChunkSize is the size of the chunks of resumable uploads but
+	// the last, the minimum size of multipart upload parts.
+	resumableChunkSize = blob.MinPartSize
+	// offsetContentType is the content type of the chunks.
+	offsetContentType = "application/offset+octet-stream"
+)
+
+// CreateResumableUploadResponse tells the client where to send the chunks of the file.
+type CreateResumableUploadResponse struct {
+	UploadID  uuid.UUID `json:"upload_id"`
+	UploadURL string    `json:"upload_url"`
+	// ChunkSize is the size of every chunk but the last.
+	ChunkSize int64     `json:"chunk_size"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CreateResumableUpload reserves a slot for a file uploaded in chunks, for
+// files shared in messages or for images. The slot accepts chunks until it
+// expires, so an interrupted upload can be continued later.
+// Route: POST /api/uploads/resumable
+// Request: {"purpose": "message_file" | "message_image" | "avatar", "session_id": "uuid", "size": 123456, "content_type": "application/pdf", "filename": "notes.pdf"}
+// Response: {"upload_id": "uuid", "upload_url": "/api/uploads/resumable/uuid", "chunk_size": 5242880, "expires_at": "..."}, with the upload URL as Location header
+func (h *UploadHandler) CreateResumableUpload(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r)
+
+	var req CreateUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if !h.checkUploadRequest(w, r, userID, &req) {
+		return
+	}
+
+	id := uuid.New()
+	upload := &models.Upload{
+		ID:          id,
+		UserID:      userID,
+		Purpose:     req.Purpose,
+		SessionID:   req.SessionID,
+		ObjectName:  fmt.Sprintf("uploads/%s", id),
+		Size:        req.Size,
+		ContentType: req.ContentType,
+		Filename:    req.Filename,
+		CreatedAt:   time.Now().UTC(),
+	}
+	upload.ExpiresAt = upload.CreatedAt.Add(config.GetConfig().MediaResumableTTL)
+
+	// Files are not moved when the upload completes. They are stored as
+	// binary data, so that browsers download rather than render them.
+	contentType := req.ContentType
+	if req.Purpose == models.UploadPurposeMessageFile {
+		upload.ObjectName = fmt.Sprintf("files/%s", id)
+		contentType = "application/octet-stream"
+	}
+
+	var err error
+	upload.MultipartID, err = blob.CreateMultipart(r.Context(), upload.ObjectName, contentType)
+	if err != nil {
+		log.Printf("Failed to create multipart upload of upload %s: %v", id, err)
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+	if err := h.store.CreateUpload(r.Context(), upload); err != nil {
+		if err := blob.AbortMultipart(r.Context(), upload.ObjectName, upload.MultipartID); err != nil {
+			log.Printf("Failed to abort multipart upload of upload %s: %v", id, err)
+		}
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+
+	uploadURL := fmt.Sprintf("/api/uploads/resumable/%s", id)
+	w.Header().Set("Location", uploadURL)
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateResumableUploadResponse{
+		UploadID:  id,
+		UploadURL: uploadURL,
+		ChunkSize: resumableChunkSize,
+		ExpiresAt: upload.ExpiresAt,
+	})
+}
+
+// GetResumableUpload reports the progress of a resumable upload, to continue
+// it after an interruption.
+// Route: HEAD /api/uploads/resumable/{id}
+// Response: no body, with the Upload-Offset, Upload-Length and Upload-Expires headers
+func (h *UploadHandler) GetResumableUpload(w http.ResponseWriter, r *http.Request) {
+	upload, ok := h.getResumableUpload(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+}
+
+// PatchResumableUpload stores the chunk of a resumable upload at its offset.
+// Every chunk but the last must have the chunk size of the upload; the last
+// chunk assembles the file, which is then used by completing the upload.
+// Route: PATCH /api/uploads/resumable/{id}
+// Request: the chunk, with Content-Type application/offset+octet-stream and the Upload-Offset header
+// Response: 204 with the new Upload-Offset header
+func (h *UploadHandler) PatchResumableUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != offsetContentType {
+		http.Error(w, "Content type must be "+offsetContentType, http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Invalid Upload-Offset header", http.StatusBadRequest)
+		return
+	}
+
+	upload, ok := h.getResumableUpload(w, r)
+	if !ok {
+		return
+	}
+	if upload.Status != models.UploadStatusPending {
+		http.Error(w, "Upload is already completed", http.StatusConflict)
+		return
+	}
+	if offset != upload.Offset {
+		http.Error(w, fmt.Sprintf("Upload is at offset %d", upload.Offset), http.StatusConflict)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, resumableChunkSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Chunks may not exceed %d bytes", resumableChunkSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+	newOffset := offset + int64(len(data))
+	switch {
+	case len(data) == 0:
+		http.Error(w, "Chunk is empty", http.StatusBadRequest)
+		return
+	case newOffset > upload.Size:
+		http.Error(w, "Chunk exceeds the upload's size", http.StatusBadRequest)
+		return
+	case newOffset < upload.Size && len(data) != resumableChunkSize:
+		http.Error(w, fmt.Sprintf("Chunks but the last must have %d bytes", resumableChunkSize), http.StatusBadRequest)
+		return
+	}
+
+	number := int(offset/resumableChunkSize) + 1
+	part, err := blob.PutPart(r.Context(), upload.ObjectName, upload.MultipartID, number, data)
+	if err != nil {
+		log.Printf("Failed to store part %d of upload %s: %v", number, upload.ID, err)
+		http.Error(w, "Failed to store chunk", http.StatusInternalServerError)
+		return
+	}
+
+	// The file is assembled before the offset is advanced, so a failure can
+	// be retried by sending the last chunk again
+	if newOffset == upload.Size {
+		parts := make([]blob.Part, 0, len(upload.Parts)+1)
+		for _, p := range upload.Parts {
+			parts = append(parts, blob.Part{Number: p.Number, ETag: p.ETag})
+		}
+		parts = append(parts, part)
+		if err := blob.CompleteMultipart(r.Context(), upload.ObjectName, upload.MultipartID, parts); err != nil {
+			log.Printf("Failed to assemble upload %s: %v", upload.ID, err)
+			http.Error(w, "Failed to store chunk", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = h.store.AdvanceUpload(r.Context(), upload.ID, offset, newOffset, models.UploadPart{Number: part.Number, ETag: part.ETag})
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Upload has been continued concurrently", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to store chunk", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getResumableUpload loads the resumable upload of the URL, which must belong
+// to the user and not be expired. On failure an error response has been
+// written and ok is false.
+func (h *UploadHandler) getResumableUpload(w http.ResponseWriter, r *http.Request) (upload *models.Upload, ok bool) {
+	userID := auth.GetUserIDFromContext(r)
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid upload ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	upload, err = h.store.GetUploadByID(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && (upload.UserID != userID || upload.MultipartID == "")) {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to get upload", http.StatusInternalServerError)
+		return nil, false
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		http.Error(w, "Upload has expired", http.StatusGone)
+		return nil, false
+	}
+	return upload, true
+}
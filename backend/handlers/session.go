@@ -3,8 +3,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"chat-room/auth"
@@ -20,13 +22,16 @@ import (
 type SessionHandler struct {
 	store        store.Store
 	tokenManager *token.TokenManager
+	hub          *WebSocketHandler
 }
 
 // NewSessionHandler creates a new session handler with the given store and token manager.
-func NewSessionHandler(store store.Store, tokenManager *token.TokenManager) *SessionHandler {
+// Session changes are recorded in the session history through hub.
+func NewSessionHandler(store store.Store, tokenManager *token.TokenManager, hub *WebSocketHandler) *SessionHandler {
 	return &SessionHandler{
 		store:        store,
 		tokenManager: tokenManager,
+		hub:          hub,
 	}
 }
 
@@ -37,6 +42,11 @@ type (
 		Name string `json:"name"`
 	}
 
+	// RenameSessionRequest represents the request body for renaming a session.
+	RenameSessionRequest struct {
+		Name string `json:"name"`
+	}
+
 	// SessionResponse represents a session with its member users.
 	SessionResponse struct {
 		*models.Session
@@ -63,6 +73,16 @@ type (
 		Token string `json:"token"`
 	}
 
+	// MessagePageResponse represents a page of messages with their senders.
+	MessagePageResponse struct {
+		Messages      []*models.Message `json:"messages"`
+		Users         []*models.User    `json:"users"`
+		PrevCursor    string            `json:"prev_cursor"`
+		NextCursor    string            `json:"next_cursor"`
+		HasMoreBefore bool              `json:"has_more_before"`
+		HasMoreAfter  bool              `json:"has_more_after"`
+	}
+
 	// ShareInfoResponse represents information about a shared session.
 	ShareInfoResponse struct {
 		SessionName     string `json:"session_name"`
@@ -86,49 +106,224 @@ func parsePaginationLimit(r *http.Request, defaultLimit, maxLimit int) int {
 	return limit
 }
 
-// parsePaginationBefore parses the before query parameter.
-// Returns the current time if no valid before parameter is provided.
-func parsePaginationBefore(r *http.Request) time.Time {
-	if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
-		if parsedBefore, err := time.Parse(time.RFC3339, beforeStr); err == nil {
-			return parsedBefore
-		}
-	}
-	return time.Now().UTC()
-}
-
-// GetMessageIDsBySessionID returns message IDs for a session with pagination.
-// Route: GET /api/sessions/messages/ids
-// Query parameters:
+// GetMessages returns a page of hydrated messages together with their senders.
+// Route: GET /api/sessions/messages
+// Query parameters (at most one of before, after, around and after_seq):
+//   - before: cursor; return the messages preceding it (default: the latest messages)
+//   - after: cursor; return the messages following it
+//   - around: message ID; return the message centered between its neighbours
+//   - after_seq: sequence number; return the messages following it in seq order,
+//     used to fill gaps detected in the seq numbers received over the WebSocket
 //   - limit: maximum number of messages to return (default: 50, max: 100)
-//   - before: timestamp to get messages before (default: now)
 //
-// Response: {"message_ids": ["uuid1", "uuid2", ...], "has_more": bool}
-func (h *SessionHandler) GetMessageIDsBySessionID(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+// Response: {"messages": [...], "users": [...], "prev_cursor": "...", "next_cursor": "...",
+// "has_more_before": bool, "has_more_after": bool}
+// Messages are ordered oldest first. prev_cursor and next_cursor are passed as
+// before and after to load the adjacent pages.
+func (h *SessionHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	sessionID := middleware.GetSessionID(r)
-
+	query := r.URL.Query()
 	limit := parsePaginationLimit(r, 50, 100)
-	before := parsePaginationBefore(r)
 
-	messageIDs, err := h.store.GetMessageIDsBySessionID(r.Context(), sessionID, limit+1, before)
+	given := 0
+	for _, param := range []string{"before", "after", "around", "after_seq"} {
+		if query.Get(param) != "" {
+			given++
+		}
+	}
+	if given > 1 {
+		http.Error(w, "Only one of before, after, around and after_seq may be given", http.StatusBadRequest)
+		return
+	}
+
+	var page *MessagePageResponse
+	var err error
+	switch {
+	case query.Get("after_seq") != "":
+		afterSeq, parseErr := strconv.ParseInt(query.Get("after_seq"), 10, 64)
+		if parseErr != nil || afterSeq < 0 {
+			http.Error(w, "Invalid sequence number", http.StatusBadRequest)
+			return
+		}
+		page, err = h.messagesAfterSeq(r, sessionID, afterSeq, limit)
+	case query.Get("around") != "":
+		messageID, parseErr := uuid.Parse(query.Get("around"))
+		if parseErr != nil {
+			http.Error(w, "Invalid message ID", http.StatusBadRequest)
+			return
+		}
+		anchor, getErr := h.store.GetMessageByID(r.Context(), messageID)
+		if errors.Is(getErr, store.ErrNotFound) || (getErr == nil && anchor.SessionID != sessionID) {
+			http.Error(w, "Message not found", http.StatusNotFound)
+			return
+		}
+		if getErr != nil {
+			http.Error(w, "Error fetching messages", http.StatusInternalServerError)
+			return
+		}
+		page, err = h.messagesAround(r, sessionID, anchor, limit)
+	case query.Get("after") != "":
+		cursor, parseErr := store.DecodeMessageCursor(query.Get("after"))
+		if parseErr != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		page, err = h.messagePage(r, sessionID, &cursor, store.PageAfter, limit)
+	case query.Get("before") != "":
+		cursor, parseErr := store.DecodeMessageCursor(query.Get("before"))
+		if parseErr != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		page, err = h.messagePage(r, sessionID, &cursor, store.PageBefore, limit)
+	default:
+		page, err = h.messagePage(r, sessionID, nil, store.PageBefore, limit)
+	}
 	if err != nil {
-		http.Error(w, "Error fetching message IDs", http.StatusInternalServerError)
+		http.Error(w, "Error fetching messages", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.hydrateSenders(r, page); err != nil {
+		http.Error(w, "Error fetching users", http.StatusInternalServerError)
 		return
 	}
+	prepareMessages(r.Context(), h.store, page.Messages)
 
-	hasMore := len(messageIDs) > limit
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// messagePage loads up to limit messages on one side of cursor.
+func (h *SessionHandler) messagePage(r *http.Request, sessionID uuid.UUID, cursor *store.MessageCursor, direction store.PageDirection, limit int) (*MessagePageResponse, error) {
+	messages, err := h.store.GetMessagesBySessionID(r.Context(), sessionID, cursor, direction, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(messages) > limit
 	if hasMore {
-		messageIDs = messageIDs[:limit]
+		messages = messages[:limit]
 	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message_ids": messageIDs,
-		"has_more":    hasMore,
-	})
+	page := &MessagePageResponse{Messages: messages}
+	if direction == store.PageBefore {
+		reverseMessages(page.Messages)
+		page.HasMoreBefore = hasMore
+		page.HasMoreAfter = cursor != nil
+	} else {
+		page.HasMoreBefore = cursor != nil
+		page.HasMoreAfter = hasMore
+	}
+	page.setCursors(cursor)
+	return page, nil
+}
+
+// messagesAfterSeq loads up to limit messages following afterSeq.
+func (h *SessionHandler) messagesAfterSeq(r *http.Request, sessionID uuid.UUID, afterSeq int64, limit int) (*MessagePageResponse, error) {
+	messages, err := h.store.GetMessagesAfterSeq(r.Context(), sessionID, afterSeq, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(messages) > limit
+	if hasMore {
+		messages = messages[:limit]
+	}
+
+	page := &MessagePageResponse{
+		Messages:      messages,
+		HasMoreBefore: afterSeq > 0,
+		HasMoreAfter:  hasMore,
+	}
+	page.setCursors(nil)
+	return page, nil
 }
 
-// PostFetchMessages retrieves messages by their IDs.
+// messagesAround loads the anchor message with up to limit messages split
+// evenly before and after it.
+func (h *SessionHandler) messagesAround(r *http.Request, sessionID uuid.UUID, anchor *models.Message, limit int) (*MessagePageResponse, error) {
+	cursor := store.CursorOf(anchor)
+	beforeLimit := (limit - 1) / 2
+	afterLimit := limit - 1 - beforeLimit
+
+	before, err := h.messagePage(r, sessionID, &cursor, store.PageBefore, beforeLimit)
+	if err != nil {
+		return nil, err
+	}
+	after, err := h.messagePage(r, sessionID, &cursor, store.PageAfter, afterLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &MessagePageResponse{
+		Messages:      append(append(before.Messages, anchor), after.Messages...),
+		HasMoreBefore: before.HasMoreBefore,
+		HasMoreAfter:  after.HasMoreAfter,
+	}
+	page.setCursors(&cursor)
+	return page, nil
+}
+
+// hydrateSenders fills in the profiles of the senders of the page's messages
+// and of the users targeted by its system events.
+func (h *SessionHandler) hydrateSenders(r *http.Request, page *MessagePageResponse) error {
+	page.Users = []*models.User{}
+	if len(page.Messages) == 0 {
+		return nil
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var userIDs []uuid.UUID
+	add := func(id uuid.UUID) {
+		if !seen[id] {
+			seen[id] = true
+			userIDs = append(userIDs, id)
+		}
+	}
+	for _, message := range page.Messages {
+		add(message.UserID)
+		if message.Type != models.MessageTypeSystem {
+			continue
+		}
+		if event, err := message.SystemEvent(); err == nil && event.TargetID != nil {
+			add(*event.TargetID)
+		}
+	}
+
+	users, err := h.store.GetUsersByIDs(r.Context(), userIDs)
+	if err != nil {
+		return err
+	}
+	resolveAvatars(r.Context(), users)
+	page.Users = append(page.Users, users...)
+	return nil
+}
+
+// setCursors points the page cursors at its first and last message.
+// An empty page keeps the cursor it was loaded from, so that clients can poll it.
+func (p *MessagePageResponse) setCursors(from *store.MessageCursor) {
+	if p.Messages == nil {
+		p.Messages = []*models.Message{}
+	}
+	if len(p.Messages) == 0 {
+		if from != nil {
+			p.PrevCursor = from.Encode()
+			p.NextCursor = from.Encode()
+		}
+		return
+	}
+	p.PrevCursor = store.CursorOf(p.Messages[0]).Encode()
+	p.NextCursor = store.CursorOf(p.Messages[len(p.Messages)-1]).Encode()
+}
+
+func reverseMessages(messages []*models.Message) {
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+}
+
+// PostFetchMessages retrieves messages of the session by their IDs.
 // Route: POST /api/messages/batch
 // Request: {"ids": ["uuid1", "uuid2", ...]}
 // Response: {"messages": [{"id": "uuid", "content": "text", ...}]}
@@ -150,6 +345,17 @@ func (h *SessionHandler) PostFetchMessages(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Messages of other sessions are omitted like unknown IDs
+	sessionID := middleware.GetSessionID(r)
+	visible := make([]*models.Message, 0, len(messages))
+	for _, message := range messages {
+		if message.SessionID == sessionID {
+			visible = append(visible, message)
+		}
+	}
+	messages = visible
+	prepareMessages(r.Context(), h.store, messages)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"messages": messages,
@@ -193,6 +399,7 @@ func (h *SessionHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error fetching users", http.StatusInternalServerError)
 		return
 	}
+	resolveAvatars(r.Context(), users)
 
 	response := SessionResponse{
 		Session: session,
@@ -225,6 +432,7 @@ func (h *SessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Error fetching users", http.StatusInternalServerError)
 		return
 	}
+	resolveAvatars(r.Context(), users)
 
 	response := SessionResponse{
 		Session: session,
@@ -235,6 +443,50 @@ func (h *SessionHandler) GetSession(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// RenameSession changes the name of the session (creator only).
+// Route: PUT /api/sessions/name
+// Request: {"name": "new name"}
+// Response: {"id": "uuid", "name": "new name", "creator_id": "uuid", ...}
+func (h *SessionHandler) RenameSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := middleware.GetSessionID(r)
+
+	var req RenameSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.store.GetSessionByID(r.Context(), sessionID)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	oldName := session.Name
+	if oldName != req.Name {
+		session.Name = req.Name
+		if err := h.store.UpdateSession(r.Context(), session); err != nil {
+			http.Error(w, "Failed to rename session", http.StatusInternalServerError)
+			return
+		}
+
+		h.hub.postSystemEvent(r.Context(), sessionID, models.SystemEvent{
+			Action:  models.SystemActionSessionRenamed,
+			ActorID: auth.GetUserIDFromContext(r),
+			OldName: oldName,
+			NewName: req.Name,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
 func (h *SessionHandler) CheckRole(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	sessionClaims := middleware.GetSessionClaims(r)
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"chat-room/auth"
+	"chat-room/blob"
+	"chat-room/config"
+	"chat-room/media"
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// UploadHandler manages HTTP requests for files uploaded directly to storage.
+//
+// The client requests an upload slot, PUTs the file to the returned presigned
+// URL, or PATCHes it in chunks for resumable uploads, and completes the
+// upload. Images are only used once they have been verified like images
+// uploaded through the API.
+type UploadHandler struct {
+	store store.Store
+	hub   *WebSocketHandler
+}
+
+// NewUploadHandler creates a new upload handler with the given store.
+// Completed message images are posted through hub.
+func NewUploadHandler(store store.Store, hub *WebSocketHandler) *UploadHandler {
+	return &UploadHandler{store: store, hub: hub}
+}
+
+// Request/Response types
+type (
+	// CreateUploadRequest represents the request body for requesting an upload slot.
+	CreateUploadRequest struct {
+		Purpose     models.UploadPurpose `json:"purpose"`
+		SessionID   *uuid.UUID           `json:"session_id"`
+		Size        int64                `json:"size"`
+		ContentType string               `json:"content_type"`
+		// Filename is required for message files.
+		Filename string `json:"filename"`
+	}
+
+	// CompleteUploadRequest represents the optional request body for completing an upload.
+	CompleteUploadRequest struct {
+		// Crop selects the part of an avatar image used.
+		Crop *CropRect `json:"crop"`
+	}
+
+	// CreateUploadResponse tells the client where to upload the file.
+	CreateUploadResponse struct {
+		UploadID  uuid.UUID `json:"upload_id"`
+		UploadURL string    `json:"upload_url"`
+		Method    string    `json:"method"`
+		// Headers must be sent with the upload request.
+		Headers   map[string]string `json:"headers"`
+		ExpiresAt time.Time         `json:"expires_at"`
+	}
+)
+
+// CreateUpload reserves a slot for a file uploaded directly to storage.
+// Message images may only be uploaded by members of the session, and files
+// exceeding the storage quota are rejected before they are uploaded.
+// Route: POST /api/uploads
+// Request: {"purpose": "message_image" | "avatar", "session_id": "uuid", "size": 123456, "content_type": "image/png"}
+// Response: {"upload_id": "uuid", "upload_url": "...", "method": "PUT", "headers": {"Content-Type": "image/png"}, "expires_at": "..."}
+func (h *UploadHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r)
+
+	var req CreateUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if req.Purpose == models.UploadPurposeMessageFile {
+		http.Error(w, "Files must be uploaded with resumable uploads", http.StatusBadRequest)
+		return
+	}
+	if !h.checkUploadRequest(w, r, userID, &req) {
+		return
+	}
+
+	ttl := config.GetConfig().MediaUploadTTL
+	id := uuid.New()
+	upload := &models.Upload{
+		ID:          id,
+		UserID:      userID,
+		Purpose:     req.Purpose,
+		SessionID:   req.SessionID,
+		ObjectName:  fmt.Sprintf("uploads/%s", id),
+		Size:        req.Size,
+		ContentType: req.ContentType,
+		CreatedAt:   time.Now().UTC(),
+	}
+	upload.ExpiresAt = upload.CreatedAt.Add(ttl)
+
+	uploadURL, err := blob.UploadURL(r.Context(), upload.ObjectName, ttl)
+	if err != nil {
+		log.Printf("Failed to presign upload %s: %v", id, err)
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+	if err := h.store.CreateUpload(r.Context(), upload); err != nil {
+		http.Error(w, "Failed to create upload", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(CreateUploadResponse{
+		UploadID:  id,
+		UploadURL: uploadURL,
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": req.ContentType},
+		ExpiresAt: upload.ExpiresAt,
+	})
+}
+
+// checkUploadRequest validates the request for an upload slot: the size and
+// content type allowed for its purpose, the membership of the session for
+// message uploads and the storage quotas, which are checked again when the
+// upload completes. On failure an error response has been written and ok is
+// false.
+func (h *UploadHandler) checkUploadRequest(w http.ResponseWriter, r *http.Request, userID uuid.UUID, req *CreateUploadRequest) (ok bool) {
+	if req.Size <= 0 {
+		http.Error(w, "Size is required", http.StatusBadRequest)
+		return false
+	}
+
+	switch req.Purpose {
+	case models.UploadPurposeMessageImage, models.UploadPurposeAvatar:
+		if req.Size > maxImageUploadBytes {
+			http.Error(w, fmt.Sprintf("Images may not exceed %d bytes", maxImageUploadBytes), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		switch req.ContentType {
+		case media.TypePNG, media.TypeJPEG, media.TypeGIF, media.TypeWebP:
+		default:
+			http.Error(w, "Images must be PNG, JPEG, GIF or WebP", http.StatusUnsupportedMediaType)
+			return false
+		}
+		req.Filename = ""
+	case models.UploadPurposeMessageFile:
+		if maxBytes := config.GetConfig().MediaMaxFileBytes; req.Size > maxBytes {
+			http.Error(w, fmt.Sprintf("Files may not exceed %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		if req.ContentType == "" {
+			req.ContentType = "application/octet-stream"
+		} else if _, _, err := mime.ParseMediaType(req.ContentType); err != nil {
+			http.Error(w, "Invalid content type", http.StatusBadRequest)
+			return false
+		}
+		if req.Filename = cleanFilename(req.Filename); req.Filename == "" {
+			http.Error(w, "Filename is required", http.StatusBadRequest)
+			return false
+		}
+	default:
+		http.Error(w, "Invalid purpose", http.StatusBadRequest)
+		return false
+	}
+
+	if req.Purpose == models.UploadPurposeAvatar {
+		req.SessionID = nil
+	} else {
+		if req.SessionID == nil {
+			http.Error(w, "Session ID is required", http.StatusBadRequest)
+			return false
+		}
+		userSessions, err := h.store.GetUserSessionsBySessionIDAndUserIDs(r.Context(), *req.SessionID, []uuid.UUID{userID})
+		if err != nil {
+			http.Error(w, "Failed to check membership", http.StatusInternalServerError)
+			return false
+		}
+		if len(userSessions) == 0 {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return false
+		}
+	}
+
+	return checkQuota(w, r, h.store, userID, req.SessionID, req.Size)
+}
+
+// maxFilenameLength limits the length of the names of message files, in runes.
+const maxFilenameLength = 255
+
+// cleanFilename returns the base name of a client's file name without
+// control characters, which may be empty.
+func cleanFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "." || name == "/" || name == ".." {
+		return ""
+	}
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		name = string(runes[:maxFilenameLength])
+	}
+	return name
+}
+
+// CompleteUpload verifies an uploaded file and uses it for the purpose of its
+// upload: the file must have the declared size and content type and pass the
+// checks of images uploaded through the API. Message files only need to have
+// the declared size. An upload is completed at most once, whether or not it
+// passes.
+// Route: POST /api/uploads/{id}/complete
+// Request: {"crop": {"x": 0, "y": 0, "width": 256, "height": 256}} (optional, avatars only)
+// Response: the posted message for message images and files, {"avatarUrl": "...", "avatarUrls": {...}} for avatars
+func (h *UploadHandler) CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	userID := auth.GetUserIDFromContext(r)
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid upload ID", http.StatusBadRequest)
+		return
+	}
+
+	var req CompleteUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	upload, err := h.store.GetUploadByID(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && upload.UserID != userID) {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to get upload", http.StatusInternalServerError)
+		return
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		http.Error(w, "Upload has expired", http.StatusGone)
+		return
+	}
+	// Resumable uploads may still be continued
+	if upload.MultipartID != "" && upload.Offset < upload.Size {
+		http.Error(w, "File has not been uploaded completely", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.CompleteUpload(r.Context(), id); errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Upload is already completed", http.StatusConflict)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to complete upload", http.StatusInternalServerError)
+		return
+	}
+
+	if upload.Purpose == models.UploadPurposeMessageFile {
+		h.completeFile(w, r, upload)
+		return
+	}
+
+	// The uploaded file is copied to its final object, if it passes
+	defer func() {
+		if err := blob.Delete(r.Context(), upload.ObjectName); err != nil {
+			log.Printf("Failed to remove uploaded file of upload %s: %v", id, err)
+		}
+	}()
+
+	stat, err := blob.Stat(r.Context(), upload.ObjectName)
+	if errors.Is(err, blob.ErrNotFound) {
+		http.Error(w, "File has not been uploaded", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to read uploaded file", http.StatusInternalServerError)
+		return
+	}
+	if stat.Size != upload.Size {
+		http.Error(w, "Uploaded file does not have the declared size", http.StatusBadRequest)
+		return
+	}
+	object, err := blob.Get(r.Context(), upload.ObjectName)
+	if err != nil {
+		http.Error(w, "Failed to read uploaded file", http.StatusInternalServerError)
+		return
+	}
+	defer object.Close()
+	data, err := io.ReadAll(io.LimitReader(object, upload.Size))
+	if err != nil {
+		http.Error(w, "Failed to read uploaded file", http.StatusInternalServerError)
+		return
+	}
+
+	info, ok := inspectImage(w, data, imageLimits())
+	if !ok {
+		return
+	}
+	if info.ContentType != upload.ContentType {
+		http.Error(w, "Uploaded file does not have the declared content type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	switch upload.Purpose {
+	case models.UploadPurposeMessageImage:
+		postImageMessage(w, r, h.hub, userID, *upload.SessionID, data, info)
+	case models.UploadPurposeAvatar:
+		saveAvatar(w, r, h.store, userID, data, info, req.Crop)
+	}
+}
+
+// completeFile posts the uploaded file of a message file upload. Files are
+// not inspected and stay at the object name of the upload; the object is
+// removed if it cannot be posted.
+func (h *UploadHandler) completeFile(w http.ResponseWriter, r *http.Request, upload *models.Upload) {
+	stat, err := blob.Stat(r.Context(), upload.ObjectName)
+	if errors.Is(err, blob.ErrNotFound) {
+		http.Error(w, "File has not been uploaded", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to read uploaded file", http.StatusInternalServerError)
+		return
+	}
+	if stat.Size != upload.Size {
+		deleteObjects(r.Context(), h.store, upload.ObjectName)
+		http.Error(w, "Uploaded file does not have the declared size", http.StatusBadRequest)
+		return
+	}
+
+	postFileMessage(w, r, h.hub, upload.UserID, *upload.SessionID, upload.ObjectName, upload.Filename, upload.ContentType, upload.Size)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"chat-room/auth"
+	"chat-room/config"
+	"chat-room/middleware"
+	"chat-room/store"
+)
+
+// UsageHandler reports how much storage users and sessions consume.
+type UsageHandler struct {
+	store store.Store
+}
+
+// NewUsageHandler creates a new usage handler with the given store.
+func NewUsageHandler(store store.Store) *UsageHandler {
+	return &UsageHandler{store: store}
+}
+
+// UsageResponse represents the storage usage of a user or session.
+type UsageResponse struct {
+	UsedBytes int64 `json:"used_bytes"`
+	// QuotaBytes is 0 if there is no quota.
+	QuotaBytes int64 `json:"quota_bytes"`
+}
+
+// GetUserUsage returns the storage used by the caller's message images,
+// avatars and custom emoji.
+// Route: GET /api/usage
+// Response: {"used_bytes": 123456, "quota_bytes": 104857600}
+func (h *UsageHandler) GetUserUsage(w http.ResponseWriter, r *http.Request) {
+	used, err := h.store.GetUserStorageUsage(r.Context(), auth.GetUserIDFromContext(r))
+	if err != nil {
+		http.Error(w, "Failed to get storage usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UsageResponse{
+		UsedBytes:  used,
+		QuotaBytes: config.GetConfig().QuotaUserBytes,
+	})
+}
+
+// GetSessionUsage returns the storage used by the message images and custom
+// emoji of the session.
+// Route: GET /api/sessions/usage
+// Response: {"used_bytes": 123456, "quota_bytes": 1073741824}
+func (h *UsageHandler) GetSessionUsage(w http.ResponseWriter, r *http.Request) {
+	used, err := h.store.GetSessionStorageUsage(r.Context(), middleware.GetSessionID(r))
+	if err != nil {
+		http.Error(w, "Failed to get storage usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(UsageResponse{
+		UsedBytes:  used,
+		QuotaBytes: config.GetConfig().QuotaSessionBytes,
+	})
+}
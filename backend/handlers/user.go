@@ -44,6 +44,7 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := user[0]
+	resolveAvatars(r.Context(), user)
 
 	json.NewEncoder(w).Encode(response)
 }
@@ -62,6 +63,7 @@ func (h *UserHandler) PostFetchUsersByIDs(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Error fetching users", http.StatusInternalServerError)
 		return
 	}
+	resolveAvatars(r.Context(), users)
 
 	json.NewEncoder(w).Encode(users)
 }
@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
 	"chat-room/auth"
 	"chat-room/middleware"
+	"chat-room/models"
 	"chat-room/store"
+	"chat-room/webhook"
 
 	"github.com/google/uuid"
 )
@@ -14,11 +17,18 @@ import (
 // UserSessionHandler manages HTTP requests for user-session relationship operations.
 type UserSessionHandler struct {
 	store store.Store
+	hub   *WebSocketHandler
 }
 
 // NewUserSessionHandler creates a new user session handler with the given store.
-func NewUserSessionHandler(store store.Store) *UserSessionHandler {
-	return &UserSessionHandler{store: store}
+// Membership changes are recorded in the session history through hub.
+func NewUserSessionHandler(store store.Store, hub *WebSocketHandler) *UserSessionHandler {
+	return &UserSessionHandler{store: store, hub: hub}
+}
+
+// SetMemberRoleRequest represents the request body for changing a member's role.
+type SetMemberRoleRequest struct {
+	Role string `json:"role"`
 }
 
 // GetSessionIDsByUserID returns all session IDs that the user is a member of.
@@ -89,11 +99,22 @@ func (h *UserSessionHandler) JoinSession(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	h.hub.events.Publish(r.Context(), claims.SessionID, webhook.EventMemberJoined, webhook.MemberEventData{
+		UserID:  userID,
+		ActorID: userID,
+		Via:     "invite_link",
+	})
+	h.hub.postSystemEvent(r.Context(), claims.SessionID, models.SystemEvent{
+		Action:  models.SystemActionMemberJoined,
+		ActorID: userID,
+		Via:     "invite_link",
+	})
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Successfully joined session"})
 }
 
-// LeaveSession removes a normal user from a session.
+// LeaveSession removes a member or moderator from a session.
 // Route: POST /api/sessions/leave
 // Response: {"message": "Successfully left session"}
 func (h *UserSessionHandler) LeaveSession(w http.ResponseWriter, r *http.Request) {
@@ -101,7 +122,7 @@ func (h *UserSessionHandler) LeaveSession(w http.ResponseWriter, r *http.Request
 	sessionClaims := middleware.GetSessionClaims(r)
 	userID := auth.GetUserIDFromContext(r)
 
-	if sessionClaims.Role != "member" {
+	if sessionClaims.Role != "member" && sessionClaims.Role != "moderator" {
 		http.Error(w, "Only normal users can leave sessions", http.StatusForbidden)
 		return
 	}
@@ -112,6 +133,15 @@ func (h *UserSessionHandler) LeaveSession(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	h.hub.events.Publish(r.Context(), sessionClaims.GroupID, webhook.EventMemberLeft, webhook.MemberEventData{
+		UserID:  userID,
+		ActorID: userID,
+	})
+	h.hub.postSystemEvent(r.Context(), sessionClaims.GroupID, models.SystemEvent{
+		Action:  models.SystemActionMemberLeft,
+		ActorID: userID,
+	})
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Successfully left session"})
 }
@@ -146,7 +176,7 @@ func (h *UserSessionHandler) KickMember(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Remove the member from the session
-	err = h.store.RemoveUserFromSession(r.Context(), memberID, sessionID)
+	err = kickMember(r.Context(), h.store, h.hub, sessionID, auth.GetUserIDFromContext(r), memberID)
 	if err != nil {
 		http.Error(w, "Failed to kick member", http.StatusInternalServerError)
 		return
@@ -155,3 +185,95 @@ func (h *UserSessionHandler) KickMember(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Member kicked successfully"})
 }
+
+// kickMember removes a member from a session on behalf of actorID and
+// records the change for webhooks and in the session history.
+func kickMember(ctx context.Context, st store.Store, hub *WebSocketHandler, sessionID, actorID, memberID uuid.UUID) error {
+	if err := st.RemoveUserFromSession(ctx, memberID, sessionID); err != nil {
+		return err
+	}
+
+	hub.events.Publish(ctx, sessionID, webhook.EventMemberKicked, webhook.MemberEventData{
+		UserID:  memberID,
+		ActorID: actorID,
+	})
+	hub.postSystemEvent(ctx, sessionID, models.SystemEvent{
+		Action:   models.SystemActionMemberKicked,
+		ActorID:  actorID,
+		TargetID: &memberID,
+	})
+	return nil
+}
+
+// SetMemberRole changes the role of a session member (creator only).
+// Moderators review reported messages in addition to what members can do.
+// The member's current session token keeps its old role until it is refreshed.
+// Route: PUT /api/sessions/members/role
+// Query parameters:
+//   - memberId: ID of the member
+//
+// Request: {"role": "creator" | "moderator" | "member"}
+// Response: {"user_id": "uuid", "session_id": "uuid", "role": "member", ...}
+func (h *UserSessionHandler) SetMemberRole(w http.ResponseWriter, r *http.Request) {
+	sessionID := middleware.GetSessionID(r)
+
+	memberID, err := uuid.Parse(r.URL.Query().Get("memberId"))
+	if err != nil {
+		http.Error(w, "Invalid member ID", http.StatusBadRequest)
+		return
+	}
+
+	var req SetMemberRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+	if req.Role != "creator" && req.Role != "moderator" && req.Role != "member" {
+		http.Error(w, "Role must be creator, moderator or member", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.store.GetSessionByID(r.Context(), sessionID)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if memberID == session.CreatorID {
+		http.Error(w, "Cannot change the role of the session owner", http.StatusBadRequest)
+		return
+	}
+
+	userSessions, err := h.store.GetUserSessionsBySessionIDAndUserIDs(r.Context(), sessionID, []uuid.UUID{memberID})
+	if err != nil || len(userSessions) == 0 {
+		http.Error(w, "User/Session not found", http.StatusNotFound)
+		return
+	}
+	userSession := userSessions[0]
+
+	if userSession.Role == "bot" {
+		http.Error(w, "Cannot change the role of a bot", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if userSession.Role == req.Role {
+		json.NewEncoder(w).Encode(userSession)
+		return
+	}
+
+	if err := h.store.UpdateUserSessionRole(r.Context(), memberID, sessionID, req.Role); err != nil {
+		http.Error(w, "Failed to update role", http.StatusInternalServerError)
+		return
+	}
+
+	h.hub.postSystemEvent(r.Context(), sessionID, models.SystemEvent{
+		Action:   models.SystemActionRoleChanged,
+		ActorID:  auth.GetUserIDFromContext(r),
+		TargetID: &memberID,
+		OldRole:  userSession.Role,
+		NewRole:  req.Role,
+	})
+
+	userSession.Role = req.Role
+	json.NewEncoder(w).Encode(userSession)
+}
@@ -0,0 +1,514 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"chat-room/auth"
+	"chat-room/config"
+	"chat-room/middleware"
+	"chat-room/models"
+	"chat-room/moderation"
+	"chat-room/store"
+	"chat-room/webhook"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// maxWebhookContentLength limits the size of a single message posted through an incoming webhook.
+const maxWebhookContentLength = 4000
+
+// WebhookHandler manages HTTP requests for session webhooks.
+type WebhookHandler struct {
+	store store.Store
+	hub   *WebSocketHandler
+}
+
+// NewWebhookHandler creates a new webhook handler with the given store and WebSocket hub.
+func NewWebhookHandler(store store.Store, hub *WebSocketHandler) *WebhookHandler {
+	return &WebhookHandler{store: store, hub: hub}
+}
+
+// Request/Response types
+type (
+	// CreateIncomingWebhookRequest represents the request body for creating an incoming webhook.
+	CreateIncomingWebhookRequest struct {
+		Name        string `json:"name"`
+		BotNickname string `json:"bot_nickname"`
+	}
+
+	// IncomingWebhookResponse represents an incoming webhook.
+	// URL and Token are only populated when a new token has just been issued.
+	IncomingWebhookResponse struct {
+		*models.IncomingWebhook
+		URL   string `json:"url,omitempty"`
+		Token string `json:"token,omitempty"`
+	}
+
+	// IncomingWebhookPayload represents the JSON body accepted by an incoming webhook.
+	IncomingWebhookPayload struct {
+		Content string `json:"content"`
+	}
+
+	// CreateOutgoingWebhookRequest represents the request body for creating an outgoing webhook.
+	CreateOutgoingWebhookRequest struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	// OutgoingWebhookResponse represents an outgoing webhook.
+	// Secret is only populated when the webhook has just been created.
+	OutgoingWebhookResponse struct {
+		*models.OutgoingWebhook
+		Secret string `json:"secret,omitempty"`
+	}
+)
+
+// generateSecret returns a random URL-safe secret with 256 bits of entropy.
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// generateWebhookToken returns a new random webhook token and its hash.
+func generateWebhookToken() (string, string, error) {
+	token, err := generateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	return token, hashWebhookToken(token), nil
+}
+
+// hashWebhookToken returns the hex-encoded SHA-256 hash of a webhook token.
+func hashWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// incomingWebhookURL returns the path external systems post payloads to, with
+// the token in the Authorization header.
+func incomingWebhookURL(id uuid.UUID) string {
+	return fmt.Sprintf("/api/hooks/%s", id)
+}
+
+// incomingWebhookToken returns the token of an incoming webhook request: the
+// bearer token of the Authorization header or, for senders that cannot set
+// headers, the {token} URL parameter.
+func incomingWebhookToken(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "bearer") {
+		return token
+	}
+	return chi.URLParam(r, "token")
+}
+
+// getSessionWebhook loads a webhook by the {id} URL parameter and checks that it
+// belongs to the session of the request. It writes an error response and returns
+// nil if the webhook cannot be used.
+func (h *WebhookHandler) getSessionWebhook(w http.ResponseWriter, r *http.Request) *models.IncomingWebhook {
+	webhookID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return nil
+	}
+
+	hook, err := h.store.GetIncomingWebhookByID(r.Context(), webhookID)
+	if err != nil || hook.SessionID != middleware.GetSessionID(r) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return nil
+	}
+	return hook
+}
+
+// CreateIncomingWebhook creates a bot user and an incoming webhook posting as that bot (creator only).
+// Route: POST /api/sessions/webhooks
+// Request: {"name": "CI", "bot_nickname": "ci-bot"}
+// Response: {"id": "uuid", "name": "CI", "bot_user_id": "uuid", "url": "/api/hooks/uuid", "token": "..."}
+func (h *WebhookHandler) CreateIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	sessionID := middleware.GetSessionID(r)
+	userID := auth.GetUserIDFromContext(r)
+
+	var req CreateIncomingWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	req.BotNickname = strings.TrimSpace(req.BotNickname)
+	if req.Name == "" || req.BotNickname == "" {
+		http.Error(w, "Name and bot nickname are required", http.StatusBadRequest)
+		return
+	}
+
+	exists, err := h.store.CheckNicknameExists(r.Context(), req.BotNickname)
+	if err != nil {
+		http.Error(w, "Error checking nickname availability", http.StatusInternalServerError)
+		return
+	}
+	if exists {
+		http.Error(w, "Nickname is already taken", http.StatusConflict)
+		return
+	}
+
+	token, tokenHash, err := generateWebhookToken()
+	if err != nil {
+		http.Error(w, "Error generating webhook token", http.StatusInternalServerError)
+		return
+	}
+
+	// Bots have no password and therefore cannot log in
+	bot := &models.User{
+		ID:       uuid.New(),
+		Nickname: req.BotNickname,
+		IsBot:    true,
+	}
+	bot.Username = "bot-" + bot.ID.String()
+	bot.AvatarURL = identiconAvatar(r.Context(), bot.ID)
+
+	hook := &models.IncomingWebhook{
+		ID:        uuid.New(),
+		SessionID: sessionID,
+		Name:      req.Name,
+		TokenHash: tokenHash,
+		CreatedBy: userID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := h.store.CreateIncomingWebhook(r.Context(), hook, bot); err != nil {
+		log.Printf("Error creating webhook: %v", err)
+		http.Error(w, "Error creating webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(IncomingWebhookResponse{
+		IncomingWebhook: hook,
+		URL:             incomingWebhookURL(hook.ID),
+		Token:           token,
+	})
+}
+
+// ListIncomingWebhooks returns all incoming webhooks of the session (creator only).
+// Route: GET /api/sessions/webhooks
+// Response: {"webhooks": [{"id": "uuid", "name": "CI", ...}]}
+func (h *WebhookHandler) ListIncomingWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	sessionID := middleware.GetSessionID(r)
+
+	webhooks, err := h.store.GetIncomingWebhooksBySessionID(r.Context(), sessionID)
+	if err != nil {
+		http.Error(w, "Error fetching webhooks", http.StatusInternalServerError)
+		return
+	}
+	if webhooks == nil {
+		webhooks = []*models.IncomingWebhook{}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"webhooks": webhooks,
+	})
+}
+
+// RotateIncomingWebhook issues a new token for a webhook, invalidating the old one (creator only).
+// Route: POST /api/sessions/webhooks/{id}/rotate
+// Response: {"id": "uuid", ..., "url": "/api/hooks/uuid", "token": "..."}
+func (h *WebhookHandler) RotateIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	hook := h.getSessionWebhook(w, r)
+	if hook == nil {
+		return
+	}
+	if hook.RevokedAt != nil {
+		http.Error(w, "Webhook has been revoked", http.StatusConflict)
+		return
+	}
+
+	token, tokenHash, err := generateWebhookToken()
+	if err != nil {
+		http.Error(w, "Error generating webhook token", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.store.RotateIncomingWebhookToken(r.Context(), hook.ID, tokenHash); err != nil {
+		http.Error(w, "Error rotating webhook token", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now().UTC()
+	hook.TokenHash = tokenHash
+	hook.RotatedAt = &now
+
+	json.NewEncoder(w).Encode(IncomingWebhookResponse{
+		IncomingWebhook: hook,
+		URL:             incomingWebhookURL(hook.ID),
+		Token:           token,
+	})
+}
+
+// RevokeIncomingWebhook permanently disables a webhook (creator only).
+// The bot user stays in the session so its past messages keep their author.
+// Route: DELETE /api/sessions/webhooks/{id}
+// Response: {"message": "Webhook revoked successfully"}
+func (h *WebhookHandler) RevokeIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	hook := h.getSessionWebhook(w, r)
+	if hook == nil {
+		return
+	}
+
+	if hook.RevokedAt == nil {
+		if err := h.store.RevokeIncomingWebhook(r.Context(), hook.ID); err != nil {
+			http.Error(w, "Error revoking webhook", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Webhook revoked successfully"})
+}
+
+// ReceiveIncomingWebhook accepts a payload from an external system and posts it
+// to the webhook's session as the bot user. The token is sent as bearer token;
+// senders that cannot set headers may append it to the path instead, which is
+// redacted from the access log.
+// Route: POST /api/hooks/{id} with "Authorization: Bearer <token>", or POST /api/hooks/{id}/{token}
+// Request: {"content": "Build #42 passed"}
+// Response: {"id": "uuid", "type": "text", "content": "...", ...}
+func (h *WebhookHandler) ReceiveIncomingWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	webhookID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	hook, err := h.store.GetIncomingWebhookByID(r.Context(), webhookID)
+	if err != nil || hook.RevokedAt != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	tokenHash := hashWebhookToken(incomingWebhookToken(r))
+	if subtle.ConstantTimeCompare([]byte(tokenHash), []byte(hook.TokenHash)) != 1 {
+		http.Error(w, "Invalid webhook token", http.StatusUnauthorized)
+		return
+	}
+
+	var payload IncomingWebhookPayload
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&payload); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	payload.Content = strings.TrimSpace(payload.Content)
+	if payload.Content == "" {
+		http.Error(w, "Content is required", http.StatusBadRequest)
+		return
+	}
+	if len([]rune(payload.Content)) > maxWebhookContentLength {
+		http.Error(w, "Content is too long", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// The bot may have been kicked from the session since the webhook was created
+	userSessions, err := h.store.GetUserSessionsBySessionIDAndUserIDs(r.Context(), hook.SessionID, []uuid.UUID{hook.BotUserID})
+	if err != nil || len(userSessions) == 0 {
+		http.Error(w, "Bot is no longer a member of the session", http.StatusForbidden)
+		return
+	}
+
+	message := &models.Message{
+		ID:        uuid.New(),
+		Type:      models.MessageTypeText,
+		Content:   payload.Content,
+		UserID:    hook.BotUserID,
+		SessionID: hook.SessionID,
+		Timestamp: time.Now().UTC(),
+	}
+
+	if err := h.hub.postMessage(r.Context(), message); err != nil {
+		var rejected *moderation.RejectedError
+		if errors.As(err, &rejected) {
+			http.Error(w, rejected.Reason, http.StatusUnprocessableEntity)
+			return
+		}
+		log.Printf("Error saving webhook message: %v", err)
+		http.Error(w, "Failed to save message", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// CreateOutgoingWebhook subscribes an external URL to events of the session (creator only).
+// Route: POST /api/sessions/outgoing-webhooks
+// Request: {"url": "https://example.com/hook", "events": ["message.created", "member.joined"]}
+// Response: {"id": "uuid", "url": "...", "events": [...], "secret": "..."}
+func (h *WebhookHandler) CreateOutgoingWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	sessionID := middleware.GetSessionID(r)
+	userID := auth.GetUserIDFromContext(r)
+
+	var req CreateOutgoingWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	target, err := webhook.ParseURL(req.URL, config.GetConfig().WebhookAllowInternal)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Events) == 0 {
+		http.Error(w, "At least one event is required", http.StatusBadRequest)
+		return
+	}
+	for _, event := range req.Events {
+		if !webhook.IsKnownEvent(event) {
+			http.Error(w, fmt.Sprintf("Unknown event %q", event), http.StatusBadRequest)
+			return
+		}
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		http.Error(w, "Error generating webhook secret", http.StatusInternalServerError)
+		return
+	}
+
+	outgoing := &models.OutgoingWebhook{
+		ID:        uuid.New(),
+		SessionID: sessionID,
+		URL:       target.String(),
+		Secret:    secret,
+		Events:    req.Events,
+		CreatedBy: userID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := h.store.CreateOutgoingWebhook(r.Context(), outgoing); err != nil {
+		http.Error(w, "Error creating webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(OutgoingWebhookResponse{
+		OutgoingWebhook: outgoing,
+		Secret:          secret,
+	})
+}
+
+// ListOutgoingWebhooks returns all outgoing webhooks of the session (creator only).
+// Route: GET /api/sessions/outgoing-webhooks
+// Response: {"webhooks": [{"id": "uuid", "url": "...", "events": [...]}]}
+func (h *WebhookHandler) ListOutgoingWebhooks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	sessionID := middleware.GetSessionID(r)
+
+	webhooks, err := h.store.GetOutgoingWebhooksBySessionID(r.Context(), sessionID)
+	if err != nil {
+		http.Error(w, "Error fetching webhooks", http.StatusInternalServerError)
+		return
+	}
+	if webhooks == nil {
+		webhooks = []*models.OutgoingWebhook{}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"webhooks": webhooks,
+	})
+}
+
+// getSessionOutgoingWebhook loads an outgoing webhook by the {id} URL parameter and
+// checks that it belongs to the session of the request. It writes an error response
+// and returns nil if the webhook cannot be used.
+func (h *WebhookHandler) getSessionOutgoingWebhook(w http.ResponseWriter, r *http.Request) *models.OutgoingWebhook {
+	webhookID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid webhook ID", http.StatusBadRequest)
+		return nil
+	}
+
+	outgoing, err := h.store.GetOutgoingWebhookByID(r.Context(), webhookID)
+	if err != nil || outgoing.SessionID != middleware.GetSessionID(r) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return nil
+	}
+	return outgoing
+}
+
+// DeleteOutgoingWebhook removes an outgoing webhook and its delivery log (creator only).
+// Route: DELETE /api/sessions/outgoing-webhooks/{id}
+// Response: {"message": "Webhook deleted successfully"}
+func (h *WebhookHandler) DeleteOutgoingWebhook(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	outgoing := h.getSessionOutgoingWebhook(w, r)
+	if outgoing == nil {
+		return
+	}
+
+	if err := h.store.DeleteOutgoingWebhook(r.Context(), outgoing.ID); err != nil {
+		http.Error(w, "Error deleting webhook", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries returns the delivery log of an outgoing webhook (creator only).
+// Route: GET /api/sessions/outgoing-webhooks/{id}/deliveries
+// Query parameters:
+//   - status: only return deliveries with this status (pending, succeeded, dead)
+//   - limit: maximum number of deliveries to return (default: 50, max: 200)
+//
+// Response: {"deliveries": [{"id": "uuid", "event": "message.created", "status": "dead", ...}]}
+func (h *WebhookHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	outgoing := h.getSessionOutgoingWebhook(w, r)
+	if outgoing == nil {
+		return
+	}
+
+	status := models.DeliveryStatus(r.URL.Query().Get("status"))
+	switch status {
+	case "", models.DeliveryStatusPending, models.DeliveryStatusSucceeded, models.DeliveryStatusDead:
+	default:
+		http.Error(w, "Invalid delivery status", http.StatusBadRequest)
+		return
+	}
+
+	limit := parsePaginationLimit(r, 50, 200)
+
+	deliveries, err := h.store.GetWebhookDeliveriesByWebhookID(r.Context(), outgoing.ID, status, limit)
+	if err != nil {
+		http.Error(w, "Error fetching deliveries", http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deliveries": deliveries,
+	})
+}
@@ -2,19 +2,28 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"chat-room/models"
+	"chat-room/moderation"
 	"chat-room/store"
 	"chat-room/token"
+	"chat-room/webhook"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// maxResumeMessages caps the number of missed messages replayed on reconnect.
+// Clients fetch anything beyond it through the history API.
+const maxResumeMessages = 500
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -37,6 +46,13 @@ type WebSocketMessage struct {
 	Type    models.MessageType `json:"type"`
 }
 
+// WebSocketEvent notifies clients of changes other than new messages.
+// Messages are sent as plain models.Message objects.
+type WebSocketEvent struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
 type SessionClients struct {
 	Clients map[uuid.UUID][]*Client // map[userID][]*Client
 	mu      sync.RWMutex
@@ -46,12 +62,16 @@ type WebSocketHandler struct {
 	sessions     sync.Map // map[uuid.UUID]*SessionClients
 	store        store.Store
 	tokenManager *token.TokenManager
+	events       *webhook.Dispatcher
+	moderator    *moderation.Moderator
 }
 
-func NewWebSocketHandler(store store.Store, tokenManager *token.TokenManager) *WebSocketHandler {
+func NewWebSocketHandler(store store.Store, tokenManager *token.TokenManager, events *webhook.Dispatcher, moderator *moderation.Moderator) *WebSocketHandler {
 	return &WebSocketHandler{
 		store:        store,
 		tokenManager: tokenManager,
+		events:       events,
+		moderator:    moderator,
 	}
 }
 
@@ -73,6 +93,16 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 	sessionID := claims.SessionID
 	userID := claims.UserID
 
+	// A reconnecting client passes the seq of the last message it received
+	var lastSeq int64 = -1
+	if lastSeqStr := r.URL.Query().Get("last_seq"); lastSeqStr != "" {
+		lastSeq, err = strconv.ParseInt(lastSeqStr, 10, 64)
+		if err != nil || lastSeq < 0 {
+			http.Error(w, "Invalid last_seq", http.StatusBadRequest)
+			return
+		}
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
@@ -87,6 +117,8 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	resolveAvatars(r.Context(), user)
+
 	// Create new client
 	client := &Client{
 		UserID:    user[0].ID,
@@ -110,6 +142,13 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 	sessionClients.Clients[userID] = append(sessionClients.Clients[userID], client)
 	sessionClients.mu.Unlock()
 
+	// Replay missed messages after registering, so that nothing posted in
+	// between is lost. Live messages may arrive before the replay finishes;
+	// clients order and deduplicate by seq.
+	if lastSeq >= 0 {
+		h.replay(r.Context(), client, sessionID, lastSeq)
+	}
+
 	// Handle messages
 	go func() {
 		defer h.removeConnection(sessionID, userID, client)
@@ -142,20 +181,117 @@ func (h *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Reques
 			log.Printf("Processing message: %+v", message)
 
 			// Use background context for message handling
-			ctx := context.Background()
-			// Save message to database
-			if err := h.store.CreateMessage(ctx, message); err != nil {
+			if err := h.postMessage(context.Background(), message); err != nil {
+				var rejected *moderation.RejectedError
+				if errors.As(err, &rejected) {
+					client.mu.Lock()
+					conn.WriteJSON(map[string]string{"error": "message rejected", "reason": rejected.Reason})
+					client.mu.Unlock()
+					continue
+				}
 				log.Printf("Error saving message: %v", err)
 				continue
 			}
-
-			log.Printf("Broadcasting message to session %s", sessionID)
-			// Broadcast message
-			h.broadcast(sessionID, message)
 		}
 	}()
 }
 
+// postMessage runs a message through moderation, persists it, broadcasts it
+// to every client connected to the message's session and notifies outgoing
+// webhooks, unless a bot posted it. A *moderation.RejectedError is returned for rejected messages and
+// for authors who are muted or no longer members of the session.
+func (h *WebSocketHandler) postMessage(ctx context.Context, message *models.Message) error {
+	author, err := h.checkAuthor(ctx, message)
+	if err != nil {
+		return err
+	}
+
+	verdict, err := h.moderator.Moderate(ctx, message)
+	if err != nil {
+		return err
+	}
+	if verdict.Rejected {
+		return &moderation.RejectedError{Reason: verdict.Reason}
+	}
+
+	if err := h.store.CreateMessage(ctx, message); err != nil {
+		return err
+	}
+
+	for _, flag := range verdict.Flags {
+		err := h.store.CreateModerationFlag(ctx, &models.ModerationFlag{
+			MessageID: message.ID,
+			SessionID: message.SessionID,
+			Filter:    flag.Filter,
+			Reason:    flag.Reason,
+		})
+		if err != nil {
+			log.Printf("Error flagging message %s: %v", message.ID, err)
+		}
+	}
+
+	prepareMessages(ctx, h.store, []*models.Message{message})
+
+	log.Printf("Broadcasting message to session %s", message.SessionID)
+	h.broadcast(message.SessionID, message)
+	h.events.PublishMessage(ctx, message, author.Role == "bot")
+	return nil
+}
+
+// checkAuthor verifies that the author of a message may post in its session
+// and returns the author's membership.
+func (h *WebSocketHandler) checkAuthor(ctx context.Context, message *models.Message) (*models.UserSession, error) {
+	userSessions, err := h.store.GetUserSessionsBySessionIDAndUserIDs(ctx, message.SessionID, []uuid.UUID{message.UserID})
+	if err != nil {
+		return nil, err
+	}
+	if len(userSessions) == 0 {
+		return nil, &moderation.RejectedError{Reason: "You are no longer a member of this session"}
+	}
+	userSession := userSessions[0]
+	if userSession.IsMuted(time.Now()) {
+		return nil, &moderation.RejectedError{
+			Reason: fmt.Sprintf("You are muted until %s", userSession.MutedUntil.UTC().Format(time.RFC3339)),
+		}
+	}
+	return userSession, nil
+}
+
+// replay sends the messages of the session following lastSeq to the client.
+func (h *WebSocketHandler) replay(ctx context.Context, client *Client, sessionID uuid.UUID, lastSeq int64) {
+	messages, err := h.store.GetMessagesAfterSeq(ctx, sessionID, lastSeq, maxResumeMessages)
+	if err != nil {
+		log.Printf("Error loading missed messages of session %s: %v", sessionID, err)
+		return
+	}
+	prepareMessages(ctx, h.store, messages)
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	for _, message := range messages {
+		if err := client.Conn.WriteJSON(message); err != nil {
+			log.Printf("Error replaying message to client: %v", err)
+			return
+		}
+	}
+}
+
+// postSystemEvent records a membership or session change as a system message
+// in the session's history and broadcasts it. The change has already been
+// applied, so failures are only logged.
+func (h *WebSocketHandler) postSystemEvent(ctx context.Context, sessionID uuid.UUID, event models.SystemEvent) {
+	message, err := models.NewSystemMessage(sessionID, event)
+	if err != nil {
+		log.Printf("Error encoding system event %s: %v", event.Action, err)
+		return
+	}
+	if err := h.store.CreateMessage(ctx, message); err != nil {
+		log.Printf("Error saving system event %s in session %s: %v", event.Action, sessionID, err)
+		return
+	}
+	h.broadcast(sessionID, message)
+}
+
 func (h *WebSocketHandler) removeConnection(sessionID, userID uuid.UUID, client *Client) {
 	sessionClientsInterface, ok := h.sessions.Load(sessionID)
 	if !ok {
@@ -188,6 +324,27 @@ func (h *WebSocketHandler) removeConnection(sessionID, userID uuid.UUID, client
 	client.Conn.Close()
 }
 
+// sendToUser sends payload to every connection of a user in a session,
+// e.g. to keep the user's tabs and devices in sync.
+func (h *WebSocketHandler) sendToUser(sessionID, userID uuid.UUID, payload interface{}) {
+	sessionClientsInterface, ok := h.sessions.Load(sessionID)
+	if !ok {
+		return
+	}
+
+	sessionClients := sessionClientsInterface.(*SessionClients)
+	sessionClients.mu.RLock()
+	defer sessionClients.mu.RUnlock()
+
+	for _, client := range sessionClients.Clients[userID] {
+		client.mu.Lock()
+		if err := client.Conn.WriteJSON(payload); err != nil {
+			log.Printf("Error sending to client: %v", err)
+		}
+		client.mu.Unlock()
+	}
+}
+
 func (h *WebSocketHandler) broadcast(sessionID uuid.UUID, message *models.Message) {
 	sessionClientsInterface, ok := h.sessions.Load(sessionID)
 	if !ok {
@@ -6,13 +6,16 @@ import (
 	"log"
 	"net/http"
 
+	"chat-room/blob"
 	"chat-room/config"
 	"chat-room/handlers"
+	"chat-room/mediagc"
 	custommw "chat-room/middleware"
-	"chat-room/s3"
+	"chat-room/moderation"
 	"chat-room/store/cache"
 	"chat-room/store/postgres"
 	"chat-room/token"
+	"chat-room/webhook"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
@@ -54,10 +57,19 @@ func main() {
 	}
 	defer store.Close()
 
-	// Initialize MinIO
-	err = s3.Initialize(cfg)
+	// Initialize blob storage
+	blobStore, err := blob.Initialize(cfg)
 	if err != nil {
-		log.Fatal("Failed to initialize MinIO:", err)
+		log.Fatal("Failed to initialize blob storage:", err)
+	}
+
+	// Start orphaned media collector
+	if cfg.MediaGCInterval > 0 {
+		collector := mediagc.NewCollector(store, blobStore, mediagc.Options{
+			Interval:    cfg.MediaGCInterval,
+			GracePeriod: cfg.MediaGCGracePeriod,
+		})
+		go collector.Run(context.Background())
 	}
 
 	// Initialize token manager
@@ -66,26 +78,46 @@ func main() {
 		log.Fatal("Failed to initialize token manager:", err)
 	}
 
+	// Start outgoing webhook delivery worker
+	dispatcher := webhook.NewDispatcher(store, webhook.Options{
+		MaxAttempts:            cfg.WebhookMaxAttempts,
+		RequestTimeout:         cfg.WebhookRequestTimeout,
+		AllowInternalAddresses: cfg.WebhookAllowInternal,
+	})
+	go dispatcher.Run(context.Background())
+
+	// Initialize content moderation
+	moderator := moderation.NewModerator(store, cfg.ModerationMaxMessageLength)
+
 	// Initialize handlers
-	wsHandler := handlers.NewWebSocketHandler(store, tokenManager)
+	wsHandler := handlers.NewWebSocketHandler(store, tokenManager, dispatcher, moderator)
 	authHandler := handlers.NewAuthHandler(store)
-	sessionHandler := handlers.NewSessionHandler(store, tokenManager)
+	sessionHandler := handlers.NewSessionHandler(store, tokenManager, wsHandler)
 	userHandler := handlers.NewUserHandler(store)
 	avatarHandler := handlers.NewAvatarHandler(store)
 	messageHandler := handlers.NewMessageHandler(store, wsHandler)
-	userSessionHandler := handlers.NewUserSessionHandler(store)
+	userSessionHandler := handlers.NewUserSessionHandler(store, wsHandler)
+	webhookHandler := handlers.NewWebhookHandler(store, wsHandler)
+	moderationHandler := handlers.NewModerationHandler(store)
+	reportHandler := handlers.NewReportHandler(store, wsHandler)
+	bookmarkHandler := handlers.NewBookmarkHandler(store)
+	draftHandler := handlers.NewDraftHandler(store, wsHandler)
+	emojiHandler := handlers.NewEmojiHandler(store)
+	uploadHandler := handlers.NewUploadHandler(store, wsHandler)
+	usageHandler := handlers.NewUsageHandler(store)
 
 	// Setup router
 	r := chi.NewRouter()
 
 	// Middleware
+	r.Use(custommw.RedactWebhookTokens)
 	r.Use(chimiddleware.Logger)
 	r.Use(chimiddleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Session-Token"},
-		ExposedHeaders:   []string{"Session-Token"},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Session-Token", "Tus-Resumable", "Upload-Offset"},
+		ExposedHeaders:   []string{"Session-Token", "Location", "Tus-Resumable", "Upload-Offset", "Upload-Length", "Upload-Expires"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -115,18 +147,57 @@ func main() {
 			r.Get("/session", sessionHandler.GetSession)
 			r.Get("/role", sessionHandler.CheckRole)
 			r.Get("/users/ids", userSessionHandler.GetUserIDsBySessionID)
-			r.Get("/messages/ids", sessionHandler.GetMessageIDsBySessionID)
+			r.Get("/messages", sessionHandler.GetMessages)
 			r.Post("/messages/batch", sessionHandler.PostFetchMessages)
 			r.Post("/messages/upload", messageHandler.UploadMessageImage)
 			r.Get("/wstoken", sessionHandler.GetWebSocketToken)
 			r.Post("/leave", userSessionHandler.LeaveSession)
+			r.Post("/reports", reportHandler.ReportMessage)
+			r.Get("/draft", draftHandler.GetDraft)
+			r.Put("/draft", draftHandler.SaveDraft)
+			r.Delete("/draft", draftHandler.DeleteDraft)
+			r.Get("/emojis", emojiHandler.ListEmojis)
+
+			// Report review queue, open to creators and moderators
+			r.Group(func(r chi.Router) {
+				r.Use(custommw.RequireRole("creator", "moderator"))
+				r.Get("/reports", reportHandler.GetReports)
+				r.Post("/reports/{id}/resolve", reportHandler.ResolveReport)
+			})
 
 			// Creator-only routes
 			r.Group(func(r chi.Router) {
 				r.Use(custommw.RequireRole("creator"))
 				r.Post("/kick", userSessionHandler.KickMember)
+				r.Put("/members/role", userSessionHandler.SetMemberRole)
+				r.Put("/name", sessionHandler.RenameSession)
 				r.Delete("/", sessionHandler.RemoveSession)
 				r.Post("/share", sessionHandler.CreateShareLink)
+				r.Get("/export", sessionHandler.ExportTranscript)
+
+				// Incoming webhook management
+				r.Post("/webhooks", webhookHandler.CreateIncomingWebhook)
+				r.Get("/webhooks", webhookHandler.ListIncomingWebhooks)
+				r.Post("/webhooks/{id}/rotate", webhookHandler.RotateIncomingWebhook)
+				r.Delete("/webhooks/{id}", webhookHandler.RevokeIncomingWebhook)
+
+				// Outgoing webhook management
+				r.Post("/outgoing-webhooks", webhookHandler.CreateOutgoingWebhook)
+				r.Get("/outgoing-webhooks", webhookHandler.ListOutgoingWebhooks)
+				r.Delete("/outgoing-webhooks/{id}", webhookHandler.DeleteOutgoingWebhook)
+				r.Get("/outgoing-webhooks/{id}/deliveries", webhookHandler.GetWebhookDeliveries)
+
+				// Content moderation
+				r.Get("/moderation", moderationHandler.GetSettings)
+				r.Put("/moderation", moderationHandler.UpdateSettings)
+				r.Get("/moderation/flags", moderationHandler.GetFlags)
+
+				// Custom emoji management
+				r.Post("/emojis", emojiHandler.UploadEmoji)
+				r.Delete("/emojis/{id}", emojiHandler.DeleteEmoji)
+
+				// Storage usage
+				r.Get("/usage", usageHandler.GetSessionUsage)
 			})
 		})
 	})
@@ -136,16 +207,46 @@ func main() {
 		r.Use(custommw.AuthMiddleware)
 		r.Get("/api/users/{id}", userHandler.GetUser)
 		r.Post("/api/users/batch", userHandler.PostFetchUsersByIDs)
+		r.Get("/api/usage", usageHandler.GetUserUsage)
 		r.Put("/api/users/{id}/nickname", userHandler.UpdateNickname)
 		r.Put("/api/users/{id}/username", userHandler.UpdateUsername)
 	})
 
+	// Bookmark routes
+	r.Group(func(r chi.Router) {
+		r.Use(custommw.AuthMiddleware)
+		r.Get("/api/bookmarks", bookmarkHandler.ListBookmarks)
+		r.Get("/api/bookmarks/folders", bookmarkHandler.ListBookmarkFolders)
+		r.Put("/api/bookmarks/{messageId}", bookmarkHandler.SaveBookmark)
+		r.Delete("/api/bookmarks/{messageId}", bookmarkHandler.DeleteBookmark)
+	})
+
 	// Avatar routes
 	r.Group(func(r chi.Router) {
 		r.Use(custommw.AuthMiddleware)
 		r.Post("/api/avatar", avatarHandler.UploadAvatar)
 	})
 
+	// Direct upload routes
+	r.Group(func(r chi.Router) {
+		r.Use(custommw.AuthMiddleware)
+		r.Post("/api/uploads", uploadHandler.CreateUpload)
+		r.Post("/api/uploads/{id}/complete", uploadHandler.CompleteUpload)
+		r.Post("/api/uploads/resumable", uploadHandler.CreateResumableUpload)
+		r.Head("/api/uploads/resumable/{id}", uploadHandler.GetResumableUpload)
+		r.Patch("/api/uploads/resumable/{id}", uploadHandler.PatchResumableUpload)
+	})
+
+	// Incoming webhook endpoint, authenticated by the bearer token or, for
+	// senders that cannot set headers, by the token in the path
+	r.Post("/api/hooks/{id}", webhookHandler.ReceiveIncomingWebhook)
+	r.Post("/api/hooks/{id}/{token}", webhookHandler.ReceiveIncomingWebhook)
+
+	// Files of the local blob backend (access is checked by the store)
+	if localStore, ok := blobStore.(*blob.LocalStore); ok {
+		r.Handle(localStore.Path()+"/*", localStore)
+	}
+
 	// WebSocket endpoint
 	r.Get("/ws", wsHandler.HandleWebSocket)
 
@@ -0,0 +1,179 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// AvatarSizes are the widths and heights of the square avatars, smallest first.
+var AvatarSizes = []int{64, 128, 256}
+
+// ErrInvalidCrop is returned for crop rectangles without area or outside the image.
+var ErrInvalidCrop = errors.New("crop rectangle must lie within the image")
+
+// Avatars crops the image to a square and returns a copy of it for every
+// AvatarSizes. crop selects the part of the displayed (oriented) image; nil
+// selects the whole image. Crops that are not square are reduced to their
+// centered square. Unlike variants, avatars are upscaled so that every size
+// exists. The copies carry no metadata.
+func (i *Info) Avatars(crop *image.Rectangle) ([]*Variant, error) {
+	bounds := i.image.Bounds()
+	rect := bounds
+	if crop != nil {
+		rect = crop.Add(bounds.Min)
+		if rect.Empty() || !rect.In(bounds) {
+			return nil, ErrInvalidCrop
+		}
+	}
+	rect = centeredSquare(rect)
+
+	var avatars []*Variant
+	for _, size := range AvatarSizes {
+		avatar, err := encode(resizeRect(i.image, rect, size, size))
+		if err != nil {
+			return nil, err
+		}
+		avatar.Name = strconv.Itoa(size)
+		avatars = append(avatars, avatar)
+	}
+	return avatars, nil
+}
+
+// centeredSquare returns the largest square centered in r.
+func centeredSquare(r image.Rectangle) image.Rectangle {
+	side := min(r.Dx(), r.Dy())
+	x := r.Min.X + (r.Dx()-side)/2
+	y := r.Min.Y + (r.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// avatarNamePattern matches object names of the form <base>_<size><ext>.
+var avatarNamePattern = regexp.MustCompile(`^(.+)_(\d+)(\.[a-z]+)$`)
+
+// AvatarObjectName returns the object name of the avatar of the given size,
+// so that all sizes of an avatar share base and ext.
+func AvatarObjectName(base string, size int, ext string) string {
+	return base + "_" + strconv.Itoa(size) + ext
+}
+
+// ParseAvatarObjectName splits an object name created by AvatarObjectName.
+// It returns false for names not ending in one of the AvatarSizes, such as
+// avatars stored before they were resized.
+func ParseAvatarObjectName(objectName string) (base string, size int, ext string, ok bool) {
+	m := avatarNamePattern.FindStringSubmatch(objectName)
+	if m == nil {
+		return "", 0, "", false
+	}
+	size, _ = strconv.Atoi(m[2])
+	for _, s := range AvatarSizes {
+		if s == size {
+			return m[1], size, m[3], true
+		}
+	}
+	return "", 0, "", false
+}
+
+// identiconGrid is the number of cells per row and column of an identicon.
+const identiconGrid = 5
+
+// Identicon returns a PNG image of size pixels derived from seed: a
+// horizontally symmetric pattern of cells in a color picked from the seed's
+// hash. The same seed always yields the same image.
+func Identicon(seed []byte, size int) ([]byte, error) {
+	hash := sha256.Sum256(seed)
+	fg := hslColor(float64(int(hash[0])<<8|int(hash[1]))/65536, 0.55, 0.5)
+	bg := color.RGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+
+	// The pattern is framed by half a cell on every side
+	cell := size / (identiconGrid + 1)
+	offset := (size - cell*identiconGrid) / 2
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.SetRGBA(x, y, bg)
+		}
+	}
+	half := (identiconGrid + 1) / 2
+	for row := 0; row < identiconGrid; row++ {
+		for col := 0; col < half; col++ {
+			if hash[2+row*half+col]&1 == 0 {
+				continue
+			}
+			for _, c := range []int{col, identiconGrid - 1 - col} {
+				x0, y0 := offset+c*cell, offset+row*cell
+				for y := y0; y < y0+cell; y++ {
+					for x := x0; x < x0+cell; x++ {
+						img.SetRGBA(x, y, fg)
+					}
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// StoreIdenticon stores the identicon of a user in every avatar size with put,
+// as "<prefix><userID>/identicon_<size>.png". Returns the object name of the
+// largest size, which references the identicon as the avatar of the user.
+func StoreIdenticon(ctx context.Context, prefix string, userID uuid.UUID, put PutFunc) (string, error) {
+	base := prefix + userID.String() + "/identicon"
+	var objectName string
+	for _, size := range AvatarSizes {
+		data, err := Identicon(userID[:], size)
+		if err != nil {
+			return "", err
+		}
+		objectName = AvatarObjectName(base, size, ".png")
+		if err := put(ctx, objectName, data, TypePNG); err != nil {
+			return "", err
+		}
+	}
+	return objectName, nil
+}
+
+// hslColor converts a hue, saturation and lightness, each in [0, 1), to RGB.
+func hslColor(h, s, l float64) color.RGBA {
+	var q float64
+	if l < 0.5 {
+		q = l * (1 + s)
+	} else {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	channel := func(t float64) uint8 {
+		switch {
+		case t < 0:
+			t++
+		case t > 1:
+			t--
+		}
+		var v float64
+		switch {
+		case t < 1.0/6:
+			v = p + (q-p)*6*t
+		case t < 1.0/2:
+			v = q
+		case t < 2.0/3:
+			v = p + (q-p)*(2.0/3-t)*6
+		default:
+			v = p
+		}
+		return uint8(v*255 + 0.5)
+	}
+	return color.RGBA{R: channel(h + 1.0/3), G: channel(h), B: channel(h - 1.0/3), A: 0xff}
+}
@@ -0,0 +1,117 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAvatars(t *testing.T) {
+	info, err := Inspect(encodePNG(t, testImage(100, 40)), Limits{})
+	require.NoError(t, err)
+
+	avatars, err := info.Avatars(nil)
+	require.NoError(t, err)
+	require.Len(t, avatars, len(AvatarSizes))
+	for i, size := range AvatarSizes {
+		assert.Equal(t, size, avatars[i].Width)
+		assert.Equal(t, size, avatars[i].Height)
+		decoded, _, err := image.Decode(bytes.NewReader(avatars[i].Data))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, size, size), decoded.Bounds())
+	}
+	assert.Equal(t, "64", avatars[0].Name)
+}
+
+func TestAvatarsCrop(t *testing.T) {
+	// The right half of the image is red
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for x := 100; x < 200; x++ {
+		for y := 0; y < 100; y++ {
+			img.Set(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+	info, err := Inspect(encodePNG(t, img), Limits{})
+	require.NoError(t, err)
+
+	crop := image.Rect(100, 0, 200, 100)
+	avatars, err := info.Avatars(&crop)
+	require.NoError(t, err)
+	decoded, _, err := image.Decode(bytes.NewReader(avatars[0].Data))
+	require.NoError(t, err)
+	r, g, b, _ := decoded.At(32, 32).RGBA()
+	assert.Greater(t, r>>8, uint32(240))
+	assert.Less(t, g>>8, uint32(16))
+	assert.Less(t, b>>8, uint32(16))
+
+	for _, crop := range []image.Rectangle{image.Rect(150, 0, 250, 100), image.Rect(10, 10, 10, 50), image.Rect(-1, 0, 50, 50)} {
+		_, err := info.Avatars(&crop)
+		assert.ErrorIs(t, err, ErrInvalidCrop, crop)
+	}
+}
+
+func TestAvatarObjectName(t *testing.T) {
+	name := AvatarObjectName("user-1/abc", 128, ".png")
+	assert.Equal(t, "user-1/abc_128.png", name)
+
+	base, size, ext, ok := ParseAvatarObjectName(name)
+	require.True(t, ok)
+	assert.Equal(t, "user-1/abc", base)
+	assert.Equal(t, 128, size)
+	assert.Equal(t, ".png", ext)
+
+	_, _, _, ok = ParseAvatarObjectName("user-1/abc.png")
+	assert.False(t, ok)
+	_, _, _, ok = ParseAvatarObjectName("user-1/abc_100.png")
+	assert.False(t, ok)
+}
+
+func TestIdenticon(t *testing.T) {
+	a, err := Identicon([]byte("a"), 120)
+	require.NoError(t, err)
+	again, err := Identicon([]byte("a"), 120)
+	require.NoError(t, err)
+	b, err := Identicon([]byte("b"), 120)
+	require.NoError(t, err)
+	assert.Equal(t, a, again)
+	assert.NotEqual(t, a, b)
+
+	decoded, format, err := image.Decode(bytes.NewReader(a))
+	require.NoError(t, err)
+	assert.Equal(t, "png", format)
+	assert.Equal(t, image.Rect(0, 0, 120, 120), decoded.Bounds())
+
+	// The pattern is mirrored
+	for y := 0; y < 120; y += 7 {
+		for x := 0; x < 60; x += 7 {
+			assert.Equal(t, decoded.At(x, y), decoded.At(119-x, y))
+		}
+	}
+}
+
+func TestStoreIdenticon(t *testing.T) {
+	userID := uuid.MustParse("3f1c8d1e-3b7a-4b8e-9a52-4c0e1f7d2a90")
+	stored := make(map[string][]byte)
+	put := func(ctx context.Context, objectName string, data []byte, contentType string) error {
+		assert.Equal(t, TypePNG, contentType)
+		stored[objectName] = data
+		return nil
+	}
+
+	objectName, err := StoreIdenticon(context.Background(), "user-", userID, put)
+	require.NoError(t, err)
+	base := "user-" + userID.String() + "/identicon"
+	assert.Equal(t, AvatarObjectName(base, 256, ".png"), objectName)
+	require.Len(t, stored, len(AvatarSizes))
+	for _, size := range AvatarSizes {
+		expected, err := Identicon(userID[:], size)
+		require.NoError(t, err)
+		assert.Equal(t, expected, stored[AvatarObjectName(base, size, ".png")])
+	}
+}
@@ -0,0 +1,130 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// blurhashSize is the maximum width and height of the downscaled copy of an
+// image its blurhash is computed from. A blurhash only keeps a few frequency
+// components, so larger copies would not change it.
+const blurhashSize = 32
+
+// base83 is the alphabet of blurhash digits.
+const base83 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[\\]^_{|}~"
+
+// Placeholder returns the blurhash (https://blurha.sh) of the image, with 4
+// components along its longer side and 3 along the shorter one, and its
+// average color as "#rrggbb". Clients show them while the image loads.
+func (i *Info) Placeholder() (blurhash, color string) {
+	width, height := Fit(i.Width, i.Height, blurhashSize)
+	img := resize(i.image, width, height)
+
+	xComponents, yComponents := 4, 3
+	if height > width {
+		xComponents, yComponents = 3, 4
+	}
+	return EncodeBlurhash(img, xComponents, yComponents)
+}
+
+// EncodeBlurhash returns the blurhash of img with the given number of
+// components, from 1 to 9 each, and its average color as "#rrggbb", which is
+// the color of the blurhash's first component.
+func EncodeBlurhash(img image.Image, xComponents, yComponents int) (blurhash, color string) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Linear RGB of the pixels, decoded once for all components
+	pixels := make([][3]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			pixels[y*width+x] = [3]float64{srgbToLinear(r >> 8), srgbToLinear(g >> 8), srgbToLinear(b >> 8)}
+		}
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) *
+						math.Cos(math.Pi*float64(j)*float64(y)/float64(height))
+					pixel := pixels[y*width+x]
+					factor[0] += basis * pixel[0]
+					factor[1] += basis * pixel[1]
+					factor[2] += basis * pixel[2]
+				}
+			}
+			scale := normalisation / float64(width*height)
+			factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+		}
+	}
+
+	var sb strings.Builder
+	writeBase83(&sb, (xComponents-1)+(yComponents-1)*9, 1)
+
+	dc, ac := factors[0], factors[1:]
+	maximumValue := 1.0
+	if len(ac) > 0 {
+		actualMaximum := 0.0
+		for _, factor := range ac {
+			for _, v := range factor {
+				actualMaximum = math.Max(actualMaximum, math.Abs(v))
+			}
+		}
+		quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(actualMaximum*166-0.5))))
+		maximumValue = float64(quantisedMaximum+1) / 166
+		writeBase83(&sb, quantisedMaximum, 1)
+	} else {
+		writeBase83(&sb, 0, 1)
+	}
+
+	r, g, b := linearToSRGB(dc[0]), linearToSRGB(dc[1]), linearToSRGB(dc[2])
+	writeBase83(&sb, r<<16|g<<8|b, 4)
+
+	for _, factor := range ac {
+		var value int
+		for _, v := range factor {
+			quantised := int(math.Max(0, math.Min(18, math.Floor(signPow(v/maximumValue, 0.5)*9+9.5))))
+			value = value*19 + quantised
+		}
+		writeBase83(&sb, value, 2)
+	}
+
+	return sb.String(), fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+func writeBase83(sb *strings.Builder, value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		digit := value / int(math.Pow(83, float64(i))) % 83
+		sb.WriteByte(base83[digit])
+	}
+}
+
+func srgbToLinear(value uint32) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
@@ -0,0 +1,44 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func uniformImage(width, height int, c color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestEncodeBlurhashUniform(t *testing.T) {
+	// A single component is the average color, in 4 digits after the size and maximum
+	hash, average := EncodeBlurhash(uniformImage(8, 6, color.White), 1, 1)
+	assert.Equal(t, "00TSUA", hash)
+	assert.Equal(t, "#ffffff", average)
+
+	hash, average = EncodeBlurhash(uniformImage(8, 6, color.RGBA{0x33, 0x66, 0x99, 0xff}), 4, 3)
+	assert.Len(t, hash, 28)
+	assert.Equal(t, "L", hash[:1])
+	assert.Equal(t, "#336699", average)
+}
+
+func TestPlaceholder(t *testing.T) {
+	info, err := Inspect(encodePNG(t, testImage(120, 60)), Limits{})
+	require.NoError(t, err)
+	hash, average := info.Placeholder()
+	assert.Len(t, hash, 28)
+	assert.Equal(t, "L", hash[:1])
+	assert.NotEqual(t, "0", hash[1:2], "gradients have AC components")
+	assert.Regexp(t, "^#[0-9a-f]{6}$", average)
+
+	info, err = Inspect(encodePNG(t, testImage(60, 120)), Limits{})
+	require.NoError(t, err)
+	hash, _ = info.Placeholder()
+	assert.Equal(t, "T", hash[:1], "portrait images have more vertical components")
+}
@@ -0,0 +1,156 @@
+// Package media validates and processes the images uploaded by users.
+//
+// Uploads are never trusted: the format is detected from the file's magic
+// bytes rather than its name or declared content type, and the image is
+// decoded before it is stored.
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Content types of the accepted image formats.
+const (
+	TypePNG  = "image/png"
+	TypeJPEG = "image/jpeg"
+	TypeGIF  = "image/gif"
+	TypeWebP = "image/webp"
+)
+
+// formats describes the accepted image formats, keyed by content type.
+var formats = map[string]struct {
+	// name is the format name registered with the image package.
+	name string
+	ext  string
+}{
+	TypePNG:  {name: "png", ext: ".png"},
+	TypeJPEG: {name: "jpeg", ext: ".jpg"},
+	TypeGIF:  {name: "gif", ext: ".gif"},
+	TypeWebP: {name: "webp", ext: ".webp"},
+}
+
+var (
+	// ErrUnsupportedFormat is returned for files that are not PNG, JPEG, GIF or WebP images.
+	ErrUnsupportedFormat = errors.New("images must be PNG, JPEG, GIF or WebP")
+	// ErrInvalidImage is returned for files that look like an image but cannot be decoded.
+	ErrInvalidImage = errors.New("invalid image")
+)
+
+// DimensionError is returned for images exceeding the dimension limits.
+type DimensionError struct {
+	Width, Height int
+	Limits        Limits
+}
+
+func (e *DimensionError) Error() string {
+	return fmt.Sprintf("image of %dx%d pixels exceeds the limit of %dx%d pixels and %d pixels in total",
+		e.Width, e.Height, e.Limits.MaxDimension, e.Limits.MaxDimension, e.Limits.MaxPixels)
+}
+
+// Limits bound the dimensions of accepted images. Zero values disable a limit.
+type Limits struct {
+	// MaxDimension is the maximum width and height.
+	MaxDimension int
+	// MaxPixels is the maximum of width times height.
+	MaxPixels int
+}
+
+// Info describes a validated image.
+type Info struct {
+	ContentType string
+	// Ext is the file extension of the format, including the dot.
+	Ext string
+	// Width and Height are the dimensions of the displayed image, after
+	// applying its orientation.
+	Width  int
+	Height int
+	// Orientation is the EXIF orientation of JPEG images, and
+	// OrientationNormal for other images.
+	Orientation int
+
+	// image is the decoded and oriented image, used to generate variants.
+	image image.Image
+}
+
+// Sniff returns the content type of an accepted image format from the magic
+// bytes of data, or "" if data is not in an accepted format.
+func Sniff(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return TypePNG
+	case bytes.HasPrefix(data, []byte("\xff\xd8\xff")):
+		return TypeJPEG
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return TypeGIF
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && bytes.Equal(data[8:12], []byte("WEBP")):
+		return TypeWebP
+	}
+	return ""
+}
+
+// Inspect validates an uploaded image. The header is checked against limits
+// before the image is decoded, so that small files declaring huge dimensions
+// are rejected without allocating their pixels.
+func Inspect(data []byte, limits Limits) (*Info, error) {
+	contentType := Sniff(data)
+	if contentType == "" {
+		return nil, ErrUnsupportedFormat
+	}
+	format := formats[contentType]
+
+	config, name, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || name != format.name {
+		return nil, ErrInvalidImage
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if limits.exceeded(config.Width, config.Height) {
+		return nil, &DimensionError{Width: config.Width, Height: config.Height, Limits: limits}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	orientation := OrientationNormal
+	if contentType == TypeJPEG {
+		orientation = jpegOrientation(data)
+	}
+	img = orient(img, orientation)
+
+	return &Info{
+		ContentType: contentType,
+		Ext:         format.ext,
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Orientation: orientation,
+		image:       img,
+	}, nil
+}
+
+func (l Limits) exceeded(width, height int) bool {
+	if l.MaxDimension > 0 && (width > l.MaxDimension || height > l.MaxDimension) {
+		return true
+	}
+	return l.MaxPixels > 0 && int64(width)*int64(height) > int64(l.MaxPixels)
+}
+
+// ContentObjectName returns the content addressed object name of data: the
+// hex encoded SHA-256 of data between prefix and ext, so identical files get
+// the same name, e.g. "messages/9f86d081...b0f00a08.png".
+func ContentObjectName(prefix string, data []byte, ext string) string {
+	sum := sha256.Sum256(data)
+	return prefix + hex.EncodeToString(sum[:]) + ext
+}
@@ -0,0 +1,111 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webpPixel is a 1x1 lossless WebP image.
+var webpPixel = []byte("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00\x2f\x00\x00\x00\x10\x07\x10\x11\x11\x88\x88\xfe\x07\x00")
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestInspectFormats(t *testing.T) {
+	img := testImage(40, 30)
+
+	var jpegBuf, gifBuf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpegBuf, img, nil))
+	require.NoError(t, gif.Encode(&gifBuf, img, nil))
+
+	tests := []struct {
+		data        []byte
+		contentType string
+		ext         string
+		width       int
+	}{
+		{encodePNG(t, img), TypePNG, ".png", 40},
+		{jpegBuf.Bytes(), TypeJPEG, ".jpg", 40},
+		{gifBuf.Bytes(), TypeGIF, ".gif", 40},
+		{webpPixel, TypeWebP, ".webp", 1},
+	}
+	for _, tt := range tests {
+		info, err := Inspect(tt.data, Limits{MaxDimension: 100, MaxPixels: 10000})
+		require.NoError(t, err, tt.contentType)
+		assert.Equal(t, tt.contentType, info.ContentType)
+		assert.Equal(t, tt.ext, info.Ext)
+		assert.Equal(t, tt.width, info.Width)
+	}
+}
+
+func TestInspectRejectsOtherFiles(t *testing.T) {
+	_, err := Inspect([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"), Limits{})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = Inspect([]byte("GIF89a not really"), Limits{})
+	assert.ErrorIs(t, err, ErrInvalidImage)
+
+	// Valid header, truncated pixel data
+	data := encodePNG(t, testImage(40, 30))
+	_, err = Inspect(data[:len(data)/2], Limits{})
+	assert.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestInspectDimensionLimits(t *testing.T) {
+	data := encodePNG(t, testImage(40, 30))
+
+	var dimErr *DimensionError
+	_, err := Inspect(data, Limits{MaxDimension: 39})
+	require.ErrorAs(t, err, &dimErr)
+	assert.Equal(t, 40, dimErr.Width)
+
+	_, err = Inspect(data, Limits{MaxPixels: 1199})
+	assert.ErrorAs(t, err, &dimErr)
+
+	_, err = Inspect(data, Limits{MaxDimension: 40, MaxPixels: 1200})
+	assert.NoError(t, err)
+}
+
+func TestInspectRejectsBombWithoutDecoding(t *testing.T) {
+	// Declare 100000x100000 pixels in the header of a tiny PNG
+	data := encodePNG(t, testImage(1, 1))
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:4], 100000)
+	binary.BigEndian.PutUint32(ihdr[4:8], 100000)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+
+	var dimErr *DimensionError
+	_, err := Inspect(data, Limits{MaxDimension: 8192, MaxPixels: 40_000_000})
+	require.ErrorAs(t, err, &dimErr)
+	assert.Equal(t, 100000, dimErr.Height)
+}
+
+func TestContentObjectName(t *testing.T) {
+	name := ContentObjectName("messages/", []byte("test"), ".png")
+	assert.Equal(t, "messages/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png", name)
+	assert.Equal(t, name, ContentObjectName("messages/", []byte("test"), ".png"))
+	assert.NotEqual(t, name, ContentObjectName("messages/", []byte("test2"), ".png"))
+}
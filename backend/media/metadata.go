@@ -0,0 +1,353 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+)
+
+// Orientations of the EXIF orientation tag, which tells viewers how to
+// transform the stored pixels for display.
+const (
+	OrientationNormal     = 1
+	OrientationFlipH      = 2
+	OrientationRotate180  = 3
+	OrientationFlipV      = 4
+	OrientationTranspose  = 5
+	OrientationRotate90   = 6
+	OrientationTransverse = 7
+	OrientationRotate270  = 8
+)
+
+// orientedJPEGQuality is the quality of JPEG images re-encoded to apply their orientation.
+const orientedJPEGQuality = 92
+
+var errMalformed = errors.New("malformed image")
+
+// StripMetadata returns data without EXIF, XMP, IPTC and comment metadata,
+// which may contain GPS coordinates and camera details. Metadata is removed
+// without re-encoding, except for JPEG images with an EXIF orientation: their
+// pixels are rotated and re-encoded, so they still display upright.
+// Color profiles are kept.
+func (i *Info) StripMetadata(data []byte) ([]byte, error) {
+	switch i.ContentType {
+	case TypeJPEG:
+		if i.Orientation > OrientationNormal {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, i.image, &jpeg.Options{Quality: orientedJPEGQuality}); err != nil {
+				return nil, err
+			}
+			i.Orientation = OrientationNormal
+			return buf.Bytes(), nil
+		}
+		return stripJPEG(data)
+	case TypePNG:
+		return stripPNG(data)
+	case TypeGIF:
+		return stripGIF(data)
+	case TypeWebP:
+		return stripWebP(data)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// jpegOrientation returns the EXIF orientation of a JPEG image, or
+// OrientationNormal if it has none.
+func jpegOrientation(data []byte) int {
+	orientation := OrientationNormal
+	walkJPEG(data, func(marker byte, segment []byte) bool {
+		payload := segment[4:]
+		if marker != 0xe1 || !bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+			return true
+		}
+		if o := exifOrientation(payload[6:]); o >= OrientationNormal && o <= OrientationRotate270 {
+			orientation = o
+		}
+		return false
+	})
+	return orientation
+}
+
+// exifOrientation reads the orientation tag of the first IFD of a TIFF structure.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// walkJPEG calls fn with the marker of each segment preceding the image data
+// and the segment, including marker and length, until fn returns false. It returns the offset of the start of
+// scan marker, or an error if the segments are malformed.
+func walkJPEG(data []byte, fn func(marker byte, segment []byte) bool) (int, error) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return 0, errMalformed
+	}
+	pos := 2
+	for {
+		// Markers may be preceded by fill bytes
+		for pos < len(data) && data[pos] == 0xff && pos+1 < len(data) && data[pos+1] == 0xff {
+			pos++
+		}
+		if pos+4 > len(data) || data[pos] != 0xff {
+			return 0, errMalformed
+		}
+		marker := data[pos+1]
+		if marker == 0xda {
+			return pos, nil
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 0, errMalformed
+		}
+		if !fn(marker, data[pos:pos+2+length]) {
+			return pos, nil
+		}
+		pos += 2 + length
+	}
+}
+
+// stripJPEG removes the APP1 (EXIF, XMP), APP3 to APP13, APP15 and comment
+// segments. APP0 (JFIF), APP2 (ICC profile) and APP14 (Adobe color transform)
+// are needed to display the image correctly and are kept.
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	sos, err := walkJPEG(data, func(marker byte, segment []byte) bool {
+		if keepJPEGSegment(marker) {
+			out = append(out, segment...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(out, data[sos:]...), nil
+}
+
+func keepJPEGSegment(marker byte) bool {
+	switch {
+	case marker == 0xfe: // COM
+		return false
+	case marker == 0xe0, marker == 0xe2, marker == 0xee: // APP0, APP2, APP14
+		return true
+	case marker >= 0xe0 && marker <= 0xef: // other APPn
+		return false
+	}
+	return true
+}
+
+// stripPNG removes the text, EXIF and modification time chunks.
+func stripPNG(data []byte) ([]byte, error) {
+	const signatureLength = 8
+	if len(data) < signatureLength {
+		return nil, errMalformed
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:signatureLength]...)
+	for pos := signatureLength; pos < len(data); {
+		if pos+12 > len(data) {
+			return nil, errMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if end > len(data) {
+			return nil, errMalformed
+		}
+		switch string(data[pos+4 : pos+8]) {
+		case "tEXt", "zTXt", "iTXt", "eXIf", "tIME":
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return out, nil
+}
+
+// stripWebP removes the EXIF and XMP chunks and clears their flags in the
+// extended format header.
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 {
+		return nil, errMalformed
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+	for pos := 12; pos < len(data); {
+		if pos+8 > len(data) {
+			return nil, errMalformed
+		}
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + size + size%2 // chunks are padded to an even size
+		if end > len(data) {
+			return nil, errMalformed
+		}
+		switch fourCC := string(data[pos : pos+4]); fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if size > 0 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF and XMP flags
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
+
+// stripGIF removes comment extensions and application extensions, such as
+// XMP, except the ones controlling animation loops.
+func stripGIF(data []byte) ([]byte, error) {
+	const headerLength = 13
+	if len(data) < headerLength {
+		return nil, errMalformed
+	}
+	pos := headerLength
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << ((flags & 0x07) + 1) // global color table
+	}
+	if pos > len(data) {
+		return nil, errMalformed
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:pos]...)
+
+	// skipSubBlocks returns the offset following the sub-blocks starting at p.
+	skipSubBlocks := func(p int) (int, error) {
+		for {
+			if p >= len(data) {
+				return 0, errMalformed
+			}
+			size := int(data[p])
+			p += 1 + size
+			if size == 0 {
+				return p, nil
+			}
+		}
+	}
+
+	for pos < len(data) {
+		start := pos
+		switch data[pos] {
+		case 0x3b: // trailer
+			return append(out, data[pos:]...), nil
+		case 0x2c: // image descriptor
+			if pos+10 > len(data) {
+				return nil, errMalformed
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << ((flags & 0x07) + 1) // local color table
+			}
+			pos++ // LZW minimum code size
+			end, err := skipSubBlocks(pos)
+			if err != nil {
+				return nil, err
+			}
+			pos = end
+			out = append(out, data[start:pos]...)
+		case 0x21: // extension
+			if pos+2 > len(data) {
+				return nil, errMalformed
+			}
+			label := data[pos+1]
+			end, err := skipSubBlocks(pos + 2)
+			if err != nil {
+				return nil, err
+			}
+			pos = end
+			if keepGIFExtension(label, data[start+2:end]) {
+				out = append(out, data[start:end]...)
+			}
+		default:
+			return nil, errMalformed
+		}
+	}
+	return nil, errMalformed
+}
+
+func keepGIFExtension(label byte, blocks []byte) bool {
+	switch label {
+	case 0xfe: // comment
+		return false
+	case 0xff: // application
+		if len(blocks) < 12 || blocks[0] != 11 {
+			return false
+		}
+		identifier := string(blocks[1:12])
+		return identifier == "NETSCAPE2.0" || identifier == "ANIMEXTS1.0"
+	}
+	return true
+}
+
+// orient transforms the pixels of img as described by an EXIF orientation.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= OrientationNormal || orientation > OrientationRotate270 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dw, dh := w, h
+	if orientation >= OrientationTranspose {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case OrientationFlipH:
+				dx, dy = w-1-x, y
+			case OrientationRotate180:
+				dx, dy = w-1-x, h-1-y
+			case OrientationFlipV:
+				dx, dy = x, h-1-y
+			case OrientationTranspose:
+				dx, dy = y, x
+			case OrientationRotate90:
+				dx, dy = h-1-y, x
+			case OrientationTransverse:
+				dx, dy = h-1-y, w-1-x
+			case OrientationRotate270:
+				dx, dy = y, w-1-x
+			}
+			s, d := src.PixOffset(x, y), dst.PixOffset(dx, dy)
+			copy(dst.Pix[d:d+4], src.Pix[s:s+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,164 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jpegSegment encodes a JPEG segment with the given marker and payload.
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// exifSegment encodes an APP1 segment with a GPS-like marker string and the orientation.
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.BigEndian.PutUint16(tiff[18:], orientation)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	return jpegSegment(0xe1, append(payload, "GPS 48.8584N 2.2945E"...))
+}
+
+// withSegments inserts segments after the start of image marker of a JPEG image.
+func withSegments(data []byte, segments ...[]byte) []byte {
+	out := append([]byte(nil), data[:2]...)
+	for _, segment := range segments {
+		out = append(out, segment...)
+	}
+	return append(out, data[2:]...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+func TestStripJPEG(t *testing.T) {
+	icc := jpegSegment(0xe2, []byte("ICC_PROFILE\x00profile"))
+	data := withSegments(encodeJPEG(t, testImage(40, 30)),
+		exifSegment(OrientationNormal), icc, jpegSegment(0xfe, []byte("shot on a phone")))
+
+	info, err := Inspect(data, Limits{})
+	require.NoError(t, err)
+	assert.Equal(t, OrientationNormal, info.Orientation)
+
+	stripped, err := info.StripMetadata(data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "GPS")
+	assert.NotContains(t, string(stripped), "shot on a phone")
+	assert.Contains(t, string(stripped), "ICC_PROFILE")
+
+	_, err = jpeg.Decode(bytes.NewReader(stripped))
+	assert.NoError(t, err)
+}
+
+func TestStripJPEGAppliesOrientation(t *testing.T) {
+	// A landscape image stored with "rotate 90° clockwise" displays as portrait
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	data := withSegments(encodeJPEG(t, img), exifSegment(OrientationRotate90))
+
+	info, err := Inspect(data, Limits{})
+	require.NoError(t, err)
+	assert.Equal(t, OrientationRotate90, info.Orientation)
+	assert.Equal(t, 20, info.Width)
+	assert.Equal(t, 40, info.Height)
+
+	stripped, err := info.StripMetadata(data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "GPS")
+	assert.Equal(t, OrientationNormal, info.Orientation)
+
+	decoded, err := jpeg.Decode(bytes.NewReader(stripped))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 20, 40), decoded.Bounds())
+	// The white left half of the stored image is now the top half
+	r, _, _, _ := decoded.At(10, 5).RGBA()
+	assert.Greater(t, r, uint32(0xc000))
+	r, _, _, _ = decoded.At(10, 35).RGBA()
+	assert.Less(t, r, uint32(0x4000))
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, color.White)
+
+	corners := map[int]image.Point{
+		OrientationFlipH:      {2, 0},
+		OrientationRotate180:  {2, 1},
+		OrientationFlipV:      {0, 1},
+		OrientationTranspose:  {0, 0},
+		OrientationRotate90:   {1, 0},
+		OrientationTransverse: {1, 2},
+		OrientationRotate270:  {0, 2},
+	}
+	for orientation, corner := range corners {
+		oriented := orient(img, orientation)
+		r, _, _, _ := oriented.At(corner.X, corner.Y).RGBA()
+		assert.Equal(t, uint32(0xffff), r, "orientation %d", orientation)
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	data := encodePNG(t, testImage(10, 10))
+	// Insert a tEXt chunk after IHDR
+	text := []byte("Comment\x00secret location")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)))
+	chunk = append(chunk, "tEXt"...)
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+	ihdrEnd := 8 + 12 + 13
+	data = append(append(append([]byte(nil), data[:ihdrEnd]...), chunk...), data[ihdrEnd:]...)
+
+	info, err := Inspect(data, Limits{})
+	require.NoError(t, err)
+	stripped, err := info.StripMetadata(data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "secret location")
+	assert.Equal(t, encodePNG(t, testImage(10, 10)), stripped)
+}
+
+func TestStripGIF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, gif.Encode(&buf, testImage(10, 10), nil))
+	data := buf.Bytes()
+
+	// Insert a comment extension before the trailer
+	comment := append([]byte{0x21, 0xfe, 14}, "secret comment"...)
+	comment = append(comment, 0)
+	data = append(append(append([]byte(nil), data[:len(data)-1]...), comment...), 0x3b)
+
+	info, err := Inspect(data, Limits{})
+	require.NoError(t, err)
+	stripped, err := info.StripMetadata(data)
+	require.NoError(t, err)
+	assert.NotContains(t, string(stripped), "secret comment")
+	assert.Equal(t, buf.Bytes(), stripped)
+}
+
+func TestStripWebP(t *testing.T) {
+	exif := append([]byte("EXIF\x05\x00\x00\x00"), "GPS!!\x00"...)
+	data := append(append([]byte(nil), webpPixel...), exif...)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+
+	info, err := Inspect(data, Limits{})
+	require.NoError(t, err)
+	stripped, err := info.StripMetadata(data)
+	require.NoError(t, err)
+	assert.Equal(t, webpPixel, stripped)
+}
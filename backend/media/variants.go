@@ -0,0 +1,142 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strings"
+
+	"chat-room/models"
+
+	"golang.org/x/image/draw"
+)
+
+// VariantSpec describes a downscaled copy of an image.
+type VariantSpec struct {
+	Name string
+	// MaxSize is the maximum width and height of the variant.
+	MaxSize int
+}
+
+// DefaultVariants are generated for image messages, smallest first.
+var DefaultVariants = []VariantSpec{
+	{Name: "thumb", MaxSize: 320},
+	{Name: "medium", MaxSize: 1024},
+}
+
+// jpegQuality is the quality of JPEG encoded variants.
+const jpegQuality = 85
+
+// Variant is an encoded downscaled copy of an image.
+type Variant struct {
+	Name        string
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+}
+
+// Variants returns a downscaled copy of the image for every spec smaller than
+// the image; images are never upscaled. Opaque images are encoded as JPEG and
+// images with transparency as PNG. Animated GIFs are reduced to their first frame.
+func (i *Info) Variants(specs []VariantSpec) ([]*Variant, error) {
+	var variants []*Variant
+	for _, spec := range specs {
+		if i.Width <= spec.MaxSize && i.Height <= spec.MaxSize {
+			continue
+		}
+		width, height := Fit(i.Width, i.Height, spec.MaxSize)
+		variant, err := encode(resize(i.image, width, height))
+		if err != nil {
+			return nil, err
+		}
+		variant.Name = spec.Name
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// Fit scales width and height down to fit a square of size, keeping the
+// aspect ratio. Both results are at least 1.
+func Fit(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+func resize(src image.Image, width, height int) *image.RGBA {
+	return resizeRect(src, src.Bounds(), width, height)
+}
+
+// resizeRect scales the part sr of src to width and height.
+func resizeRect(src image.Image, sr image.Rectangle, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, sr, draw.Src, nil)
+	return dst
+}
+
+func encode(img *image.RGBA) (*Variant, error) {
+	var buf bytes.Buffer
+	variant := &Variant{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	if img.Opaque() {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, err
+		}
+		variant.ContentType, variant.Ext = TypeJPEG, formats[TypeJPEG].ext
+	} else {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		variant.ContentType, variant.Ext = TypePNG, formats[TypePNG].ext
+	}
+	variant.Data = buf.Bytes()
+	return variant, nil
+}
+
+// PutFunc stores an object.
+type PutFunc func(ctx context.Context, objectName string, data []byte, contentType string) error
+
+// StoreVariants generates the DefaultVariants of the image stored as
+// objectName, stores them next to it with put and returns the media
+// description of the image, including its placeholder. Variants are named after the original, so
+// "messages/abc.png" gets "messages/abc_thumb.jpg". If storing a variant
+// fails, the returned description lists the variants stored before, which the
+// caller must delete.
+func (i *Info) StoreVariants(ctx context.Context, objectName string, put PutFunc) (*models.MessageMedia, error) {
+	variants, err := i.Variants(DefaultVariants)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.MessageMedia{
+		Key:         objectName,
+		ContentType: i.ContentType,
+		Width:       i.Width,
+		Height:      i.Height,
+		Variants:    []models.ImageVariant{},
+	}
+	result.Blurhash, result.Color = i.Placeholder()
+	base := strings.TrimSuffix(objectName, path.Ext(objectName))
+	for _, variant := range variants {
+		key := base + "_" + variant.Name + variant.Ext
+		if err := put(ctx, key, variant.Data, variant.ContentType); err != nil {
+			return result, err
+		}
+		result.Variants = append(result.Variants, models.ImageVariant{
+			Name:        variant.Name,
+			Key:         key,
+			ContentType: variant.ContentType,
+			Width:       variant.Width,
+			Height:      variant.Height,
+		})
+	}
+	return result, nil
+}
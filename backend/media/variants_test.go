@@ -0,0 +1,99 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFit(t *testing.T) {
+	w, h := Fit(2000, 1000, 320)
+	assert.Equal(t, []int{320, 160}, []int{w, h})
+	w, h = Fit(1000, 2000, 320)
+	assert.Equal(t, []int{160, 320}, []int{w, h})
+	w, h = Fit(100, 50, 320)
+	assert.Equal(t, []int{100, 50}, []int{w, h})
+	w, h = Fit(5000, 1, 320)
+	assert.Equal(t, []int{320, 1}, []int{w, h})
+}
+
+func TestVariants(t *testing.T) {
+	info, err := Inspect(encodePNG(t, testImage(400, 200)), Limits{})
+	require.NoError(t, err)
+
+	variants, err := info.Variants([]VariantSpec{{Name: "small", MaxSize: 100}, {Name: "large", MaxSize: 1000}})
+	require.NoError(t, err)
+	// The image is smaller than the large variant, which is skipped
+	require.Len(t, variants, 1)
+	assert.Equal(t, "small", variants[0].Name)
+	assert.Equal(t, 100, variants[0].Width)
+	assert.Equal(t, 50, variants[0].Height)
+	assert.Equal(t, TypeJPEG, variants[0].ContentType)
+
+	decoded, format, err := image.Decode(bytes.NewReader(variants[0].Data))
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", format)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), decoded.Bounds())
+}
+
+func TestVariantsKeepTransparency(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 400))
+	info, err := Inspect(encodePNG(t, img), Limits{})
+	require.NoError(t, err)
+
+	variants, err := info.Variants([]VariantSpec{{Name: "small", MaxSize: 100}})
+	require.NoError(t, err)
+	require.Len(t, variants, 1)
+	assert.Equal(t, TypePNG, variants[0].ContentType)
+	assert.Equal(t, ".png", variants[0].Ext)
+}
+
+func TestStoreVariants(t *testing.T) {
+	info, err := Inspect(encodePNG(t, testImage(2000, 1000)), Limits{})
+	require.NoError(t, err)
+
+	stored := make(map[string]string)
+	put := func(ctx context.Context, objectName string, data []byte, contentType string) error {
+		stored[objectName] = contentType
+		return nil
+	}
+	result, err := info.StoreVariants(context.Background(), "messages/abc.png", put)
+	require.NoError(t, err)
+
+	assert.Equal(t, "messages/abc.png", result.Key)
+	assert.Equal(t, 2000, result.Width)
+	assert.Len(t, result.Blurhash, 28)
+	assert.NotEmpty(t, result.Color)
+	require.Len(t, result.Variants, 2)
+	assert.Equal(t, "thumb", result.Variants[0].Name)
+	assert.Equal(t, "messages/abc_thumb.jpg", result.Variants[0].Key)
+	assert.Equal(t, 320, result.Variants[0].Width)
+	assert.Equal(t, "messages/abc_medium.jpg", result.Variants[1].Key)
+	assert.Equal(t, map[string]string{
+		"messages/abc_thumb.jpg":  TypeJPEG,
+		"messages/abc_medium.jpg": TypeJPEG,
+	}, stored)
+}
+
+func TestStoreVariants_ReportsStoredVariantsOnFailure(t *testing.T) {
+	info, err := Inspect(encodePNG(t, testImage(2000, 1000)), Limits{})
+	require.NoError(t, err)
+
+	failure := errors.New("storage unavailable")
+	put := func(ctx context.Context, objectName string, data []byte, contentType string) error {
+		if objectName == "messages/abc_medium.jpg" {
+			return failure
+		}
+		return nil
+	}
+	result, err := info.StoreVariants(context.Background(), "messages/abc.png", put)
+	assert.ErrorIs(t, err, failure)
+	require.NotNil(t, result)
+	require.Len(t, result.Variants, 1)
+	assert.Equal(t, "messages/abc_thumb.jpg", result.Variants[0].Key)
+}
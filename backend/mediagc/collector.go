@@ -0,0 +1,196 @@
+// Package mediagc deletes blob store objects that are no longer referenced.
+//
+// Replaced avatars, images and files of removed messages and sessions,
+// deleted emoji and abandoned uploads leave their objects behind. The collector compares
+// the objects below the prefixes managed by the backend with the object
+// names referenced by the database, and deletes unreferenced objects once
+// they are older than a grace period. The grace period protects objects
+// stored just before the record referencing them is written. The multipart
+// uploads of abandoned resumable uploads are aborted with their records.
+//
+// Content addressed objects are reused without being stored again, so their
+// age does not tell whether they are about to be referenced. They are kept
+// while they have references, which is checked when they are deleted.
+package mediagc
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"chat-room/blob"
+	"chat-room/media"
+	"chat-room/models"
+)
+
+// Prefixes lists the object name prefixes managed by the collector. Other
+// objects in the blob store are never deleted.
+var Prefixes = []string{blob.AvatarPrefix, "messages/", "emojis/", "uploads/", "files/"}
+
+// Store is the subset of store.Store used by the collector.
+type Store interface {
+	GetReferencedObjectNames(ctx context.Context) ([]string, error)
+	DeleteExpiredUploads(ctx context.Context, before time.Time) ([]*models.Upload, error)
+	DeleteOrphanedMediaObject(ctx context.Context, objectName string, remove func(ctx context.Context) error) (bool, error)
+}
+
+// Options configures a Collector. Zero values are replaced by defaults.
+type Options struct {
+	// Interval is how often Run collects orphaned objects.
+	Interval time.Duration
+	// GracePeriod is the minimum age of deleted objects and of deleted expired uploads.
+	GracePeriod time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Interval <= 0 {
+		o.Interval = 24 * time.Hour
+	}
+	if o.GracePeriod <= 0 {
+		o.GracePeriod = 24 * time.Hour
+	}
+	return o
+}
+
+// Report summarizes a collection.
+type Report struct {
+	DryRun bool `json:"dry_run"`
+	// Scanned is the number of objects below the managed prefixes.
+	Scanned int `json:"scanned"`
+	// Referenced is the number of scanned objects still in use.
+	Referenced int `json:"referenced"`
+	// Recent is the number of unreferenced objects kept for the grace period.
+	Recent int `json:"recent"`
+	// Orphans are the deleted objects, or the objects a dry run would delete.
+	Orphans []*blob.ObjectInfo `json:"-"`
+	// Failed is the number of orphans that could not be deleted.
+	Failed int `json:"failed"`
+	// ReclaimedBytes is the total size of the orphans.
+	ReclaimedBytes int64 `json:"reclaimed_bytes"`
+	// ExpiredUploads is the number of deleted upload records.
+	ExpiredUploads int `json:"expired_uploads"`
+}
+
+// Collector deletes orphaned objects from a blob store.
+type Collector struct {
+	store Store
+	blobs blob.BlobStore
+	opts  Options
+	now   func() time.Time
+}
+
+// NewCollector creates a new collector. Call Run to collect periodically.
+func NewCollector(store Store, blobs blob.BlobStore, opts Options) *Collector {
+	return &Collector{
+		store: store,
+		blobs: blobs,
+		opts:  opts.withDefaults(),
+		now:   func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run collects orphaned objects every Interval until ctx is cancelled.
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := c.Collect(ctx, false)
+		if err != nil {
+			log.Printf("Error collecting orphaned media: %v", err)
+			continue
+		}
+		log.Printf("Collected orphaned media: %d of %d objects deleted, %d bytes reclaimed, %d failed, %d expired uploads removed",
+			len(report.Orphans)-report.Failed, report.Scanned, report.ReclaimedBytes, report.Failed, report.ExpiredUploads)
+	}
+}
+
+// Collect deletes the unreferenced objects older than the grace period. A
+// dry run only reports the objects that would be deleted.
+func (c *Collector) Collect(ctx context.Context, dryRun bool) (*Report, error) {
+	report := &Report{DryRun: dryRun}
+	cutoff := c.now().Add(-c.opts.GracePeriod)
+
+	// The references are loaded before listing the objects, so objects stored
+	// meanwhile are either referenced or too recent to be deleted.
+	names, err := c.store.GetReferencedObjectNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool, len(names))
+	for _, name := range names {
+		if strings.Contains(name, "://") {
+			name = blob.ObjectNameOf(name)
+		}
+		referenced[name] = true
+		// Users reference one size of their avatar, which keeps all sizes
+		if base, _, ext, ok := media.ParseAvatarObjectName(name); ok && strings.HasPrefix(name, blob.AvatarPrefix) {
+			for _, size := range media.AvatarSizes {
+				referenced[media.AvatarObjectName(base, size, ext)] = true
+			}
+		}
+	}
+
+	for _, prefix := range Prefixes {
+		objects, err := c.blobs.List(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			report.Scanned++
+			switch {
+			case referenced[object.Name]:
+				report.Referenced++
+			case object.LastModified.After(cutoff):
+				report.Recent++
+			case dryRun:
+				report.Orphans = append(report.Orphans, object)
+				report.ReclaimedBytes += object.Size
+			default:
+				name := object.Name
+				removed, err := c.store.DeleteOrphanedMediaObject(ctx, name, func(ctx context.Context) error {
+					return c.blobs.Delete(ctx, name)
+				})
+				if err != nil {
+					log.Printf("Failed to delete orphaned object %s: %v", name, err)
+					report.Orphans = append(report.Orphans, object)
+					report.Failed++
+					continue
+				}
+				if !removed {
+					report.Referenced++
+					continue
+				}
+				report.Orphans = append(report.Orphans, object)
+				report.ReclaimedBytes += object.Size
+			}
+		}
+	}
+
+	// Expired uploads no longer reference their objects, their records are
+	// only kept for the grace period
+	if !dryRun {
+		uploads, err := c.store.DeleteExpiredUploads(ctx, cutoff)
+		if err != nil {
+			return nil, err
+		}
+		report.ExpiredUploads = len(uploads)
+		for _, upload := range uploads {
+			if upload.MultipartID == "" || upload.Status != models.UploadStatusPending {
+				continue
+			}
+			if err := c.blobs.AbortMultipart(ctx, upload.ObjectName, upload.MultipartID); err != nil {
+				log.Printf("Failed to abort multipart upload of upload %s: %v", upload.ID, err)
+			}
+		}
+	}
+
+	return report, nil
+}
@@ -0,0 +1,164 @@
+package mediagc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat-room/blob"
+	"chat-room/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore implements Store in memory for testing
+type memoryStore struct {
+	names         []string
+	expired       []*models.Upload
+	expiredBefore time.Time
+	deleted       []string
+	// refs are the reference counts of content addressed objects
+	refs map[string]int
+}
+
+func (m *memoryStore) GetReferencedObjectNames(ctx context.Context) ([]string, error) {
+	return m.names, nil
+}
+
+func (m *memoryStore) DeleteExpiredUploads(ctx context.Context, before time.Time) ([]*models.Upload, error) {
+	m.expiredBefore = before
+	expired := m.expired
+	m.expired = nil
+	return expired, nil
+}
+
+func (m *memoryStore) DeleteOrphanedMediaObject(ctx context.Context, objectName string, remove func(ctx context.Context) error) (bool, error) {
+	if m.refs[objectName] > 0 {
+		return false, nil
+	}
+	if err := remove(ctx); err != nil {
+		return false, err
+	}
+	m.deleted = append(m.deleted, objectName)
+	return true, nil
+}
+
+func newTestCollector(t *testing.T, names []string, objects map[string]string) (*Collector, *memoryStore, blob.BlobStore) {
+	t.Helper()
+	blobs, err := blob.NewLocalStore(t.TempDir(), "http://example.com/blobs", "", []byte("secret"), time.Minute)
+	require.NoError(t, err)
+	for name, data := range objects {
+		require.NoError(t, blobs.Put(context.Background(), name, []byte(data), "image/png"))
+	}
+	st := &memoryStore{names: names}
+	return NewCollector(st, blobs, Options{GracePeriod: time.Hour}), st, blobs
+}
+
+func TestCollectDeletesOrphans(t *testing.T) {
+	ctx := context.Background()
+	c, st, blobs := newTestCollector(t,
+		[]string{"messages/kept.png", blob.AvatarPrefix + "1/new_256.png"},
+		map[string]string{
+			"messages/kept.png":                 "kept",
+			"messages/removed.png":              "removed",
+			blob.AvatarPrefix + "1/new_64.png":  "new",
+			blob.AvatarPrefix + "1/new_256.png": "new",
+			blob.AvatarPrefix + "1/old_256.png": "old",
+			"other/unmanaged.png":               "unmanaged",
+		})
+	now := time.Now().Add(2 * time.Hour)
+	c.now = func() time.Time { return now }
+	st.expired = []*models.Upload{{Status: models.UploadStatusPending}, {Status: models.UploadStatusCompleted}}
+
+	report, err := c.Collect(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, 5, report.Scanned)
+	assert.Equal(t, 3, report.Referenced)
+	assert.Len(t, report.Orphans, 2)
+	assert.Equal(t, int64(len("removed")+len("old")), report.ReclaimedBytes)
+	assert.Equal(t, 2, report.ExpiredUploads)
+	assert.Equal(t, now.Add(-time.Hour), st.expiredBefore)
+	assert.ElementsMatch(t, []string{"messages/removed.png", blob.AvatarPrefix + "1/old_256.png"}, st.deleted)
+
+	for name, exists := range map[string]bool{
+		"messages/kept.png":                 true,
+		"messages/removed.png":              false,
+		blob.AvatarPrefix + "1/new_64.png":  true,
+		blob.AvatarPrefix + "1/new_256.png": true,
+		blob.AvatarPrefix + "1/old_256.png": false,
+		"other/unmanaged.png":               true,
+	} {
+		_, err := blobs.Stat(ctx, name)
+		if exists {
+			assert.NoError(t, err, name)
+		} else {
+			assert.ErrorIs(t, err, blob.ErrNotFound, name)
+		}
+	}
+}
+
+func TestCollectKeepsReferencedContentAddressedObjects(t *testing.T) {
+	ctx := context.Background()
+	// The message of the reused object is not written yet, and the object
+	// keeps the age of its first upload
+	c, st, blobs := newTestCollector(t, nil, map[string]string{"messages/abc.png": "shared"})
+	c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	st.refs = map[string]int{"messages/abc.png": 1}
+
+	report, err := c.Collect(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Referenced)
+	assert.Empty(t, report.Orphans)
+	assert.Empty(t, st.deleted)
+
+	_, err = blobs.Stat(ctx, "messages/abc.png")
+	assert.NoError(t, err)
+}
+
+func TestCollectKeepsRecentObjects(t *testing.T) {
+	c, _, blobs := newTestCollector(t, nil, map[string]string{"uploads/1": "upload"})
+
+	report, err := c.Collect(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Recent)
+	assert.Empty(t, report.Orphans)
+
+	_, err = blobs.Stat(context.Background(), "uploads/1")
+	assert.NoError(t, err)
+}
+
+func TestCollectDryRun(t *testing.T) {
+	c, st, blobs := newTestCollector(t, nil, map[string]string{"emojis/s/1.png": "emoji"})
+	c.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	report, err := c.Collect(context.Background(), true)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	require.Len(t, report.Orphans, 1)
+	assert.Equal(t, "emojis/s/1.png", report.Orphans[0].Name)
+	assert.Equal(t, int64(len("emoji")), report.ReclaimedBytes)
+	assert.True(t, st.expiredBefore.IsZero())
+	assert.Empty(t, st.deleted)
+
+	_, err = blobs.Stat(context.Background(), "emojis/s/1.png")
+	assert.NoError(t, err)
+}
+
+func TestCollectAbortsAbandonedMultipartUploads(t *testing.T) {
+	ctx := context.Background()
+	c, st, blobs := newTestCollector(t, nil, nil)
+
+	uploadID, err := blobs.CreateMultipart(ctx, "files/1", "application/zip")
+	require.NoError(t, err)
+	part, err := blobs.PutPart(ctx, "files/1", uploadID, 1, []byte("data"))
+	require.NoError(t, err)
+	st.expired = []*models.Upload{{ObjectName: "files/1", MultipartID: uploadID, Status: models.UploadStatusPending}}
+
+	report, err := c.Collect(ctx, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.ExpiredUploads)
+
+	err = blobs.CompleteMultipart(ctx, "files/1", uploadID, []blob.Part{part})
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactWebhookTokens(t *testing.T) {
+	for uri, expected := range map[string]string{
+		"/api/hooks/123/secret-token":       "/api/hooks/123/REDACTED",
+		"/api/hooks/123/secret?x=1":         "/api/hooks/123/REDACTED?x=1",
+		"/api/hooks/123":                    "/api/hooks/123",
+		"/api/sessions/webhooks/123/rotate": "/api/sessions/webhooks/123/rotate",
+	} {
+		var logged, routed string
+		handler := RedactWebhookTokens(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logged, routed = r.RequestURI, r.URL.Path
+		}))
+		req := httptest.NewRequest(http.MethodPost, uri, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, expected, logged, uri)
+		assert.Equal(t, req.URL.Path, routed, uri)
+	}
+}
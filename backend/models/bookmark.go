@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Bookmark is a message saved by a user.
+type Bookmark struct {
+	UserID    uuid.UUID `json:"user_id"`
+	MessageID uuid.UUID `json:"message_id"`
+	SessionID uuid.UUID `json:"session_id"`
+	Note      string    `json:"note"`
+	Folder    string    `json:"folder"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Message and SessionName are filled in when bookmarks are listed.
+	Message     *Message `json:"message,omitempty"`
+	SessionName string   `json:"session_name,omitempty"`
+}
+
+// BookmarkFolder is a folder of a user's bookmarks.
+type BookmarkFolder struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
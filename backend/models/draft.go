@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Draft is the unsent message of a user in a session.
+// Concurrent edits from several devices are resolved by UpdatedAt: the latest write wins.
+type Draft struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	SessionID uuid.UUID  `json:"session_id"`
+	Content   string     `json:"content"`
+	ReplyToID *uuid.UUID `json:"reply_to_id,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomEmoji is an image uploaded to a session that members can use as :shortcode:.
+type CustomEmoji struct {
+	ID         uuid.UUID `json:"id"`
+	SessionID  uuid.UUID `json:"session_id"`
+	Shortcode  string    `json:"shortcode"`
+	ObjectName string    `json:"object_name"`
+	// URL is a presigned URL of the image, set when the emoji is served.
+	URL         string    `json:"url,omitempty"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedBy   uuid.UUID `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// EntityType identifies the kind of a message entity.
+type EntityType string
+
+const (
+	// EntityTypeCustomEmoji marks a :shortcode: of a custom emoji of the session.
+	EntityTypeCustomEmoji EntityType = "custom_emoji"
+)
+
+// MessageEntity annotates a range of a message's content.
+// Offset and Length count UTF-16 code units, so that clients written in
+// JavaScript can slice the content directly.
+type MessageEntity struct {
+	Type      EntityType `json:"type"`
+	Offset    int        `json:"offset"`
+	Length    int        `json:"length"`
+	Shortcode string     `json:"shortcode,omitempty"`
+	EmojiID   *uuid.UUID `json:"emoji_id,omitempty"`
+	URL       string     `json:"url,omitempty"`
+}
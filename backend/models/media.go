@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MediaObject records a stored object in the storage usage of its owner.
+type MediaObject struct {
+	ObjectName string    `json:"object_name"`
+	UserID     uuid.UUID `json:"user_id"`
+	// SessionID is set for objects belonging to a session, such as message
+	// images and custom emoji.
+	SessionID *uuid.UUID `json:"session_id,omitempty"`
+	Size      int64      `json:"size"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -11,13 +11,60 @@ type MessageType string
 const (
 	MessageTypeText  MessageType = "text"
 	MessageTypeImage MessageType = "image"
+	// MessageTypeFile messages share a file uploaded with a resumable upload.
+	MessageTypeFile MessageType = "file"
+	// MessageTypeSystem messages record membership and session changes.
+	// Their content is a JSON encoded SystemEvent.
+	MessageTypeSystem MessageType = "system"
 )
 
 type Message struct {
-	ID        uuid.UUID   `json:"id"`
-	Type      MessageType `json:"type"`
-	Content   string      `json:"content"`
-	UserID    uuid.UUID   `json:"user_id"`
-	SessionID uuid.UUID   `json:"session_id"`
-	Timestamp time.Time   `json:"timestamp"`
+	ID   uuid.UUID   `json:"id"`
+	Type MessageType `json:"type"`
+	// Content is the text of text messages and the object name of the image of
+	// image messages or the file of file messages, which is replaced with a
+	// presigned URL when served.
+	Content   string    `json:"content"`
+	UserID    uuid.UUID `json:"user_id"`
+	SessionID uuid.UUID `json:"session_id"`
+	Timestamp time.Time `json:"timestamp"`
+	// Seq numbers the messages of a session in insertion order, starting at 1.
+	Seq int64 `json:"seq"`
+	// Media describes the image of image messages uploaded since variants were
+	// introduced, and the file of file messages.
+	Media *MessageMedia `json:"media,omitempty"`
+	// Entities are derived from the content when the message is served and are not stored.
+	Entities []MessageEntity `json:"entities,omitempty"`
+}
+
+// MessageMedia describes the stored image of an image message or the stored
+// file of a file message.
+type MessageMedia struct {
+	// Key is the object name of the original image or of the file.
+	Key         string `json:"key"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	// Variants are downscaled copies of the image, smallest first. Images
+	// smaller than a variant size have no such variant.
+	Variants []ImageVariant `json:"variants,omitempty"`
+	// Blurhash is a compact blurred preview of the image (https://blurha.sh)
+	// and Color its average color as "#rrggbb", shown while the image loads.
+	Blurhash string `json:"blurhash,omitempty"`
+	Color    string `json:"color,omitempty"`
+	// Name is the original name of a file.
+	Name string `json:"name,omitempty"`
+	// Size is the size of a file in bytes.
+	Size int64 `json:"size,omitempty"`
+}
+
+// ImageVariant is a downscaled copy of an image, such as its thumbnail.
+type ImageVariant struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// URL is a presigned URL set when the message is served.
+	URL         string `json:"url,omitempty"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
 }
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ModerationAction is what a moderation filter does with a matching message.
+type ModerationAction string
+
+const (
+	// ModerationActionAllow posts the message unchanged.
+	ModerationActionAllow ModerationAction = "allow"
+	// ModerationActionRedact masks the matching text and posts the message.
+	ModerationActionRedact ModerationAction = "redact"
+	// ModerationActionFlag posts the message and records it for review.
+	ModerationActionFlag ModerationAction = "flag"
+	// ModerationActionReject refuses the message and tells the sender why.
+	ModerationActionReject ModerationAction = "reject"
+)
+
+// ModerationPattern is a regular expression rule of a session.
+type ModerationPattern struct {
+	Pattern string           `json:"pattern"`
+	Action  ModerationAction `json:"action"`
+	Reason  string           `json:"reason,omitempty"`
+}
+
+// ModerationSettings configures the filters applied to the messages of a session.
+// Zero values disable the corresponding filter.
+type ModerationSettings struct {
+	SessionID      uuid.UUID           `json:"session_id"`
+	BlockedWords   []string            `json:"blocked_words"`
+	WordAction     ModerationAction    `json:"word_action"`
+	Patterns       []ModerationPattern `json:"patterns"`
+	BlockedDomains []string            `json:"blocked_domains"`
+	// MaxLength limits messages to fewer characters than the server-wide limit.
+	MaxLength int `json:"max_length"`
+	// MaxRepeatedChars rejects messages repeating a character more often in a row.
+	MaxRepeatedChars int       `json:"max_repeated_chars"`
+	UpdatedBy        uuid.UUID `json:"updated_by"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ModerationFlag records a message flagged by a moderation filter.
+type ModerationFlag struct {
+	ID        uuid.UUID `json:"id"`
+	MessageID uuid.UUID `json:"message_id"`
+	SessionID uuid.UUID `json:"session_id"`
+	Filter    string    `json:"filter"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReportStatus is the review state of a message report.
+type ReportStatus string
+
+const (
+	ReportStatusOpen     ReportStatus = "open"
+	ReportStatusResolved ReportStatus = "resolved"
+)
+
+// ReportResolution is the action taken when a report was resolved.
+type ReportResolution string
+
+const (
+	ReportResolutionDismissed      ReportResolution = "dismissed"
+	ReportResolutionMessageDeleted ReportResolution = "message_deleted"
+	ReportResolutionAuthorMuted    ReportResolution = "author_muted"
+	ReportResolutionAuthorKicked   ReportResolution = "author_kicked"
+)
+
+// MessageReport is a message reported by a session member for review.
+// The message is copied into the report so that it outlives the message.
+type MessageReport struct {
+	ID          uuid.UUID        `json:"id"`
+	MessageID   uuid.UUID        `json:"message_id"`
+	SessionID   uuid.UUID        `json:"session_id"`
+	ReporterID  uuid.UUID        `json:"reporter_id"`
+	AuthorID    uuid.UUID        `json:"author_id"`
+	MessageType MessageType      `json:"message_type"`
+	Content     string           `json:"content"`
+	Reason      string           `json:"reason"`
+	Status      ReportStatus     `json:"status"`
+	Resolution  ReportResolution `json:"resolution,omitempty"`
+	ResolvedBy  *uuid.UUID       `json:"resolved_by,omitempty"`
+	ResolvedAt  *time.Time       `json:"resolved_at,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SystemAction identifies the change recorded by a system message.
+type SystemAction string
+
+const (
+	SystemActionMemberJoined   SystemAction = "member_joined"
+	SystemActionMemberLeft     SystemAction = "member_left"
+	SystemActionMemberKicked   SystemAction = "member_kicked"
+	SystemActionSessionRenamed SystemAction = "session_renamed"
+	SystemActionRoleChanged    SystemAction = "role_changed"
+	SystemActionMemberMuted    SystemAction = "member_muted"
+	SystemActionMessageRemoved SystemAction = "message_removed"
+)
+
+// SystemEvent is the payload of a system message. It is stored as JSON in
+// the message content so that clients can render it in their own words.
+type SystemEvent struct {
+	Action   SystemAction `json:"action"`
+	ActorID  uuid.UUID    `json:"actor_id"`
+	TargetID *uuid.UUID   `json:"target_id,omitempty"`
+	// Via describes how a member joined, e.g. "invite_link"
+	Via     string `json:"via,omitempty"`
+	OldName string `json:"old_name,omitempty"`
+	NewName string `json:"new_name,omitempty"`
+	OldRole string `json:"old_role,omitempty"`
+	NewRole string `json:"new_role,omitempty"`
+	// MutedUntil is the end of a member's mute
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	// MessageID is the message removed by a moderator
+	MessageID *uuid.UUID `json:"message_id,omitempty"`
+}
+
+// NewSystemMessage creates a system message recording event in a session.
+// The actor of the event is the author of the message.
+func NewSystemMessage(sessionID uuid.UUID, event SystemEvent) (*Message, error) {
+	content, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{
+		ID:        uuid.New(),
+		Type:      MessageTypeSystem,
+		Content:   string(content),
+		UserID:    event.ActorID,
+		SessionID: sessionID,
+		Timestamp: time.Now().UTC(),
+	}, nil
+}
+
+// SystemEvent decodes the payload of a system message.
+func (m *Message) SystemEvent() (*SystemEvent, error) {
+	var event SystemEvent
+	if err := json.Unmarshal([]byte(m.Content), &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
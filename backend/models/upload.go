@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UploadPurpose is what an uploaded file becomes once the upload completes.
+type UploadPurpose string
+
+const (
+	// UploadPurposeMessageImage uploads are posted as image messages of a session.
+	UploadPurposeMessageImage UploadPurpose = "message_image"
+	// UploadPurposeAvatar uploads replace the avatar of the user.
+	UploadPurposeAvatar UploadPurpose = "avatar"
+	// UploadPurposeMessageFile uploads are posted as file messages of a session.
+	// They can only be uploaded with resumable uploads.
+	UploadPurposeMessageFile UploadPurpose = "message_file"
+)
+
+// UploadStatus is the state of an upload.
+type UploadStatus string
+
+const (
+	UploadStatusPending   UploadStatus = "pending"
+	UploadStatusCompleted UploadStatus = "completed"
+)
+
+// Upload is a slot for a file that the client uploads directly to storage
+// with a presigned URL, or in chunks through the API for resumable uploads,
+// and then completes through the API.
+type Upload struct {
+	ID      uuid.UUID     `json:"id"`
+	UserID  uuid.UUID     `json:"user_id"`
+	Purpose UploadPurpose `json:"purpose"`
+	// SessionID is set for message images and files.
+	SessionID *uuid.UUID `json:"session_id,omitempty"`
+	// ObjectName is where the client uploads the file; images are moved to
+	// their final object name when the upload completes.
+	ObjectName  string `json:"-"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	// Filename is the name of the uploaded file shown with message files.
+	Filename string `json:"filename,omitempty"`
+	// MultipartID identifies the multipart upload of the blob store assembling
+	// the chunks of a resumable upload. It is empty for presigned uploads.
+	MultipartID string `json:"-"`
+	// Offset is the number of bytes of a resumable upload received so far.
+	Offset int64 `json:"offset"`
+	// Parts are the stored chunks of a resumable upload, in order.
+	Parts     []UploadPart `json:"-"`
+	Status    UploadStatus `json:"status"`
+	CreatedAt time.Time    `json:"created_at"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
+
+// UploadPart is a stored chunk of a resumable upload.
+type UploadPart struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+}
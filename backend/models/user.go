@@ -12,5 +12,9 @@ type User struct {
 	Password  string    `json:"-"`
 	Nickname  string    `json:"nickname"`
 	AvatarURL string    `json:"avatar_url"`
-	CreatedAt time.Time `json:"-"`
+	// AvatarURLs are the URLs of the avatar keyed by size in pixels, set when
+	// the user is served. Avatars uploaded before they were resized have none.
+	AvatarURLs map[string]string `json:"avatar_urls,omitempty"`
+	IsBot      bool              `json:"is_bot"`
+	CreatedAt  time.Time         `json:"-"`
 }
@@ -13,4 +13,11 @@ type UserSession struct {
 	Role           string    `json:"role"`
 	JoinedAt       time.Time `json:"joined_at"`
 	LastReceivedAt time.Time `json:"-"`
+	// MutedUntil is set while the member is not allowed to post.
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+}
+
+// IsMuted reports whether the member is muted at the given time.
+func (us *UserSession) IsMuted(now time.Time) bool {
+	return us.MutedUntil != nil && now.Before(*us.MutedUntil)
 }
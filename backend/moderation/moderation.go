@@ -0,0 +1,436 @@
+// Package moderation checks messages against the filters configured for their
+// session before they are stored.
+//
+// A Pipeline runs its filters in order. Each filter allows, redacts, flags or
+// rejects the content: redactions are applied before the next filter runs,
+// flags are collected, and the first rejection stops the pipeline.
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+)
+
+// Limits on the size of session settings, which are compiled for every message.
+const (
+	MaxBlockedWords   = 500
+	MaxPatterns       = 50
+	MaxPatternLength  = 500
+	MaxBlockedDomains = 500
+)
+
+// Decision is the outcome of a single filter.
+type Decision struct {
+	Action models.ModerationAction
+	// Content is the redacted content, set when Action is redact.
+	Content string
+	Reason  string
+}
+
+// allow is the decision of a filter that found nothing.
+var allow = Decision{Action: models.ModerationActionAllow}
+
+// Filter inspects the content of a text message.
+type Filter interface {
+	// Name identifies the filter in flags.
+	Name() string
+	Check(content string) Decision
+}
+
+// Flag records why a filter flagged a message.
+type Flag struct {
+	Filter string
+	Reason string
+}
+
+// Verdict is the outcome of running a message through a Pipeline.
+type Verdict struct {
+	Rejected bool
+	// Reason tells the sender why the message was rejected.
+	Reason string
+	// Content is the message content after redactions.
+	Content string
+	Flags   []Flag
+}
+
+// Pipeline is an ordered chain of filters.
+type Pipeline struct {
+	filters []Filter
+}
+
+// NewPipeline creates a pipeline running filters in the given order.
+func NewPipeline(filters ...Filter) *Pipeline {
+	return &Pipeline{filters: filters}
+}
+
+// Check runs content through all filters.
+func (p *Pipeline) Check(content string) Verdict {
+	verdict := Verdict{Content: content}
+	for _, filter := range p.filters {
+		decision := filter.Check(verdict.Content)
+		switch decision.Action {
+		case models.ModerationActionReject:
+			return Verdict{Rejected: true, Reason: decision.Reason, Content: content}
+		case models.ModerationActionRedact:
+			verdict.Content = decision.Content
+		case models.ModerationActionFlag:
+			verdict.Flags = append(verdict.Flags, Flag{Filter: filter.Name(), Reason: decision.Reason})
+		}
+	}
+	return verdict
+}
+
+// Build compiles the settings of a session into a pipeline. maxLength is the
+// server-wide message length limit; settings may be nil.
+func Build(settings *models.ModerationSettings, maxLength int) (*Pipeline, error) {
+	if settings == nil {
+		return NewPipeline(MaxLength(maxLength)), nil
+	}
+
+	if settings.MaxLength > 0 && (maxLength <= 0 || settings.MaxLength < maxLength) {
+		maxLength = settings.MaxLength
+	}
+	filters := []Filter{MaxLength(maxLength)}
+
+	if settings.MaxRepeatedChars > 0 {
+		filters = append(filters, RepeatedChars(settings.MaxRepeatedChars))
+	}
+
+	if len(settings.BlockedDomains) > 0 {
+		filter, err := BlockedDomains(settings.BlockedDomains)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	if len(settings.BlockedWords) > 0 {
+		filter, err := BlockedWords(settings.BlockedWords, settings.WordAction)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	for _, pattern := range settings.Patterns {
+		filter, err := Pattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	return NewPipeline(filters...), nil
+}
+
+// Validate checks settings before they are stored.
+func Validate(settings *models.ModerationSettings) error {
+	switch {
+	case len(settings.BlockedWords) > MaxBlockedWords:
+		return fmt.Errorf("at most %d blocked words are allowed", MaxBlockedWords)
+	case len(settings.Patterns) > MaxPatterns:
+		return fmt.Errorf("at most %d patterns are allowed", MaxPatterns)
+	case len(settings.BlockedDomains) > MaxBlockedDomains:
+		return fmt.Errorf("at most %d blocked domains are allowed", MaxBlockedDomains)
+	case settings.MaxLength < 0 || settings.MaxRepeatedChars < 0:
+		return errors.New("limits must not be negative")
+	}
+	for _, pattern := range settings.Patterns {
+		if len(pattern.Pattern) > MaxPatternLength {
+			return fmt.Errorf("patterns must not be longer than %d characters", MaxPatternLength)
+		}
+	}
+	_, err := Build(settings, 0)
+	return err
+}
+
+// RejectedError is returned when a message is rejected by moderation.
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return "message rejected: " + e.Reason
+}
+
+// SettingsStore is the subset of store.Store used by the Moderator.
+type SettingsStore interface {
+	GetModerationSettings(ctx context.Context, sessionID uuid.UUID) (*models.ModerationSettings, error)
+}
+
+// Moderator applies the pipeline of a message's session.
+type Moderator struct {
+	store     SettingsStore
+	maxLength int
+}
+
+// NewModerator creates a moderator. maxLength is the server-wide message length limit.
+func NewModerator(store SettingsStore, maxLength int) *Moderator {
+	return &Moderator{store: store, maxLength: maxLength}
+}
+
+// Moderate checks a message and applies redactions to its content.
+// Only text messages are filtered; other messages are allowed as they are.
+func (m *Moderator) Moderate(ctx context.Context, message *models.Message) (Verdict, error) {
+	if message.Type != models.MessageTypeText {
+		return Verdict{Content: message.Content}, nil
+	}
+
+	settings, err := m.store.GetModerationSettings(ctx, message.SessionID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return Verdict{}, err
+	}
+
+	pipeline, err := Build(settings, m.maxLength)
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	verdict := pipeline.Check(message.Content)
+	if !verdict.Rejected {
+		message.Content = verdict.Content
+	}
+	return verdict, nil
+}
+
+type maxLengthFilter int
+
+// MaxLength rejects messages longer than limit characters. A limit of zero disables it.
+func MaxLength(limit int) Filter {
+	return maxLengthFilter(limit)
+}
+
+func (f maxLengthFilter) Name() string { return "max_length" }
+
+func (f maxLengthFilter) Check(content string) Decision {
+	if f > 0 && len([]rune(content)) > int(f) {
+		return Decision{
+			Action: models.ModerationActionReject,
+			Reason: fmt.Sprintf("Message is longer than %d characters", int(f)),
+		}
+	}
+	return allow
+}
+
+type repeatedCharsFilter int
+
+// RepeatedChars rejects messages repeating a non-space character more than max times in a row.
+func RepeatedChars(max int) Filter {
+	return repeatedCharsFilter(max)
+}
+
+func (f repeatedCharsFilter) Name() string { return "repeated_chars" }
+
+func (f repeatedCharsFilter) Check(content string) Decision {
+	var last rune
+	run := 0
+	for _, r := range content {
+		if r == last && !unicode.IsSpace(r) {
+			run++
+		} else {
+			last, run = r, 1
+		}
+		if run > int(f) {
+			return Decision{
+				Action: models.ModerationActionReject,
+				Reason: "Message repeats a character too many times",
+			}
+		}
+	}
+	return allow
+}
+
+type blockedWordsFilter struct {
+	re     *regexp.Regexp
+	action models.ModerationAction
+}
+
+// BlockedWords matches whole words case-insensitively. An empty action rejects.
+func BlockedWords(words []string, action models.ModerationAction) (Filter, error) {
+	if action == "" {
+		action = models.ModerationActionReject
+	}
+	if err := validateAction(action); err != nil {
+		return nil, err
+	}
+
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+	if len(quoted) == 0 {
+		return allowFilter{}, nil
+	}
+
+	re, err := regexp.Compile(`(?i)(?:` + strings.Join(quoted, "|") + `)`)
+	if err != nil {
+		return nil, err
+	}
+	return &blockedWordsFilter{re: re, action: action}, nil
+}
+
+func (f *blockedWordsFilter) Name() string { return "blocked_words" }
+
+func (f *blockedWordsFilter) Check(content string) Decision {
+	// Word boundaries are checked by hand, as \b only knows ASCII letters
+	var matches [][]int
+	for _, loc := range f.re.FindAllStringIndex(content, -1) {
+		if isWordBoundary(content, loc[0], loc[1]) {
+			matches = append(matches, loc)
+		}
+	}
+	if len(matches) == 0 {
+		return allow
+	}
+	return decide(f.action, "Message contains a blocked word", content, matches)
+}
+
+type patternFilter struct {
+	re     *regexp.Regexp
+	action models.ModerationAction
+	reason string
+}
+
+// Pattern matches a regular expression rule.
+func Pattern(pattern models.ModerationPattern) (Filter, error) {
+	if err := validateAction(pattern.Action); err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(pattern.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern.Pattern, err)
+	}
+
+	reason := pattern.Reason
+	if reason == "" {
+		reason = "Message matches a blocked pattern"
+	}
+	return &patternFilter{re: re, action: pattern.Action, reason: reason}, nil
+}
+
+func (f *patternFilter) Name() string { return "pattern" }
+
+func (f *patternFilter) Check(content string) Decision {
+	matches := f.re.FindAllStringIndex(content, -1)
+	if len(matches) == 0 {
+		return allow
+	}
+	return decide(f.action, f.reason, content, matches)
+}
+
+// hostname matches domain names, with or without scheme and path.
+var hostname = regexp.MustCompile(`(?i)(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,}`)
+
+type blockedDomainsFilter struct {
+	domains []string
+}
+
+// BlockedDomains rejects messages linking to a domain or one of its subdomains.
+func BlockedDomains(domains []string) (Filter, error) {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if strings.Contains(domain, "://") {
+			u, err := url.Parse(domain)
+			if err != nil {
+				return nil, fmt.Errorf("invalid domain %q", domain)
+			}
+			domain = u.Hostname()
+		}
+		domain = strings.Trim(domain, ".")
+		if domain == "" {
+			continue
+		}
+		if !hostname.MatchString(domain) {
+			return nil, fmt.Errorf("invalid domain %q", domain)
+		}
+		normalized = append(normalized, domain)
+	}
+	return &blockedDomainsFilter{domains: normalized}, nil
+}
+
+func (f *blockedDomainsFilter) Name() string { return "blocked_domains" }
+
+func (f *blockedDomainsFilter) Check(content string) Decision {
+	for _, host := range hostname.FindAllString(content, -1) {
+		host = strings.ToLower(host)
+		for _, domain := range f.domains {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return Decision{
+					Action: models.ModerationActionReject,
+					Reason: "Message links to a blocked domain",
+				}
+			}
+		}
+	}
+	return allow
+}
+
+// allowFilter lets everything pass.
+type allowFilter struct{}
+
+func (allowFilter) Name() string          { return "allow" }
+func (allowFilter) Check(string) Decision { return allow }
+
+// decide turns the matches of a filter into a decision.
+func decide(action models.ModerationAction, reason, content string, matches [][]int) Decision {
+	switch action {
+	case models.ModerationActionRedact:
+		return Decision{Action: action, Content: redact(content, matches), Reason: reason}
+	case models.ModerationActionAllow:
+		return allow
+	}
+	return Decision{Action: action, Reason: reason}
+}
+
+// redact replaces the matched ranges with one asterisk per character.
+func redact(content string, matches [][]int) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range matches {
+		b.WriteString(content[last:loc[0]])
+		b.WriteString(strings.Repeat("*", len([]rune(content[loc[0]:loc[1]]))))
+		last = loc[1]
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+func isWordBoundary(content string, start, end int) bool {
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+	}
+	if start > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(content[:start]); isWord(r) {
+			return false
+		}
+	}
+	if end < len(content) {
+		if r, _ := utf8.DecodeRuneInString(content[end:]); isWord(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func validateAction(action models.ModerationAction) error {
+	switch action {
+	case models.ModerationActionAllow, models.ModerationActionRedact,
+		models.ModerationActionFlag, models.ModerationActionReject:
+		return nil
+	}
+	return fmt.Errorf("invalid action %q", action)
+}
@@ -0,0 +1,139 @@
+package moderation
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxLength(t *testing.T) {
+	p := NewPipeline(MaxLength(5))
+	assert.False(t, p.Check("héllo").Rejected)
+
+	verdict := p.Check("hello!")
+	assert.True(t, verdict.Rejected)
+	assert.Equal(t, "Message is longer than 5 characters", verdict.Reason)
+}
+
+func TestRepeatedChars(t *testing.T) {
+	p := NewPipeline(RepeatedChars(3))
+	assert.False(t, p.Check("cool     stuff!!!").Rejected)
+	assert.True(t, p.Check("nooooo").Rejected)
+}
+
+func TestBlockedWords(t *testing.T) {
+	filter, err := BlockedWords([]string{"darn", "heck"}, models.ModerationActionRedact)
+	require.NoError(t, err)
+
+	verdict := NewPipeline(filter).Check("Darn it, what the heck. Darned darnit")
+	assert.False(t, verdict.Rejected)
+	assert.Equal(t, "**** it, what the ****. Darned darnit", verdict.Content)
+
+	filter, err = BlockedWords([]string{"darn"}, "")
+	require.NoError(t, err)
+	assert.True(t, NewPipeline(filter).Check("oh darn").Rejected)
+
+	_, err = BlockedWords([]string{"darn"}, "delete")
+	assert.Error(t, err)
+}
+
+func TestPatternFlag(t *testing.T) {
+	filter, err := Pattern(models.ModerationPattern{
+		Pattern: `\b\d{4}-\d{4}-\d{4}-\d{4}\b`,
+		Action:  models.ModerationActionFlag,
+		Reason:  "Looks like a card number",
+	})
+	require.NoError(t, err)
+
+	verdict := NewPipeline(filter).Check("my card is 1234-5678-9012-3456")
+	assert.False(t, verdict.Rejected)
+	assert.Equal(t, "my card is 1234-5678-9012-3456", verdict.Content)
+	require.Len(t, verdict.Flags, 1)
+	assert.Equal(t, Flag{Filter: "pattern", Reason: "Looks like a card number"}, verdict.Flags[0])
+
+	_, err = Pattern(models.ModerationPattern{Pattern: "(", Action: models.ModerationActionReject})
+	assert.Error(t, err)
+}
+
+func TestBlockedDomains(t *testing.T) {
+	filter, err := BlockedDomains([]string{"https://Evil.example/", "spam.test"})
+	require.NoError(t, err)
+	p := NewPipeline(filter)
+
+	assert.True(t, p.Check("see https://evil.example/path").Rejected)
+	assert.True(t, p.Check("go to www.spam.test now").Rejected)
+	assert.True(t, p.Check("cdn.evil.example").Rejected)
+	assert.False(t, p.Check("notevil.example and example.com").Rejected)
+}
+
+func TestPipelineOrder(t *testing.T) {
+	redact, err := BlockedWords([]string{"secret"}, models.ModerationActionRedact)
+	require.NoError(t, err)
+	reject, err := Pattern(models.ModerationPattern{Pattern: "secret", Action: models.ModerationActionReject})
+	require.NoError(t, err)
+
+	// The redaction hides the word from the following filter
+	verdict := NewPipeline(redact, reject).Check("a secret")
+	assert.False(t, verdict.Rejected)
+	assert.Equal(t, "a ******", verdict.Content)
+
+	verdict = NewPipeline(reject, redact).Check("a secret")
+	assert.True(t, verdict.Rejected)
+	assert.Equal(t, "a secret", verdict.Content)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(&models.ModerationSettings{BlockedWords: []string{"x"}}))
+	assert.Error(t, Validate(&models.ModerationSettings{MaxLength: -1}))
+	assert.Error(t, Validate(&models.ModerationSettings{BlockedWords: make([]string, MaxBlockedWords+1)}))
+	assert.Error(t, Validate(&models.ModerationSettings{
+		Patterns: []models.ModerationPattern{{Pattern: strings.Repeat("a", MaxPatternLength+1), Action: models.ModerationActionFlag}},
+	}))
+}
+
+type settingsStore map[uuid.UUID]*models.ModerationSettings
+
+func (s settingsStore) GetModerationSettings(ctx context.Context, sessionID uuid.UUID) (*models.ModerationSettings, error) {
+	if settings, ok := s[sessionID]; ok {
+		return settings, nil
+	}
+	return nil, store.ErrNotFound
+}
+
+func TestModerator(t *testing.T) {
+	moderated, plain := uuid.New(), uuid.New()
+	m := NewModerator(settingsStore{
+		moderated: {SessionID: moderated, BlockedWords: []string{"darn"}, WordAction: models.ModerationActionRedact, MaxLength: 20},
+	}, 100)
+	ctx := context.Background()
+
+	message := &models.Message{Type: models.MessageTypeText, SessionID: moderated, Content: "darn"}
+	verdict, err := m.Moderate(ctx, message)
+	require.NoError(t, err)
+	assert.False(t, verdict.Rejected)
+	assert.Equal(t, "****", message.Content)
+
+	// The session limit applies as it is lower than the server-wide one
+	message = &models.Message{Type: models.MessageTypeText, SessionID: moderated, Content: strings.Repeat("a", 21)}
+	verdict, err = m.Moderate(ctx, message)
+	require.NoError(t, err)
+	assert.True(t, verdict.Rejected)
+
+	message = &models.Message{Type: models.MessageTypeText, SessionID: plain, Content: strings.Repeat("a", 101)}
+	verdict, err = m.Moderate(ctx, message)
+	require.NoError(t, err)
+	assert.True(t, verdict.Rejected)
+
+	// Only text messages are filtered
+	message = &models.Message{Type: models.MessageTypeImage, SessionID: plain, Content: strings.Repeat("a", 101)}
+	verdict, err = m.Moderate(ctx, message)
+	require.NoError(t, err)
+	assert.False(t, verdict.Rejected)
+}
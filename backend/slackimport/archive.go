@@ -0,0 +1,181 @@
+// Package slackimport imports chat history from a Slack workspace export.
+//
+// A Slack export is a zip archive with users.json, channels.json (and
+// groups.json for private channels) at the top level and one directory per
+// channel holding a JSON file of messages per day.
+package slackimport
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// User is a member of the exported workspace.
+type User struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Deleted bool   `json:"deleted"`
+	IsBot   bool   `json:"is_bot"`
+	Profile struct {
+		RealName    string `json:"real_name"`
+		DisplayName string `json:"display_name"`
+	} `json:"profile"`
+}
+
+// DisplayName returns the name the user is shown with in Slack.
+func (u *User) DisplayName() string {
+	for _, name := range []string{u.Profile.DisplayName, u.Profile.RealName, u.Name} {
+		if name = strings.TrimSpace(name); name != "" {
+			return name
+		}
+	}
+	return u.ID
+}
+
+// Channel is a public or private channel of the exported workspace.
+type Channel struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Created int64    `json:"created"`
+	Creator string   `json:"creator"`
+	Members []string `json:"members"`
+}
+
+// File is a file shared in a message. Only its metadata is imported.
+type File struct {
+	Name      string `json:"name"`
+	Permalink string `json:"permalink"`
+}
+
+// Message is a single entry of a channel's daily message file.
+type Message struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	User     string `json:"user"`
+	BotID    string `json:"bot_id"`
+	Username string `json:"username"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	Files    []File `json:"files"`
+}
+
+// Time converts the Slack message timestamp ("seconds.microseconds") to a time.
+func (m *Message) Time() (time.Time, error) {
+	secs, frac, _ := strings.Cut(m.TS, ".")
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid message timestamp %q", m.TS)
+	}
+	var usec int64
+	if frac != "" {
+		frac = (frac + "000000")[:6]
+		if usec, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("invalid message timestamp %q", m.TS)
+		}
+	}
+	return time.Unix(sec, usec*int64(time.Microsecond)).UTC(), nil
+}
+
+// Archive is an opened Slack export.
+type Archive struct {
+	Users    []*User
+	Channels []*Channel
+
+	files map[string]*zip.File
+}
+
+// Open reads the users and channels of a Slack export. Message files are read
+// lazily by Messages.
+func Open(r *zip.Reader) (*Archive, error) {
+	a := &Archive{files: make(map[string]*zip.File)}
+	for _, f := range r.File {
+		a.files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	if err := a.readJSON("users.json", &a.Users); err != nil {
+		return nil, err
+	}
+	if err := a.readJSON("channels.json", &a.Channels); err != nil {
+		return nil, err
+	}
+
+	// Private channels are only present in exports of some plans
+	if _, ok := a.files["groups.json"]; ok {
+		var groups []*Channel
+		if err := a.readJSON("groups.json", &groups); err != nil {
+			return nil, err
+		}
+		a.Channels = append(a.Channels, groups...)
+	}
+
+	return a, nil
+}
+
+// Messages returns the messages of a channel in chronological order.
+func (a *Archive) Messages(channel *Channel) ([]*Message, error) {
+	var days []string
+	prefix := channel.Name + "/"
+	for name := range a.files {
+		if strings.HasPrefix(name, prefix) && path.Ext(name) == ".json" && path.Dir(name) == channel.Name {
+			days = append(days, name)
+		}
+	}
+	// Daily files are named YYYY-MM-DD.json and sort chronologically
+	sort.Strings(days)
+
+	var messages []*Message
+	for _, day := range days {
+		var dayMessages []*Message
+		if err := a.readJSON(day, &dayMessages); err != nil {
+			return nil, err
+		}
+		messages = append(messages, dayMessages...)
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool {
+		return compareTS(messages[i].TS, messages[j].TS) < 0
+	})
+	return messages, nil
+}
+
+func (a *Archive) readJSON(name string, dest interface{}) error {
+	f, ok := a.files[name]
+	if !ok {
+		return fmt.Errorf("%s not found in archive", name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, dest); err != nil {
+		return fmt.Errorf("parsing %s: %w", name, err)
+	}
+	return nil
+}
+
+// compareTS orders Slack timestamps, which are decimal strings of varying length.
+func compareTS(a, b string) int {
+	aSec, aFrac, _ := strings.Cut(a, ".")
+	bSec, bFrac, _ := strings.Cut(b, ".")
+	if len(aSec) != len(bSec) {
+		return len(aSec) - len(bSec)
+	}
+	if c := strings.Compare(aSec, bSec); c != 0 {
+		return c
+	}
+	return strings.Compare(aFrac, bFrac)
+}
@@ -0,0 +1,392 @@
+package slackimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"chat-room/blob"
+	"chat-room/media"
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+)
+
+// namespace derives stable IDs for imported records, so that a rerun of the
+// same export resolves to the rows created by the previous run.
+var namespace = uuid.MustParse("6f1c8d1e-3b7a-4b8e-9a52-4c0e1f7d2a90")
+
+// messageBatchSize is the number of messages inserted per round trip.
+const messageBatchSize = 500
+
+// importedSubtypes lists the message subtypes that carry user content.
+// Join/leave notices, topic changes and similar events are skipped.
+var importedSubtypes = map[string]bool{
+	"":                 true,
+	"bot_message":      true,
+	"me_message":       true,
+	"thread_broadcast": true,
+	"file_share":       true,
+}
+
+// Store is the subset of store.Store used by the importer.
+type Store interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	CheckNicknameExists(ctx context.Context, nickname string) (bool, error)
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSessionByID(ctx context.Context, id uuid.UUID) (*models.Session, error)
+	AddUserToSession(ctx context.Context, userID, sessionID uuid.UUID, role string) error
+	GetUserSessionsBySessionIDAndUserIDs(ctx context.Context, sessionID uuid.UUID, userIDs []uuid.UUID) ([]*models.UserSession, error)
+	CreateMessages(ctx context.Context, messages []*models.Message) (int, error)
+}
+
+// Stats summarizes an import run.
+type Stats struct {
+	UsersMatched     int
+	UsersCreated     int
+	SessionsCreated  int
+	SessionsExisting int
+	MembershipsAdded int
+	MessagesImported int
+	MessagesExisting int
+	MessagesSkipped  int
+}
+
+// Importer maps a Slack export onto users, sessions and messages.
+type Importer struct {
+	store    Store
+	owner    *models.User
+	accounts map[string]string
+	put      media.PutFunc
+
+	// users maps Slack user and bot IDs to local users
+	users map[string]*models.User
+}
+
+// NewImporter creates an importer. Owner, if not nil, becomes the creator of
+// channels whose Slack creator is unknown and is added to every imported session.
+// Accounts maps Slack user IDs to the usernames of the existing accounts they
+// are merged into; every other Slack user becomes a placeholder user. Put, if
+// not nil, stores the identicons of the placeholder users, which
+// become their avatars.
+func NewImporter(store Store, owner *models.User, accounts map[string]string, put media.PutFunc) *Importer {
+	return &Importer{
+		store:    store,
+		owner:    owner,
+		accounts: accounts,
+		put:      put,
+		users:    make(map[string]*models.User),
+	}
+}
+
+// Import imports all users, channels and messages of the archive.
+// Running it again on the same archive does not duplicate any data.
+func (im *Importer) Import(ctx context.Context, archive *Archive) (*Stats, error) {
+	stats := &Stats{}
+
+	for _, user := range archive.Users {
+		if _, err := im.ensureUser(ctx, user, stats); err != nil {
+			return stats, fmt.Errorf("importing user %s: %w", user.ID, err)
+		}
+	}
+
+	for _, channel := range archive.Channels {
+		if err := im.importChannel(ctx, archive, channel, stats); err != nil {
+			return stats, fmt.Errorf("importing channel %s: %w", channel.Name, err)
+		}
+	}
+
+	return stats, nil
+}
+
+// ensureUser returns the local user for a Slack user: the account it is mapped
+// to, or a placeholder that cannot log in. Slack usernames are never matched
+// against local usernames, as a local account sharing a Slack handle need not
+// belong to the same person.
+func (im *Importer) ensureUser(ctx context.Context, su *User, stats *Stats) (*models.User, error) {
+	if user, ok := im.users[su.ID]; ok {
+		return user, nil
+	}
+
+	if username, ok := im.accounts[su.ID]; ok {
+		user, err := im.store.GetUserByUsername(ctx, username)
+		if err != nil {
+			return nil, fmt.Errorf("mapped account %q: %w", username, err)
+		}
+		im.users[su.ID] = user
+		stats.UsersMatched++
+		return user, nil
+	}
+
+	// The placeholder of a previous run
+	user, err := im.store.GetUserByUsername(ctx, placeholderUsername(su.ID))
+	if err == nil {
+		im.users[su.ID] = user
+		stats.UsersMatched++
+		return user, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	nickname, err := im.uniqueNickname(ctx, su.DisplayName())
+	if err != nil {
+		return nil, err
+	}
+
+	user = &models.User{
+		ID:       uuid.NewSHA1(namespace, []byte("user:"+su.ID)),
+		Username: placeholderUsername(su.ID),
+		Nickname: nickname,
+		IsBot:    su.IsBot,
+	}
+	if im.put != nil {
+		if user.AvatarURL, err = media.StoreIdenticon(ctx, blob.AvatarPrefix, user.ID, im.put); err != nil {
+			return nil, err
+		}
+	}
+	if err := im.store.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	im.users[su.ID] = user
+	stats.UsersCreated++
+	return user, nil
+}
+
+// uniqueNickname returns name, or name with a suffix if it is already taken.
+func (im *Importer) uniqueNickname(ctx context.Context, name string) (string, error) {
+	candidate := name
+	for i := 1; ; i++ {
+		exists, err := im.store.CheckNicknameExists(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		if i == 1 {
+			candidate = fmt.Sprintf("%s (Slack)", name)
+		} else {
+			candidate = fmt.Sprintf("%s (Slack %d)", name, i)
+		}
+	}
+}
+
+func (im *Importer) importChannel(ctx context.Context, archive *Archive, channel *Channel, stats *Stats) error {
+	session, err := im.ensureSession(ctx, channel, stats)
+	if err != nil {
+		return err
+	}
+
+	messages, err := archive.Messages(channel)
+	if err != nil {
+		return err
+	}
+
+	memberIDs := make(map[uuid.UUID]bool)
+	for _, slackID := range channel.Members {
+		if user, ok := im.users[slackID]; ok {
+			memberIDs[user.ID] = true
+		}
+	}
+	if im.owner != nil {
+		memberIDs[im.owner.ID] = true
+	}
+
+	var batch []*models.Message
+	for _, sm := range messages {
+		message, err := im.convertMessage(ctx, session, sm, stats)
+		if err != nil {
+			return err
+		}
+		if message == nil {
+			stats.MessagesSkipped++
+			continue
+		}
+		memberIDs[message.UserID] = true
+
+		batch = append(batch, message)
+		if len(batch) == messageBatchSize {
+			if err := im.flush(ctx, batch, stats); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := im.flush(ctx, batch, stats); err != nil {
+		return err
+	}
+
+	return im.ensureMembers(ctx, session, memberIDs, stats)
+}
+
+// ensureSession returns the session for a channel, creating it on the first run.
+func (im *Importer) ensureSession(ctx context.Context, channel *Channel, stats *Stats) (*models.Session, error) {
+	id := uuid.NewSHA1(namespace, []byte("channel:"+channel.ID))
+
+	session, err := im.store.GetSessionByID(ctx, id)
+	if err == nil {
+		stats.SessionsExisting++
+		return session, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	creator, ok := im.users[channel.Creator]
+	if !ok {
+		if im.owner == nil {
+			return nil, fmt.Errorf("creator %s is unknown and no owner was given", channel.Creator)
+		}
+		creator = im.owner
+	}
+
+	session = &models.Session{
+		ID:        id,
+		Name:      channel.Name,
+		CreatorID: creator.ID,
+		CreatedAt: time.Unix(channel.Created, 0).UTC(),
+	}
+	if err := im.store.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	stats.SessionsCreated++
+	return session, nil
+}
+
+// ensureMembers adds the users that are not yet members of the session.
+func (im *Importer) ensureMembers(ctx context.Context, session *models.Session, memberIDs map[uuid.UUID]bool, stats *Stats) error {
+	ids := make([]uuid.UUID, 0, len(memberIDs))
+	for id := range memberIDs {
+		ids = append(ids, id)
+	}
+
+	existing, err := im.store.GetUserSessionsBySessionIDAndUserIDs(ctx, session.ID, ids)
+	if err != nil {
+		return err
+	}
+	for _, us := range existing {
+		delete(memberIDs, us.UserID)
+	}
+
+	for id := range memberIDs {
+		if err := im.store.AddUserToSession(ctx, id, session.ID, "member"); err != nil {
+			return err
+		}
+		stats.MembershipsAdded++
+	}
+	return nil
+}
+
+func (im *Importer) flush(ctx context.Context, batch []*models.Message, stats *Stats) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	inserted, err := im.store.CreateMessages(ctx, batch)
+	if err != nil {
+		return err
+	}
+	stats.MessagesImported += inserted
+	stats.MessagesExisting += len(batch) - inserted
+	return nil
+}
+
+// convertMessage maps a Slack message onto a text message. It returns nil for
+// messages that carry no user content.
+func (im *Importer) convertMessage(ctx context.Context, session *models.Session, sm *Message, stats *Stats) (*models.Message, error) {
+	if sm.Type != "message" || !importedSubtypes[sm.Subtype] {
+		return nil, nil
+	}
+
+	var author *models.User
+	switch {
+	case sm.User != "":
+		author = im.users[sm.User]
+	case sm.BotID != "":
+		bot := &User{ID: sm.BotID, IsBot: true}
+		bot.Profile.DisplayName = sm.Username
+		var err error
+		if author, err = im.ensureUser(ctx, bot, stats); err != nil {
+			return nil, err
+		}
+	}
+	if author == nil {
+		return nil, nil
+	}
+
+	content := im.convertText(sm.Text)
+	for _, file := range sm.Files {
+		line := "[file] " + file.Name
+		if file.Permalink != "" {
+			line += ": " + file.Permalink
+		}
+		content = strings.TrimSpace(content + "\n" + line)
+	}
+	if content == "" {
+		return nil, nil
+	}
+
+	timestamp, err := sm.Time()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Message{
+		ID:        uuid.NewSHA1(namespace, []byte("message:"+session.ID.String()+":"+sm.TS)),
+		Type:      models.MessageTypeText,
+		Content:   content,
+		UserID:    author.ID,
+		SessionID: session.ID,
+		Timestamp: timestamp,
+	}, nil
+}
+
+// slackEntity matches Slack's angle-bracket markup: <@U123>, <#C123|general>,
+// <!here>, <https://example.com|label>.
+var slackEntity = regexp.MustCompile(`<([^<>|]+)(?:\|([^<>]*))?>`)
+
+// convertText turns Slack mrkdwn markup into plain text.
+func (im *Importer) convertText(text string) string {
+	text = slackEntity.ReplaceAllStringFunc(text, func(match string) string {
+		parts := slackEntity.FindStringSubmatch(match)
+		target, label := parts[1], parts[2]
+
+		switch {
+		case strings.HasPrefix(target, "@"):
+			if user, ok := im.users[target[1:]]; ok {
+				return "@" + user.Nickname
+			}
+			if label != "" {
+				return "@" + strings.TrimPrefix(label, "@")
+			}
+			return target
+		case strings.HasPrefix(target, "#"):
+			if label != "" {
+				return "#" + label
+			}
+			return target
+		case strings.HasPrefix(target, "!"):
+			if label != "" {
+				return label
+			}
+			return "@" + strings.TrimPrefix(target, "!")
+		case label != "" && label != target:
+			return label + " (" + target + ")"
+		default:
+			return target
+		}
+	})
+	return strings.TrimSpace(html.UnescapeString(text))
+}
+
+func placeholderUsername(slackID string) string {
+	return "slack-" + strings.ToLower(slackID)
+}
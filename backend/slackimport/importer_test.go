@@ -0,0 +1,290 @@
+package slackimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	users    map[uuid.UUID]*models.User
+	sessions map[uuid.UUID]*models.Session
+	members  map[uuid.UUID]map[uuid.UUID]string
+	messages map[uuid.UUID]*models.Message
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		users:    make(map[uuid.UUID]*models.User),
+		sessions: make(map[uuid.UUID]*models.Session),
+		members:  make(map[uuid.UUID]map[uuid.UUID]string),
+		messages: make(map[uuid.UUID]*models.Message),
+	}
+}
+
+func (s *memoryStore) CreateUser(ctx context.Context, user *models.User) error {
+	s.users[user.ID] = user
+	return nil
+}
+
+func (s *memoryStore) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	for _, user := range s.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *memoryStore) CheckNicknameExists(ctx context.Context, nickname string) (bool, error) {
+	for _, user := range s.users {
+		if user.Nickname == nickname {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryStore) CreateSession(ctx context.Context, session *models.Session) error {
+	s.sessions[session.ID] = session
+	return s.AddUserToSession(ctx, session.CreatorID, session.ID, "creator")
+}
+
+func (s *memoryStore) GetSessionByID(ctx context.Context, id uuid.UUID) (*models.Session, error) {
+	if session, ok := s.sessions[id]; ok {
+		return session, nil
+	}
+	return nil, store.ErrNotFound
+}
+
+func (s *memoryStore) AddUserToSession(ctx context.Context, userID, sessionID uuid.UUID, role string) error {
+	if s.members[sessionID] == nil {
+		s.members[sessionID] = make(map[uuid.UUID]string)
+	}
+	s.members[sessionID][userID] = role
+	return nil
+}
+
+func (s *memoryStore) GetUserSessionsBySessionIDAndUserIDs(ctx context.Context, sessionID uuid.UUID, userIDs []uuid.UUID) ([]*models.UserSession, error) {
+	var result []*models.UserSession
+	for _, id := range userIDs {
+		if role, ok := s.members[sessionID][id]; ok {
+			result = append(result, &models.UserSession{UserID: id, SessionID: sessionID, Role: role})
+		}
+	}
+	return result, nil
+}
+
+func (s *memoryStore) CreateMessages(ctx context.Context, messages []*models.Message) (int, error) {
+	inserted := 0
+	for _, message := range messages {
+		if _, ok := s.messages[message.ID]; ok {
+			continue
+		}
+		s.messages[message.ID] = message
+		inserted++
+	}
+	return inserted, nil
+}
+
+func testArchive(t *testing.T) *Archive {
+	files := map[string]string{
+		"users.json": `[
+			{"id": "U1", "name": "alice", "profile": {"real_name": "Alice A", "display_name": "Alice"}},
+			{"id": "U2", "name": "bob", "profile": {"real_name": "Bob B"}}
+		]`,
+		"channels.json": `[
+			{"id": "C1", "name": "general", "created": 1700000000, "creator": "U1", "members": ["U1", "U2"]}
+		]`,
+		"general/2023-11-15.json": `[
+			{"type": "message", "user": "U2", "text": "second &amp; <@U1>", "ts": "1700000100.000200"},
+			{"type": "message", "subtype": "channel_join", "user": "U2", "text": "<@U2> has joined", "ts": "1700000050.000100"}
+		]`,
+		"general/2023-11-14.json": `[
+			{"type": "message", "user": "U1", "text": "see <https://example.com|the docs>", "ts": "1700000010.000100",
+			 "files": [{"name": "plan.pdf", "permalink": "https://slack.example/plan.pdf"}]},
+			{"type": "message", "subtype": "bot_message", "bot_id": "B1", "username": "deploybot", "text": "deployed", "ts": "1700000020.000000"}
+		]`,
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	archive, err := Open(zr)
+	require.NoError(t, err)
+	return archive
+}
+
+func TestMessagesAreChronological(t *testing.T) {
+	archive := testArchive(t)
+	require.Len(t, archive.Users, 2)
+	require.Len(t, archive.Channels, 1)
+
+	messages, err := archive.Messages(archive.Channels[0])
+	require.NoError(t, err)
+
+	var ts []string
+	for _, m := range messages {
+		ts = append(ts, m.TS)
+	}
+	assert.Equal(t, []string{"1700000010.000100", "1700000020.000000", "1700000050.000100", "1700000100.000200"}, ts)
+}
+
+func TestMessageTime(t *testing.T) {
+	m := &Message{TS: "1700000010.000250"}
+	got, err := m.Time()
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000010, 250000).UTC(), got)
+
+	_, err = (&Message{TS: "nope"}).Time()
+	assert.Error(t, err)
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	s := newMemoryStore()
+
+	// bob already has an account and is mapped to it
+	bob := &models.User{ID: uuid.New(), Username: "bob", Nickname: "Bobby"}
+	s.users[bob.ID] = bob
+	// alice's display name is taken by someone else
+	other := &models.User{ID: uuid.New(), Username: "other", Nickname: "Alice"}
+	s.users[other.ID] = other
+
+	accounts := map[string]string{"U2": "bob"}
+
+	stats, err := NewImporter(s, nil, accounts, nil).Import(ctx, testArchive(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, stats.UsersMatched)
+	assert.Equal(t, 2, stats.UsersCreated) // alice and the bot
+	assert.Equal(t, 1, stats.SessionsCreated)
+	assert.Equal(t, 3, stats.MessagesImported)
+	assert.Equal(t, 1, stats.MessagesSkipped)
+
+	alice, err := s.GetUserByUsername(ctx, "slack-u1")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice (Slack)", alice.Nickname)
+	assert.Empty(t, alice.Password)
+
+	bot, err := s.GetUserByUsername(ctx, "slack-b1")
+	require.NoError(t, err)
+	assert.True(t, bot.IsBot)
+	assert.Equal(t, "deploybot", bot.Nickname)
+
+	require.Len(t, s.sessions, 1)
+	for id, session := range s.sessions {
+		assert.Equal(t, "general", session.Name)
+		assert.Equal(t, alice.ID, session.CreatorID)
+		assert.Equal(t, "creator", s.members[id][alice.ID])
+		assert.Equal(t, "member", s.members[id][bob.ID])
+		assert.Equal(t, "member", s.members[id][bot.ID])
+	}
+
+	contents := make(map[uuid.UUID]string)
+	for _, m := range s.messages {
+		contents[m.UserID] = m.Content
+	}
+	assert.Equal(t, "see the docs (https://example.com)\n[file] plan.pdf: https://slack.example/plan.pdf", contents[alice.ID])
+	assert.Equal(t, "second & @Alice (Slack)", contents[bob.ID])
+	assert.Equal(t, "deployed", contents[bot.ID])
+
+	// A second run resolves everything to the rows of the first one
+	stats, err = NewImporter(s, nil, accounts, nil).Import(ctx, testArchive(t))
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.UsersCreated)
+	assert.Equal(t, 0, stats.SessionsCreated)
+	assert.Equal(t, 0, stats.MembershipsAdded)
+	assert.Equal(t, 0, stats.MessagesImported)
+	assert.Equal(t, 3, stats.MessagesExisting)
+	assert.Len(t, s.messages, 3)
+}
+
+func TestImportDoesNotMatchUsernames(t *testing.T) {
+	ctx := context.Background()
+	s := newMemoryStore()
+	// A local bob who is not the Slack bob
+	bob := &models.User{ID: uuid.New(), Username: "bob", Nickname: "Bobby"}
+	s.users[bob.ID] = bob
+
+	stats, err := NewImporter(s, nil, nil, nil).Import(ctx, testArchive(t))
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.UsersMatched)
+	assert.Equal(t, 3, stats.UsersCreated)
+
+	slackBob, err := s.GetUserByUsername(ctx, "slack-u2")
+	require.NoError(t, err)
+	assert.NotEqual(t, bob.ID, slackBob.ID)
+	for id := range s.sessions {
+		assert.NotContains(t, s.members[id], bob.ID)
+	}
+	for _, m := range s.messages {
+		assert.NotEqual(t, bob.ID, m.UserID)
+	}
+}
+
+func TestImportMappedAccountMustExist(t *testing.T) {
+	s := newMemoryStore()
+	_, err := NewImporter(s, nil, map[string]string{"U2": "bob"}, nil).Import(context.Background(), testArchive(t))
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	assert.Empty(t, s.messages)
+}
+
+func TestImportUnknownCreatorNeedsOwner(t *testing.T) {
+	s := newMemoryStore()
+	archive := testArchive(t)
+	archive.Channels[0].Creator = "U404"
+
+	_, err := NewImporter(s, nil, nil, nil).Import(context.Background(), archive)
+	assert.Error(t, err)
+
+	owner := &models.User{ID: uuid.New(), Username: "admin", Nickname: "Admin"}
+	s.users[owner.ID] = owner
+	_, err = NewImporter(s, owner, nil, nil).Import(context.Background(), archive)
+	require.NoError(t, err)
+	for _, session := range s.sessions {
+		assert.Equal(t, owner.ID, session.CreatorID)
+	}
+}
+
+func TestImportStoresIdenticonsOfPlaceholderUsers(t *testing.T) {
+	ctx := context.Background()
+	s := newMemoryStore()
+	bob := &models.User{ID: uuid.New(), Username: "bob", Nickname: "Bobby"}
+	s.users[bob.ID] = bob
+
+	stored := make(map[string]bool)
+	put := func(ctx context.Context, objectName string, data []byte, contentType string) error {
+		stored[objectName] = true
+		return nil
+	}
+	_, err := NewImporter(s, nil, map[string]string{"U2": "bob"}, put).Import(ctx, testArchive(t))
+	require.NoError(t, err)
+
+	alice, err := s.GetUserByUsername(ctx, "slack-u1")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(alice.AvatarURL, "user-"+alice.ID.String()+"/identicon_"))
+	assert.True(t, stored[alice.AvatarURL])
+	// Matched accounts keep their avatar
+	assert.Empty(t, bob.AvatarURL)
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -27,6 +28,12 @@ type CachedSession struct {
 	Members []*CachedUser   `json:"members"`
 }
 
+// CachedModerationSettings caches the moderation settings of a session,
+// including their absence, as they are read for every message.
+type CachedModerationSettings struct {
+	Settings *models.ModerationSettings `json:"settings"`
+}
+
 // Cache keys
 const (
 	userKey             = "user:%s"            // user:{userID}
@@ -36,6 +43,8 @@ const (
 	sessionUsersKey     = "session:%s:users"   // session:{sessionID}:users
 	userSessionKey      = "user_session:%s:%s" // user_session:{sessionID}:{userID}
 	userSessionBatchKey = "user_sessions:%s"   // user_sessions:{sessionID} - for batch operations
+	moderationKey       = "moderation:%s"      // moderation:{sessionID}
+	emojisKey           = "emojis:%s"          // emojis:{sessionID}
 )
 
 // Cache expiration times
@@ -44,6 +53,8 @@ const (
 	sessionExpiration     = 10 * time.Second
 	messageExpiration     = 1 * time.Hour
 	userSessionExpiration = 10 * time.Second
+	moderationExpiration  = 1 * time.Minute
+	emojisExpiration      = 10 * time.Minute
 )
 
 type RedisStore struct {
@@ -115,6 +126,18 @@ func (s *RedisStore) UpdateUser(ctx context.Context, user *models.User) error {
 	return s.cacheUser(ctx, user)
 }
 
+func (s *RedisStore) SetDefaultAvatar(ctx context.Context, userID uuid.UUID, avatarURL string) error {
+	if err := s.store.SetDefaultAvatar(ctx, userID, avatarURL); err != nil {
+		return err
+	}
+	s.invalidateCache(ctx, fmt.Sprintf(userKey, userID))
+	return nil
+}
+
+func (s *RedisStore) GetUserIDsWithoutAvatar(ctx context.Context, afterID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	return s.store.GetUserIDsWithoutAvatar(ctx, afterID, limit)
+}
+
 func (s *RedisStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	if err := s.store.DeleteUser(ctx, id); err != nil {
 		return err
@@ -158,6 +181,10 @@ func (s *RedisStore) CreateMessage(ctx context.Context, message *models.Message)
 	return s.cacheMessage(ctx, message)
 }
 
+func (s *RedisStore) CreateMessages(ctx context.Context, messages []*models.Message) (int, error) {
+	return s.store.CreateMessages(ctx, messages)
+}
+
 func (s *RedisStore) GetMessagesByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Message, error) {
 	return s.store.GetMessagesByIDs(ctx, ids)
 }
@@ -170,6 +197,18 @@ func (s *RedisStore) DeleteMessage(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (s *RedisStore) UpdateMessageMedia(ctx context.Context, id uuid.UUID, media *models.MessageMedia) error {
+	if err := s.store.UpdateMessageMedia(ctx, id, media); err != nil {
+		return err
+	}
+	s.invalidateCache(ctx, fmt.Sprintf(messageKey, id))
+	return nil
+}
+
+func (s *RedisStore) GetImageMessagesWithoutMedia(ctx context.Context, afterID uuid.UUID, limit int) ([]*models.Message, error) {
+	return s.store.GetImageMessagesWithoutMedia(ctx, afterID, limit)
+}
+
 // UserSession operations
 func (s *RedisStore) AddUserToSession(ctx context.Context, userID, sessionID uuid.UUID, role string) error {
 	if err := s.store.AddUserToSession(ctx, userID, sessionID, role); err != nil {
@@ -203,6 +242,32 @@ func (s *RedisStore) RemoveUserFromSession(ctx context.Context, userID, sessionI
 	return nil
 }
 
+func (s *RedisStore) UpdateUserSessionRole(ctx context.Context, userID, sessionID uuid.UUID, role string) error {
+	if err := s.store.UpdateUserSessionRole(ctx, userID, sessionID, role); err != nil {
+		return err
+	}
+
+	// Invalidate affected caches
+	s.invalidateCache(ctx,
+		fmt.Sprintf(userSessionKey, sessionID, userID),
+		fmt.Sprintf(userSessionBatchKey, sessionID),
+	)
+	return nil
+}
+
+func (s *RedisStore) SetUserSessionMutedUntil(ctx context.Context, userID, sessionID uuid.UUID, mutedUntil *time.Time) error {
+	if err := s.store.SetUserSessionMutedUntil(ctx, userID, sessionID, mutedUntil); err != nil {
+		return err
+	}
+
+	// Invalidate affected caches
+	s.invalidateCache(ctx,
+		fmt.Sprintf(userSessionKey, sessionID, userID),
+		fmt.Sprintf(userSessionBatchKey, sessionID),
+	)
+	return nil
+}
+
 func (s *RedisStore) GetSessionIDsByUserID(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
 	key := fmt.Sprintf(userSessionsKey, userID)
 	var sessionIDs []uuid.UUID
@@ -316,8 +381,267 @@ func (s *RedisStore) CheckNicknameExists(ctx context.Context, nickname string) (
 	return s.store.CheckNicknameExists(ctx, nickname)
 }
 
-func (s *RedisStore) GetMessageIDsBySessionID(ctx context.Context, sessionID uuid.UUID, limit int, before time.Time) ([]uuid.UUID, error) {
-	return s.store.GetMessageIDsBySessionID(ctx, sessionID, limit, before)
+func (s *RedisStore) GetMessagesBySessionID(ctx context.Context, sessionID uuid.UUID, cursor *store.MessageCursor, direction store.PageDirection, limit int) ([]*models.Message, error) {
+	return s.store.GetMessagesBySessionID(ctx, sessionID, cursor, direction, limit)
+}
+
+func (s *RedisStore) GetMessagesAfterSeq(ctx context.Context, sessionID uuid.UUID, afterSeq int64, limit int) ([]*models.Message, error) {
+	return s.store.GetMessagesAfterSeq(ctx, sessionID, afterSeq, limit)
+}
+
+func (s *RedisStore) GetMessageByID(ctx context.Context, id uuid.UUID) (*models.Message, error) {
+	return s.store.GetMessageByID(ctx, id)
+}
+
+// Webhook operations
+func (s *RedisStore) CreateIncomingWebhook(ctx context.Context, webhook *models.IncomingWebhook, bot *models.User) error {
+	if err := s.store.CreateIncomingWebhook(ctx, webhook, bot); err != nil {
+		return err
+	}
+
+	// The bot joined the session
+	s.invalidateCache(ctx,
+		fmt.Sprintf(sessionKey, webhook.SessionID),
+		fmt.Sprintf(userSessionsKey, bot.ID),
+		fmt.Sprintf(sessionUsersKey, webhook.SessionID),
+		fmt.Sprintf(userSessionKey, webhook.SessionID, bot.ID),
+		fmt.Sprintf(userSessionBatchKey, webhook.SessionID),
+	)
+	return s.cacheUser(ctx, bot)
+}
+
+func (s *RedisStore) GetIncomingWebhookByID(ctx context.Context, id uuid.UUID) (*models.IncomingWebhook, error) {
+	return s.store.GetIncomingWebhookByID(ctx, id)
+}
+
+func (s *RedisStore) GetIncomingWebhooksBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*models.IncomingWebhook, error) {
+	return s.store.GetIncomingWebhooksBySessionID(ctx, sessionID)
+}
+
+func (s *RedisStore) RotateIncomingWebhookToken(ctx context.Context, id uuid.UUID, tokenHash string) error {
+	return s.store.RotateIncomingWebhookToken(ctx, id, tokenHash)
+}
+
+func (s *RedisStore) RevokeIncomingWebhook(ctx context.Context, id uuid.UUID) error {
+	return s.store.RevokeIncomingWebhook(ctx, id)
+}
+
+func (s *RedisStore) CreateOutgoingWebhook(ctx context.Context, webhook *models.OutgoingWebhook) error {
+	return s.store.CreateOutgoingWebhook(ctx, webhook)
+}
+
+func (s *RedisStore) GetOutgoingWebhookByID(ctx context.Context, id uuid.UUID) (*models.OutgoingWebhook, error) {
+	return s.store.GetOutgoingWebhookByID(ctx, id)
+}
+
+func (s *RedisStore) GetOutgoingWebhooksBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*models.OutgoingWebhook, error) {
+	return s.store.GetOutgoingWebhooksBySessionID(ctx, sessionID)
+}
+
+func (s *RedisStore) DeleteOutgoingWebhook(ctx context.Context, id uuid.UUID) error {
+	return s.store.DeleteOutgoingWebhook(ctx, id)
+}
+
+func (s *RedisStore) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return s.store.CreateWebhookDelivery(ctx, delivery)
+}
+
+func (s *RedisStore) ClaimDueWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	return s.store.ClaimDueWebhookDeliveries(ctx, now, leaseUntil, limit)
+}
+
+func (s *RedisStore) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return s.store.UpdateWebhookDelivery(ctx, delivery)
+}
+
+func (s *RedisStore) GetWebhookDeliveriesByWebhookID(ctx context.Context, webhookID uuid.UUID, status models.DeliveryStatus, limit int) ([]*models.WebhookDelivery, error) {
+	return s.store.GetWebhookDeliveriesByWebhookID(ctx, webhookID, status, limit)
+}
+
+// Moderation operations
+func (s *RedisStore) GetModerationSettings(ctx context.Context, sessionID uuid.UUID) (*models.ModerationSettings, error) {
+	key := fmt.Sprintf(moderationKey, sessionID)
+
+	var cached CachedModerationSettings
+	if err := s.getFromCache(ctx, key, &cached); err == nil {
+		if cached.Settings == nil {
+			return nil, store.ErrNotFound
+		}
+		return cached.Settings, nil
+	}
+
+	settings, err := s.store.GetModerationSettings(ctx, sessionID)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	s.setCache(ctx, key, CachedModerationSettings{Settings: settings}, moderationExpiration)
+	if settings == nil {
+		return nil, store.ErrNotFound
+	}
+	return settings, nil
+}
+
+func (s *RedisStore) UpsertModerationSettings(ctx context.Context, settings *models.ModerationSettings) error {
+	if err := s.store.UpsertModerationSettings(ctx, settings); err != nil {
+		return err
+	}
+	s.invalidateCache(ctx, fmt.Sprintf(moderationKey, settings.SessionID))
+	return nil
+}
+
+func (s *RedisStore) CreateModerationFlag(ctx context.Context, flag *models.ModerationFlag) error {
+	return s.store.CreateModerationFlag(ctx, flag)
+}
+
+func (s *RedisStore) GetModerationFlagsBySessionID(ctx context.Context, sessionID uuid.UUID, limit int) ([]*models.ModerationFlag, error) {
+	return s.store.GetModerationFlagsBySessionID(ctx, sessionID, limit)
+}
+
+func (s *RedisStore) CreateMessageReport(ctx context.Context, report *models.MessageReport) error {
+	return s.store.CreateMessageReport(ctx, report)
+}
+
+func (s *RedisStore) GetMessageReportByID(ctx context.Context, id uuid.UUID) (*models.MessageReport, error) {
+	return s.store.GetMessageReportByID(ctx, id)
+}
+
+func (s *RedisStore) GetMessageReportsBySessionID(ctx context.Context, sessionID uuid.UUID, status models.ReportStatus, limit int) ([]*models.MessageReport, error) {
+	return s.store.GetMessageReportsBySessionID(ctx, sessionID, status, limit)
+}
+
+func (s *RedisStore) ResolveMessageReports(ctx context.Context, messageID uuid.UUID, resolution models.ReportResolution, resolvedBy uuid.UUID, resolvedAt time.Time) error {
+	return s.store.ResolveMessageReports(ctx, messageID, resolution, resolvedBy, resolvedAt)
+}
+
+// Bookmark operations
+func (s *RedisStore) SaveBookmark(ctx context.Context, bookmark *models.Bookmark) error {
+	return s.store.SaveBookmark(ctx, bookmark)
+}
+
+func (s *RedisStore) DeleteBookmark(ctx context.Context, userID, messageID uuid.UUID) error {
+	return s.store.DeleteBookmark(ctx, userID, messageID)
+}
+
+func (s *RedisStore) GetBookmarksByUserID(ctx context.Context, userID uuid.UUID, folder string, before *store.MessageCursor, limit int) ([]*models.Bookmark, error) {
+	return s.store.GetBookmarksByUserID(ctx, userID, folder, before, limit)
+}
+
+func (s *RedisStore) GetBookmarkFoldersByUserID(ctx context.Context, userID uuid.UUID) ([]*models.BookmarkFolder, error) {
+	return s.store.GetBookmarkFoldersByUserID(ctx, userID)
+}
+
+// Draft operations
+func (s *RedisStore) GetDraft(ctx context.Context, userID, sessionID uuid.UUID) (*models.Draft, error) {
+	return s.store.GetDraft(ctx, userID, sessionID)
+}
+
+func (s *RedisStore) SaveDraft(ctx context.Context, draft *models.Draft) (bool, error) {
+	return s.store.SaveDraft(ctx, draft)
+}
+
+func (s *RedisStore) DeleteDraft(ctx context.Context, userID, sessionID uuid.UUID, before time.Time) error {
+	return s.store.DeleteDraft(ctx, userID, sessionID, before)
+}
+
+// Custom emoji operations
+func (s *RedisStore) CreateCustomEmoji(ctx context.Context, emoji *models.CustomEmoji) error {
+	if err := s.store.CreateCustomEmoji(ctx, emoji); err != nil {
+		return err
+	}
+	s.invalidateCache(ctx, fmt.Sprintf(emojisKey, emoji.SessionID))
+	return nil
+}
+
+func (s *RedisStore) GetCustomEmojiByID(ctx context.Context, id uuid.UUID) (*models.CustomEmoji, error) {
+	return s.store.GetCustomEmojiByID(ctx, id)
+}
+
+// GetCustomEmojisBySessionID is cached, as the catalog is needed to annotate every message served.
+func (s *RedisStore) GetCustomEmojisBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*models.CustomEmoji, error) {
+	key := fmt.Sprintf(emojisKey, sessionID)
+
+	var emojis []*models.CustomEmoji
+	if err := s.getFromCache(ctx, key, &emojis); err == nil {
+		return emojis, nil
+	}
+
+	emojis, err := s.store.GetCustomEmojisBySessionID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if emojis == nil {
+		emojis = []*models.CustomEmoji{}
+	}
+	if err := s.setCache(ctx, key, emojis, emojisExpiration); err != nil {
+		s.logCacheError("Failed to cache custom emoji of session", sessionID, err)
+	}
+	return emojis, nil
+}
+
+func (s *RedisStore) DeleteCustomEmoji(ctx context.Context, id uuid.UUID) error {
+	emoji, err := s.store.GetCustomEmojiByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.store.DeleteCustomEmoji(ctx, id); err != nil {
+		return err
+	}
+	s.invalidateCache(ctx, fmt.Sprintf(emojisKey, emoji.SessionID))
+	return nil
+}
+
+// Upload operations
+func (s *RedisStore) CreateUpload(ctx context.Context, upload *models.Upload) error {
+	return s.store.CreateUpload(ctx, upload)
+}
+
+func (s *RedisStore) GetUploadByID(ctx context.Context, id uuid.UUID) (*models.Upload, error) {
+	return s.store.GetUploadByID(ctx, id)
+}
+
+func (s *RedisStore) AdvanceUpload(ctx context.Context, id uuid.UUID, offset, newOffset int64, part models.UploadPart) error {
+	return s.store.AdvanceUpload(ctx, id, offset, newOffset, part)
+}
+
+func (s *RedisStore) CompleteUpload(ctx context.Context, id uuid.UUID) error {
+	return s.store.CompleteUpload(ctx, id)
+}
+
+func (s *RedisStore) DeleteExpiredUploads(ctx context.Context, before time.Time) ([]*models.Upload, error) {
+	return s.store.DeleteExpiredUploads(ctx, before)
+}
+
+// Media operations
+func (s *RedisStore) GetReferencedObjectNames(ctx context.Context) ([]string, error) {
+	return s.store.GetReferencedObjectNames(ctx)
+}
+
+func (s *RedisStore) CreateMediaObject(ctx context.Context, object *models.MediaObject) error {
+	return s.store.CreateMediaObject(ctx, object)
+}
+
+func (s *RedisStore) DeleteMediaObjects(ctx context.Context, objectNames []string) error {
+	return s.store.DeleteMediaObjects(ctx, objectNames)
+}
+
+func (s *RedisStore) AcquireMediaBlob(ctx context.Context, objectName string, size int64) (int, error) {
+	return s.store.AcquireMediaBlob(ctx, objectName, size)
+}
+
+func (s *RedisStore) ReleaseMediaBlob(ctx context.Context, objectName string, remove func(ctx context.Context) error) error {
+	return s.store.ReleaseMediaBlob(ctx, objectName, remove)
+}
+
+func (s *RedisStore) DeleteOrphanedMediaObject(ctx context.Context, objectName string, remove func(ctx context.Context) error) (bool, error) {
+	return s.store.DeleteOrphanedMediaObject(ctx, objectName, remove)
+}
+
+func (s *RedisStore) GetUserStorageUsage(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.store.GetUserStorageUsage(ctx, userID)
+}
+
+func (s *RedisStore) GetSessionStorageUsage(ctx context.Context, sessionID uuid.UUID) (int64, error) {
+	return s.store.GetSessionStorageUsage(ctx, sessionID)
 }
 
 func (s *RedisStore) BeginTx(ctx context.Context) (store.Transaction, error) {
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageDirection selects which side of a cursor a page of messages is read from.
+type PageDirection int
+
+const (
+	// PageBefore reads messages older than the cursor, newest first.
+	PageBefore PageDirection = iota
+	// PageAfter reads messages newer than the cursor, oldest first.
+	PageAfter
+)
+
+// MessageCursor is a position in the message history of a session.
+// Messages are ordered by (timestamp, id), so messages sharing a timestamp
+// are neither skipped nor repeated across pages.
+type MessageCursor struct {
+	Timestamp time.Time
+	ID        uuid.UUID
+}
+
+// CursorOf returns the cursor positioned at message.
+func CursorOf(message *models.Message) MessageCursor {
+	return MessageCursor{Timestamp: message.Timestamp, ID: message.ID}
+}
+
+// BookmarkCursorOf returns the cursor positioned at bookmark. Bookmarks are
+// ordered by (created_at, message_id), so bookmarks saved at the same time
+// are neither skipped nor repeated across pages.
+func BookmarkCursorOf(bookmark *models.Bookmark) MessageCursor {
+	return MessageCursor{Timestamp: bookmark.CreatedAt, ID: bookmark.MessageID}
+}
+
+// Encode returns the opaque string form of the cursor.
+func (c MessageCursor) Encode() string {
+	raw := strconv.FormatInt(c.Timestamp.UnixMicro(), 10) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeMessageCursor parses a cursor produced by Encode.
+func DecodeMessageCursor(s string) (MessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return MessageCursor{}, ErrInvalidCursor
+	}
+
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return MessageCursor{}, ErrInvalidCursor
+	}
+	usec, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return MessageCursor{}, ErrInvalidCursor
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return MessageCursor{}, ErrInvalidCursor
+	}
+
+	return MessageCursor{Timestamp: time.UnixMicro(usec).UTC(), ID: parsedID}, nil
+}
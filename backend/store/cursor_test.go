@@ -0,0 +1,57 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageCursor_RoundTrip(t *testing.T) {
+	message := &models.Message{
+		ID:        uuid.New(),
+		Timestamp: time.Date(2024, 5, 1, 12, 30, 15, 123456000, time.UTC),
+	}
+
+	cursor := CursorOf(message)
+	decoded, err := DecodeMessageCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, message.ID, decoded.ID)
+	assert.True(t, message.Timestamp.Equal(decoded.Timestamp))
+}
+
+func TestMessageCursor_IsOpaque(t *testing.T) {
+	cursor := MessageCursor{Timestamp: time.Now(), ID: uuid.New()}
+	encoded := cursor.Encode()
+	assert.NotContains(t, encoded, cursor.ID.String())
+	assert.NotContains(t, encoded, "=")
+}
+
+func TestDecodeMessageCursor_Invalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"not base64!",
+		"MTIzNA",               // "1234", no separator
+		"YWJjOjEyMw",           // "abc:123"
+		"MTIzNDpub3QtYS11dWlk", // "1234:not-a-uuid"
+	} {
+		_, err := DecodeMessageCursor(s)
+		assert.ErrorIs(t, err, ErrInvalidCursor, "cursor %q", s)
+	}
+}
+
+func TestBookmarkCursorOf(t *testing.T) {
+	bookmark := &models.Bookmark{
+		MessageID: uuid.New(),
+		CreatedAt: time.Date(2024, 5, 1, 12, 30, 15, 123456000, time.UTC),
+	}
+
+	decoded, err := DecodeMessageCursor(BookmarkCursorOf(bookmark).Encode())
+	require.NoError(t, err)
+	assert.Equal(t, bookmark.MessageID, decoded.ID)
+	assert.True(t, bookmark.CreatedAt.Equal(decoded.Timestamp))
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) SaveBookmark(ctx context.Context, bookmark *models.Bookmark) error {
+	if bookmark.CreatedAt.IsZero() {
+		bookmark.CreatedAt = time.Now().UTC()
+	}
+
+	return s.loader.queryRow(ctx, SaveBookmarkQuery,
+		func(row pgx.Row) error {
+			return row.Scan(&bookmark.CreatedAt)
+		},
+		bookmark.UserID, bookmark.MessageID, bookmark.SessionID,
+		bookmark.Note, bookmark.Folder, bookmark.CreatedAt)
+}
+
+func (s *Store) DeleteBookmark(ctx context.Context, userID, messageID uuid.UUID) error {
+	return s.loader.exec(ctx, DeleteBookmarkQuery, userID, messageID)
+}
+
+func (s *Store) GetBookmarksByUserID(ctx context.Context, userID uuid.UUID, folder string, before *store.MessageCursor, limit int) ([]*models.Bookmark, error) {
+	var beforeTime *time.Time
+	var beforeID uuid.UUID
+	if before != nil {
+		beforeTime, beforeID = &before.Timestamp, before.ID
+	}
+
+	var bookmarks []*models.Bookmark
+	err := s.loader.queryRows(ctx, GetBookmarksByUserIDQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				bookmark := &models.Bookmark{Message: &models.Message{}}
+				msg := bookmark.Message
+				var media []byte
+				err := rows.Scan(
+					&bookmark.UserID, &bookmark.MessageID, &bookmark.SessionID,
+					&bookmark.Note, &bookmark.Folder, &bookmark.CreatedAt,
+					&msg.ID, &msg.Type, &msg.Content, &msg.UserID,
+					&msg.SessionID, &msg.Timestamp, &msg.Seq, &media,
+					&bookmark.SessionName,
+				)
+				if err != nil {
+					return err
+				}
+				if err := decodeMessageMedia(media, msg); err != nil {
+					return err
+				}
+				bookmarks = append(bookmarks, bookmark)
+			}
+			return nil
+		},
+		userID, folder, beforeTime, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return bookmarks, nil
+}
+
+func (s *Store) GetBookmarkFoldersByUserID(ctx context.Context, userID uuid.UUID) ([]*models.BookmarkFolder, error) {
+	var folders []*models.BookmarkFolder
+	err := s.loader.queryRows(ctx, GetBookmarkFoldersByUserIDQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				folder := &models.BookmarkFolder{}
+				if err := rows.Scan(&folder.Name, &folder.Count); err != nil {
+					return err
+				}
+				folders = append(folders, folder)
+			}
+			return nil
+		},
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	return folders, nil
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBookmarksByUserID_PagesBookmarksSavedAtOnce(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	user := &models.User{Username: "saver-" + uuid.NewString(), Nickname: "saver-" + uuid.NewString()}
+	require.NoError(t, s.CreateUser(ctx, user))
+	session := &models.Session{Name: "bookmarks", CreatorID: user.ID}
+	require.NoError(t, s.CreateSession(ctx, session))
+	t.Cleanup(func() {
+		s.DeleteSession(ctx, session.ID)
+		s.DeleteUser(ctx, user.ID)
+	})
+
+	// A bulk save gives every bookmark the same creation time
+	savedAt := time.Now().UTC().Truncate(time.Microsecond)
+	saved := make(map[uuid.UUID]bool)
+	for i := 0; i < 5; i++ {
+		message := &models.Message{Type: models.MessageTypeText, Content: "saved", UserID: user.ID, SessionID: session.ID}
+		require.NoError(t, s.CreateMessage(ctx, message))
+		require.NoError(t, s.SaveBookmark(ctx, &models.Bookmark{
+			UserID: user.ID, MessageID: message.ID, SessionID: session.ID, CreatedAt: savedAt,
+		}))
+		saved[message.ID] = true
+	}
+
+	listed := make(map[uuid.UUID]bool)
+	var before *store.MessageCursor
+	for {
+		page, err := s.GetBookmarksByUserID(ctx, user.ID, "", before, 2)
+		require.NoError(t, err)
+		for _, bookmark := range page {
+			assert.False(t, listed[bookmark.MessageID], "bookmark listed twice")
+			listed[bookmark.MessageID] = true
+		}
+		if len(page) < 2 {
+			break
+		}
+		cursor := store.BookmarkCursorOf(page[len(page)-1])
+		before = &cursor
+	}
+	assert.Equal(t, saved, listed)
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) GetDraft(ctx context.Context, userID, sessionID uuid.UUID) (*models.Draft, error) {
+	draft := &models.Draft{}
+	err := s.loader.queryRow(ctx, GetDraftQuery,
+		func(row pgx.Row) error {
+			return row.Scan(
+				&draft.UserID, &draft.SessionID, &draft.Content,
+				&draft.ReplyToID, &draft.UpdatedAt,
+			)
+		},
+		userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+func (s *Store) SaveDraft(ctx context.Context, draft *models.Draft) (bool, error) {
+	if draft.UpdatedAt.IsZero() {
+		draft.UpdatedAt = time.Now().UTC()
+	}
+
+	var applied bool
+	err := s.loader.queryRow(ctx, SaveDraftQuery,
+		func(row pgx.Row) error {
+			return row.Scan(
+				&draft.UserID, &draft.SessionID, &draft.Content,
+				&draft.ReplyToID, &draft.UpdatedAt, &applied,
+			)
+		},
+		draft.UserID, draft.SessionID, draft.Content, draft.ReplyToID, draft.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	return applied, nil
+}
+
+func (s *Store) DeleteDraft(ctx context.Context, userID, sessionID uuid.UUID, before time.Time) error {
+	return s.loader.exec(ctx, DeleteDraftQuery, userID, sessionID, before)
+}
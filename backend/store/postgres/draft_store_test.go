@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteDraft_RejectsOlderWrites(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	user := &models.User{Username: "drafter-" + uuid.NewString(), Nickname: "drafter-" + uuid.NewString()}
+	require.NoError(t, s.CreateUser(ctx, user))
+	session := &models.Session{Name: "drafts", CreatorID: user.ID}
+	require.NoError(t, s.CreateSession(ctx, session))
+	t.Cleanup(func() {
+		s.DeleteSession(ctx, session.ID)
+		s.DeleteUser(ctx, user.ID)
+	})
+
+	edited := time.Now().UTC().Add(-time.Minute).Truncate(time.Microsecond)
+	_, err := s.SaveDraft(ctx, &models.Draft{UserID: user.ID, SessionID: session.ID, Content: "hello", UpdatedAt: edited})
+	require.NoError(t, err)
+
+	deleted := edited.Add(10 * time.Second)
+	require.NoError(t, s.DeleteDraft(ctx, user.ID, session.ID, deleted))
+	_, err = s.GetDraft(ctx, user.ID, session.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	// A write from another device made before the delete arrives late
+	stale := &models.Draft{UserID: user.ID, SessionID: session.ID, Content: "hello wor", UpdatedAt: edited.Add(5 * time.Second)}
+	applied, err := s.SaveDraft(ctx, stale)
+	require.NoError(t, err)
+	assert.False(t, applied)
+	assert.Empty(t, stale.Content)
+	_, err = s.GetDraft(ctx, user.ID, session.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+
+	// Writes after the delete start a new draft
+	_, err = s.SaveDraft(ctx, &models.Draft{UserID: user.ID, SessionID: session.ID, Content: "next", UpdatedAt: deleted.Add(time.Second)})
+	require.NoError(t, err)
+	draft, err := s.GetDraft(ctx, user.ID, session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "next", draft.Content)
+}
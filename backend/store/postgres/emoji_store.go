@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) CreateCustomEmoji(ctx context.Context, emoji *models.CustomEmoji) error {
+	if emoji.ID == uuid.Nil {
+		emoji.ID = uuid.New()
+	}
+	if emoji.CreatedAt.IsZero() {
+		emoji.CreatedAt = time.Now().UTC()
+	}
+
+	return s.loader.exec(ctx, CreateCustomEmojiQuery,
+		emoji.ID, emoji.SessionID, emoji.Shortcode, emoji.ObjectName,
+		emoji.ContentType, emoji.Size, emoji.CreatedBy, emoji.CreatedAt)
+}
+
+func (s *Store) GetCustomEmojiByID(ctx context.Context, id uuid.UUID) (*models.CustomEmoji, error) {
+	emoji := &models.CustomEmoji{}
+	err := s.loader.queryRow(ctx, GetCustomEmojiByIDQuery,
+		func(row pgx.Row) error {
+			return scanCustomEmoji(row, emoji)
+		},
+		id)
+	if err != nil {
+		return nil, err
+	}
+	return emoji, nil
+}
+
+func (s *Store) GetCustomEmojisBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*models.CustomEmoji, error) {
+	var emojis []*models.CustomEmoji
+	err := s.loader.queryRows(ctx, GetCustomEmojisBySessionIDQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				emoji := &models.CustomEmoji{}
+				if err := scanCustomEmoji(rows, emoji); err != nil {
+					return err
+				}
+				emojis = append(emojis, emoji)
+			}
+			return nil
+		},
+		sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return emojis, nil
+}
+
+func (s *Store) DeleteCustomEmoji(ctx context.Context, id uuid.UUID) error {
+	return s.loader.exec(ctx, DeleteCustomEmojiQuery, id)
+}
+
+func scanCustomEmoji(row pgx.Row, emoji *models.CustomEmoji) error {
+	return row.Scan(
+		&emoji.ID, &emoji.SessionID, &emoji.Shortcode, &emoji.ObjectName,
+		&emoji.ContentType, &emoji.Size, &emoji.CreatedBy, &emoji.CreatedAt,
+	)
+}
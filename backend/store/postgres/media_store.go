@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) GetReferencedObjectNames(ctx context.Context) ([]string, error) {
+	var names []string
+	err := s.loader.queryRows(ctx, GetReferencedObjectNamesQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				var name string
+				if err := rows.Scan(&name); err != nil {
+					return err
+				}
+				names = append(names, name)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (s *Store) CreateMediaObject(ctx context.Context, object *models.MediaObject) error {
+	if object.CreatedAt.IsZero() {
+		object.CreatedAt = time.Now().UTC()
+	}
+	return s.loader.exec(ctx, CreateMediaObjectQuery,
+		object.ObjectName, object.UserID, object.SessionID, object.Size, object.CreatedAt)
+}
+
+func (s *Store) DeleteMediaObjects(ctx context.Context, objectNames []string) error {
+	return s.loader.exec(ctx, DeleteMediaObjectsQuery, objectNames)
+}
+
+// withMediaBlobLock runs fn in a transaction holding the advisory lock of the
+// object name. The lock serializes taking references to a content addressed
+// object with deleting it, including when it has no reference count yet.
+func (s *Store) withMediaBlobLock(ctx context.Context, objectName string, fn func(loader *queryLoader) error) error {
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	loader := tx.(*Tx).loader
+
+	if err := loader.exec(ctx, LockMediaBlobQuery, objectName); err != nil {
+		return err
+	}
+	if err := fn(loader); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) AcquireMediaBlob(ctx context.Context, objectName string, size int64) (int, error) {
+	var refCount int
+	err := s.withMediaBlobLock(ctx, objectName, func(loader *queryLoader) error {
+		return loader.queryRow(ctx, AcquireMediaBlobQuery,
+			func(row pgx.Row) error {
+				return row.Scan(&refCount)
+			},
+			objectName, size, time.Now().UTC())
+	})
+	if err != nil {
+		return 0, err
+	}
+	return refCount, nil
+}
+
+func (s *Store) ReleaseMediaBlob(ctx context.Context, objectName string, remove func(ctx context.Context) error) error {
+	var removeErr error
+	err := s.withMediaBlobLock(ctx, objectName, func(loader *queryLoader) error {
+		var refCount int
+		err := loader.queryRow(ctx, ReleaseMediaBlobQuery,
+			func(row pgx.Row) error {
+				return row.Scan(&refCount)
+			},
+			objectName)
+		if err != nil || refCount > 0 {
+			return err
+		}
+
+		removeErr = remove(ctx)
+		return deleteMediaBlob(ctx, loader, objectName)
+	})
+	if err != nil {
+		return err
+	}
+	return removeErr
+}
+
+func (s *Store) DeleteOrphanedMediaObject(ctx context.Context, objectName string, remove func(ctx context.Context) error) (bool, error) {
+	kept := false
+	err := s.withMediaBlobLock(ctx, objectName, func(loader *queryLoader) error {
+		var refCount int
+		err := loader.queryRow(ctx, GetMediaBlobRefCountQuery,
+			func(row pgx.Row) error {
+				return row.Scan(&refCount)
+			},
+			objectName)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		if refCount > 0 {
+			kept = true
+			return nil
+		}
+
+		if err := remove(ctx); err != nil {
+			return err
+		}
+		return deleteMediaBlob(ctx, loader, objectName)
+	})
+	if err != nil {
+		return false, err
+	}
+	return !kept, nil
+}
+
+// deleteMediaBlob removes the reference count and the record of a deleted object.
+func deleteMediaBlob(ctx context.Context, loader *queryLoader, objectName string) error {
+	if err := loader.exec(ctx, DeleteUnreferencedMediaBlobQuery, objectName); err != nil {
+		return err
+	}
+	return loader.exec(ctx, DeleteMediaObjectsQuery, []string{objectName})
+}
+
+func (s *Store) GetUserStorageUsage(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.queryStorageUsage(ctx, GetUserStorageUsageQuery, userID)
+}
+
+func (s *Store) GetSessionStorageUsage(ctx context.Context, sessionID uuid.UUID) (int64, error) {
+	return s.queryStorageUsage(ctx, GetSessionStorageUsageQuery, sessionID)
+}
+
+func (s *Store) queryStorageUsage(ctx context.Context, name QueryName, id uuid.UUID) (int64, error) {
+	var usage int64
+	err := s.loader.queryRow(ctx, name,
+		func(row pgx.Row) error {
+			return row.Scan(&usage)
+		},
+		id)
+	if err != nil {
+		return 0, err
+	}
+	return usage, nil
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageUsage_CountsPendingUploads(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	user := &models.User{Username: "uploader-" + uuid.NewString(), Nickname: "uploader-" + uuid.NewString()}
+	require.NoError(t, s.CreateUser(ctx, user))
+	session := &models.Session{Name: "uploads", CreatorID: user.ID}
+	require.NoError(t, s.CreateSession(ctx, session))
+	t.Cleanup(func() {
+		s.DeleteSession(ctx, session.ID)
+		s.DeleteUser(ctx, user.ID)
+	})
+
+	objectName := "messages/" + uuid.NewString() + ".png"
+	require.NoError(t, s.CreateMediaObject(ctx, &models.MediaObject{ObjectName: objectName, UserID: user.ID, SessionID: &session.ID, Size: 100}))
+	t.Cleanup(func() { s.DeleteMediaObjects(ctx, []string{objectName}) })
+
+	now := time.Now().UTC()
+	newUpload := func(size int64, expiresAt time.Time) *models.Upload {
+		id := uuid.New()
+		upload := &models.Upload{
+			ID:          id,
+			UserID:      user.ID,
+			Purpose:     models.UploadPurposeMessageImage,
+			SessionID:   &session.ID,
+			ObjectName:  "uploads/" + id.String(),
+			Size:        size,
+			ContentType: "image/png",
+			ExpiresAt:   expiresAt,
+		}
+		require.NoError(t, s.CreateUpload(ctx, upload))
+		return upload
+	}
+	newUpload(20, now.Add(time.Hour))
+	completed := newUpload(40, now.Add(time.Hour))
+	require.NoError(t, s.CompleteUpload(ctx, completed.ID))
+	newUpload(80, now.Add(-time.Minute))
+
+	// Only the pending upload that has not expired reserves its size
+	used, err := s.GetUserStorageUsage(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), used)
+	used, err = s.GetSessionStorageUsage(ctx, session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), used)
+}
@@ -2,9 +2,11 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"chat-room/models"
+	"chat-room/store"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -18,39 +20,176 @@ func (s *Store) CreateMessage(ctx context.Context, message *models.Message) erro
 		message.Timestamp = time.Now().UTC()
 	}
 
-	return s.loader.exec(ctx, CreateMessageQuery,
+	media, err := encodeMessageMedia(message.Media)
+	if err != nil {
+		return err
+	}
+
+	// The sequence number is reserved on the session row, which serializes
+	// concurrent inserts into the same session.
+	return s.loader.queryRow(ctx, CreateMessageQuery,
+		func(row pgx.Row) error {
+			return row.Scan(&message.Seq)
+		},
 		message.ID, message.Type, message.Content, message.UserID,
-		message.SessionID, message.Timestamp)
+		message.SessionID, message.Timestamp, media)
 }
 
-func (s *Store) GetMessageIDsBySessionID(ctx context.Context, sessionID uuid.UUID, limit int, before time.Time) ([]uuid.UUID, error) {
-	var messageIDs []uuid.UUID
-	err := s.loader.queryRows(ctx, GetMessageIDsBySessionQuery,
+func (s *Store) CreateMessages(ctx context.Context, messages []*models.Message) (int, error) {
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uuid.UUID, len(messages))
+	sessionIDs := make([]uuid.UUID, 0, len(messages))
+	for i, message := range messages {
+		if message.ID == uuid.Nil {
+			message.ID = uuid.New()
+		}
+		if message.Timestamp.IsZero() {
+			message.Timestamp = time.Now().UTC()
+		}
+		ids[i] = message.ID
+		sessionIDs = append(sessionIDs, message.SessionID)
+	}
+
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	loader := tx.(*Tx).loader
+
+	// The session rows stay locked until commit, so the messages inserted
+	// concurrently are visible to the existence check below and numbers are
+	// only taken for messages that are inserted
+	lastSeq := make(map[uuid.UUID]int64)
+	err = loader.queryRows(ctx, LockSessionSequencesQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				var id uuid.UUID
+				var seq int64
+				if err := rows.Scan(&id, &seq); err != nil {
+					return err
+				}
+				lastSeq[id] = seq
+			}
+			return nil
+		},
+		sessionIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	existing := make(map[uuid.UUID]bool)
+	err = loader.queryRows(ctx, GetExistingMessageIDsQuery,
 		func(rows pgx.Rows) error {
 			for rows.Next() {
 				var id uuid.UUID
 				if err := rows.Scan(&id); err != nil {
 					return err
 				}
-				messageIDs = append(messageIDs, id)
+				existing[id] = true
 			}
 			return nil
 		},
-		sessionID, before, limit)
+		ids)
+	if err != nil {
+		return 0, err
+	}
+
+	numbered := store.NumberMessages(messages, existing, lastSeq)
+	if len(numbered) == 0 {
+		return 0, nil
+	}
+
+	ids = make([]uuid.UUID, len(numbered))
+	types := make([]string, len(numbered))
+	contents := make([]string, len(numbered))
+	userIDs := make([]uuid.UUID, len(numbered))
+	sessionIDs = make([]uuid.UUID, len(numbered))
+	timestamps := make([]time.Time, len(numbered))
+	seqs := make([]int64, len(numbered))
+	media := make([][]byte, len(numbered))
+	for i, message := range numbered {
+		ids[i] = message.ID
+		types[i] = string(message.Type)
+		contents[i] = message.Content
+		userIDs[i] = message.UserID
+		sessionIDs[i] = message.SessionID
+		timestamps[i] = message.Timestamp
+		seqs[i] = message.Seq
+		if media[i], err = encodeMessageMedia(message.Media); err != nil {
+			return 0, err
+		}
+	}
+	err = loader.exec(ctx, CreateMessagesQuery,
+		ids, types, contents, userIDs, sessionIDs, timestamps, seqs, media)
+	if err != nil {
+		return 0, err
+	}
+
+	lockedIDs := make([]uuid.UUID, 0, len(lastSeq))
+	lastSeqs := make([]int64, 0, len(lastSeq))
+	for id, seq := range lastSeq {
+		lockedIDs = append(lockedIDs, id)
+		lastSeqs = append(lastSeqs, seq)
+	}
+	if err := loader.exec(ctx, SetSessionSequencesQuery, lockedIDs, lastSeqs); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(numbered), nil
+}
+
+// Bounds used in place of a nil cursor; they lie outside every stored (timestamp, id) pair.
+var (
+	newestCursor = store.MessageCursor{
+		Timestamp: time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC),
+		ID:        uuid.Max,
+	}
+	oldestCursor = store.MessageCursor{
+		Timestamp: time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		ID:        uuid.Nil,
+	}
+)
+
+func (s *Store) GetMessagesBySessionID(ctx context.Context, sessionID uuid.UUID, cursor *store.MessageCursor, direction store.PageDirection, limit int) ([]*models.Message, error) {
+	query, from := GetMessagesBeforeCursorQuery, newestCursor
+	if direction == store.PageAfter {
+		query, from = GetMessagesAfterCursorQuery, oldestCursor
+	}
+	if cursor != nil {
+		from = *cursor
+	}
+
+	var messages []*models.Message
+	err := s.loader.queryRows(ctx, query,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				msg := &models.Message{}
+				if err := scanMessage(rows, msg); err != nil {
+					return err
+				}
+				messages = append(messages, msg)
+			}
+			return nil
+		},
+		sessionID, from.Timestamp, from.ID, limit)
 	if err != nil {
 		return nil, err
 	}
-	return messageIDs, nil
+	return messages, nil
 }
 
 func (s *Store) GetMessageByID(ctx context.Context, id uuid.UUID) (*models.Message, error) {
 	msg := &models.Message{}
 	err := s.loader.queryRow(ctx, GetMessageByIDQuery,
 		func(row pgx.Row) error {
-			return row.Scan(
-				&msg.ID, &msg.Type, &msg.Content, &msg.UserID,
-				&msg.SessionID, &msg.Timestamp,
-			)
+			return scanMessage(row, msg)
 		},
 		id)
 	if err != nil {
@@ -69,11 +208,7 @@ func (s *Store) GetMessagesByIDs(ctx context.Context, ids []uuid.UUID) ([]*model
 		func(rows pgx.Rows) error {
 			for rows.Next() {
 				msg := &models.Message{}
-				err := rows.Scan(
-					&msg.ID, &msg.Type, &msg.Content, &msg.UserID,
-					&msg.SessionID, &msg.Timestamp,
-				)
-				if err != nil {
+				if err := scanMessage(rows, msg); err != nil {
 					return err
 				}
 				messages = append(messages, msg)
@@ -86,3 +221,79 @@ func (s *Store) GetMessagesByIDs(ctx context.Context, ids []uuid.UUID) ([]*model
 	}
 	return messages, nil
 }
+
+func (s *Store) GetMessagesAfterSeq(ctx context.Context, sessionID uuid.UUID, afterSeq int64, limit int) ([]*models.Message, error) {
+	var messages []*models.Message
+	err := s.loader.queryRows(ctx, GetMessagesAfterSeqQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				msg := &models.Message{}
+				if err := scanMessage(rows, msg); err != nil {
+					return err
+				}
+				messages = append(messages, msg)
+			}
+			return nil
+		},
+		sessionID, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (s *Store) UpdateMessageMedia(ctx context.Context, id uuid.UUID, media *models.MessageMedia) error {
+	encoded, err := encodeMessageMedia(media)
+	if err != nil {
+		return err
+	}
+	return s.loader.exec(ctx, UpdateMessageMediaQuery, id, encoded)
+}
+
+func (s *Store) GetImageMessagesWithoutMedia(ctx context.Context, afterID uuid.UUID, limit int) ([]*models.Message, error) {
+	var messages []*models.Message
+	err := s.loader.queryRows(ctx, GetImageMessagesWithoutMediaQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				msg := &models.Message{}
+				if err := scanMessage(rows, msg); err != nil {
+					return err
+				}
+				messages = append(messages, msg)
+			}
+			return nil
+		},
+		afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func scanMessage(row pgx.Row, msg *models.Message) error {
+	var media []byte
+	err := row.Scan(
+		&msg.ID, &msg.Type, &msg.Content, &msg.UserID,
+		&msg.SessionID, &msg.Timestamp, &msg.Seq, &media,
+	)
+	if err != nil {
+		return err
+	}
+	return decodeMessageMedia(media, msg)
+}
+
+// encodeMessageMedia returns the JSON of media, or nil for a NULL column.
+func encodeMessageMedia(media *models.MessageMedia) ([]byte, error) {
+	if media == nil {
+		return nil, nil
+	}
+	return json.Marshal(media)
+}
+
+func decodeMessageMedia(media []byte, msg *models.Message) error {
+	if media == nil {
+		return nil
+	}
+	msg.Media = &models.MessageMedia{}
+	return json.Unmarshal(media, msg.Media)
+}
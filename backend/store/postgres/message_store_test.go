@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateMessages_StoresMedia(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	user := &models.User{Username: "importer-" + uuid.NewString(), Nickname: "importer-" + uuid.NewString()}
+	require.NoError(t, s.CreateUser(ctx, user))
+	session := &models.Session{Name: "import", CreatorID: user.ID}
+	require.NoError(t, s.CreateSession(ctx, session))
+	t.Cleanup(func() {
+		s.DeleteSession(ctx, session.ID)
+		s.DeleteUser(ctx, user.ID)
+	})
+
+	timestamp := time.Now().UTC().Truncate(time.Microsecond)
+	image := &models.Message{
+		ID: uuid.New(), Type: models.MessageTypeImage, Content: "messages/cat.png",
+		UserID: user.ID, SessionID: session.ID, Timestamp: timestamp,
+		Media: &models.MessageMedia{Key: "messages/cat.png", ContentType: "image/png", Width: 640, Height: 480},
+	}
+	text := &models.Message{
+		ID: uuid.New(), Type: models.MessageTypeText, Content: "hello",
+		UserID: user.ID, SessionID: session.ID, Timestamp: timestamp.Add(time.Second),
+	}
+	inserted, err := s.CreateMessages(ctx, []*models.Message{image, text})
+	require.NoError(t, err)
+	assert.Equal(t, 2, inserted)
+
+	stored, err := s.GetMessageByID(ctx, image.ID)
+	require.NoError(t, err)
+	assert.Equal(t, image.Media, stored.Media)
+	stored, err = s.GetMessageByID(ctx, text.ID)
+	require.NoError(t, err)
+	assert.Nil(t, stored.Media)
+}
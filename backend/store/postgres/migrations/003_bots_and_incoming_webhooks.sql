@@ -0,0 +1,21 @@
+-- Add bot accounts and per-session incoming webhooks
+ALTER TABLE users ADD COLUMN is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE incoming_webhooks (
+    id           UUID PRIMARY KEY,
+    session_id   UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    bot_user_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name         TEXT NOT NULL,
+    token_hash   TEXT NOT NULL,
+    created_by   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL,
+    rotated_at   TIMESTAMP WITH TIME ZONE,
+    revoked_at   TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX incoming_webhooks_session_id_idx ON incoming_webhooks(session_id);
+
+-- Down
+DROP INDEX IF EXISTS incoming_webhooks_session_id_idx;
+DROP TABLE IF EXISTS incoming_webhooks;
+ALTER TABLE users DROP COLUMN IF EXISTS is_bot;
@@ -0,0 +1,35 @@
+-- Add outgoing webhook subscriptions and their delivery log
+CREATE TABLE outgoing_webhooks (
+    id           UUID PRIMARY KEY,
+    session_id   UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    url          TEXT NOT NULL,
+    secret       TEXT NOT NULL,
+    events       TEXT[] NOT NULL,
+    created_by   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+    id               UUID PRIMARY KEY,
+    webhook_id       UUID NOT NULL REFERENCES outgoing_webhooks(id) ON DELETE CASCADE,
+    event            TEXT NOT NULL,
+    payload          JSONB NOT NULL,
+    status           TEXT NOT NULL,
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_error       TEXT NOT NULL DEFAULT '',
+    response_status  INTEGER,
+    created_at       TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at     TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX outgoing_webhooks_session_id_idx ON outgoing_webhooks(session_id);
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_webhook_id_created_at_idx ON webhook_deliveries(webhook_id, created_at DESC);
+
+-- Down
+DROP INDEX IF EXISTS webhook_deliveries_webhook_id_created_at_idx;
+DROP INDEX IF EXISTS webhook_deliveries_pending_idx;
+DROP INDEX IF EXISTS outgoing_webhooks_session_id_idx;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS outgoing_webhooks;
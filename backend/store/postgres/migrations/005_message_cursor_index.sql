@@ -0,0 +1,7 @@
+-- Cursor pagination orders messages by (timestamp, id) within a session
+CREATE INDEX messages_session_id_timestamp_id_idx ON messages(session_id, timestamp, id);
+DROP INDEX IF EXISTS messages_session_id_timestamp_idx;
+
+-- Down
+CREATE INDEX messages_session_id_timestamp_idx ON messages(session_id, timestamp DESC);
+DROP INDEX IF EXISTS messages_session_id_timestamp_id_idx;
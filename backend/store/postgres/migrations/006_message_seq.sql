@@ -0,0 +1,23 @@
+-- Per-session message sequence numbers, assigned from sessions.last_message_seq
+ALTER TABLE sessions ADD COLUMN last_message_seq BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN seq BIGINT;
+
+-- Number existing messages in the order they were written
+UPDATE messages m
+SET seq = numbered.seq
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY session_id ORDER BY timestamp, id) AS seq
+    FROM messages
+) numbered
+WHERE m.id = numbered.id;
+
+UPDATE sessions s
+SET last_message_seq = COALESCE((SELECT max(seq) FROM messages WHERE session_id = s.id), 0);
+
+ALTER TABLE messages ALTER COLUMN seq SET NOT NULL;
+CREATE UNIQUE INDEX messages_session_id_seq_idx ON messages(session_id, seq);
+
+-- Down
+DROP INDEX IF EXISTS messages_session_id_seq_idx;
+ALTER TABLE messages DROP COLUMN IF EXISTS seq;
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_message_seq;
@@ -0,0 +1,28 @@
+-- Per-session moderation settings and the log of flagged messages
+CREATE TABLE moderation_settings (
+    session_id          UUID PRIMARY KEY REFERENCES sessions(id) ON DELETE CASCADE,
+    blocked_words       TEXT[] NOT NULL DEFAULT '{}',
+    word_action         TEXT NOT NULL DEFAULT 'reject',
+    patterns            JSONB NOT NULL DEFAULT '[]',
+    blocked_domains     TEXT[] NOT NULL DEFAULT '{}',
+    max_length          INTEGER NOT NULL DEFAULT 0,
+    max_repeated_chars  INTEGER NOT NULL DEFAULT 0,
+    updated_by          UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    updated_at          TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE moderation_flags (
+    id          UUID PRIMARY KEY,
+    message_id  UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    session_id  UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    filter      TEXT NOT NULL,
+    reason      TEXT NOT NULL,
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX moderation_flags_session_id_created_at_idx ON moderation_flags(session_id, created_at DESC);
+
+-- Down
+DROP INDEX IF EXISTS moderation_flags_session_id_created_at_idx;
+DROP TABLE IF EXISTS moderation_flags;
+DROP TABLE IF EXISTS moderation_settings;
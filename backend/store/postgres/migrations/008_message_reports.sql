@@ -0,0 +1,30 @@
+-- Messages reported by members and the outcome of their review.
+-- The reported message is copied, so that reports outlive deleted messages.
+CREATE TABLE message_reports (
+    id            UUID PRIMARY KEY,
+    message_id    UUID NOT NULL,
+    session_id    UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    reporter_id   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    author_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message_type  TEXT NOT NULL,
+    content       TEXT NOT NULL,
+    reason        TEXT NOT NULL,
+    status        TEXT NOT NULL DEFAULT 'open',
+    resolution    TEXT,
+    resolved_by   UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at   TIMESTAMP WITH TIME ZONE,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- A member has at most one open report per message
+CREATE UNIQUE INDEX message_reports_open_idx ON message_reports(message_id, reporter_id) WHERE status = 'open';
+CREATE INDEX message_reports_session_id_created_at_idx ON message_reports(session_id, created_at DESC);
+
+-- Muted members cannot post until the given time
+ALTER TABLE user_sessions ADD COLUMN muted_until TIMESTAMP WITH TIME ZONE;
+
+-- Down
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS muted_until;
+DROP INDEX IF EXISTS message_reports_session_id_created_at_idx;
+DROP INDEX IF EXISTS message_reports_open_idx;
+DROP TABLE IF EXISTS message_reports;
@@ -0,0 +1,16 @@
+-- Messages saved by users, optionally with a note and a folder
+CREATE TABLE bookmarks (
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message_id  UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    session_id  UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    note        TEXT NOT NULL DEFAULT '',
+    folder      TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, message_id)
+);
+
+CREATE INDEX bookmarks_user_id_created_at_idx ON bookmarks(user_id, created_at DESC);
+
+-- Down
+DROP INDEX IF EXISTS bookmarks_user_id_created_at_idx;
+DROP TABLE IF EXISTS bookmarks;
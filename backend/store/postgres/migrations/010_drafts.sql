@@ -0,0 +1,12 @@
+-- Unsent messages of a user in a session, synced between the user's devices
+CREATE TABLE drafts (
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id   UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    content      TEXT NOT NULL,
+    reply_to_id  UUID REFERENCES messages(id) ON DELETE SET NULL,
+    updated_at   TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, session_id)
+);
+
+-- Down
+DROP TABLE IF EXISTS drafts;
@@ -0,0 +1,16 @@
+-- Custom emoji uploaded to a session, referenced in messages as :shortcode:
+CREATE TABLE custom_emojis (
+    id            UUID PRIMARY KEY,
+    session_id    UUID NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    shortcode     TEXT NOT NULL,
+    url           TEXT NOT NULL,
+    object_name   TEXT NOT NULL,
+    content_type  TEXT NOT NULL,
+    size          BIGINT NOT NULL,
+    created_by    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (session_id, shortcode)
+);
+
+-- Down
+DROP TABLE IF EXISTS custom_emojis;
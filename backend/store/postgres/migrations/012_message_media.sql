@@ -0,0 +1,9 @@
+-- Stored image and variants of image messages
+ALTER TABLE messages ADD COLUMN media JSONB;
+
+-- Image messages still to be backfilled with variants
+CREATE INDEX messages_image_without_media_idx ON messages(id) WHERE type = 'image' AND media IS NULL;
+
+-- Down
+DROP INDEX IF EXISTS messages_image_without_media_idx;
+ALTER TABLE messages DROP COLUMN IF EXISTS media;
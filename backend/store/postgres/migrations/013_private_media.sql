@@ -0,0 +1,5 @@
+-- Emoji images are read through presigned URLs derived from their object name
+ALTER TABLE custom_emojis DROP COLUMN url;
+
+-- Down
+ALTER TABLE custom_emojis ADD COLUMN url TEXT NOT NULL DEFAULT '';
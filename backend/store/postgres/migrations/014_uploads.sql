@@ -0,0 +1,18 @@
+-- Slots for files uploaded directly to storage with presigned URLs
+CREATE TABLE uploads (
+    id            UUID PRIMARY KEY,
+    user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose       TEXT NOT NULL,
+    session_id    UUID REFERENCES sessions(id) ON DELETE CASCADE,
+    object_name   TEXT NOT NULL,
+    size          BIGINT NOT NULL,
+    content_type  TEXT NOT NULL,
+    status        TEXT NOT NULL DEFAULT 'pending',
+    created_at    TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at    TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX uploads_pending_idx ON uploads(expires_at) WHERE status = 'pending';
+
+-- Down
+DROP TABLE IF EXISTS uploads;
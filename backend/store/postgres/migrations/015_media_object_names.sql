@@ -0,0 +1,13 @@
+-- Avatars and image messages reference their objects by name; URLs are
+-- generated when they are served. Rewrites the absolute URLs stored before,
+-- e.g. http://localhost:9000/<bucket>/user-<id>/<file> to user-<id>/<file>.
+UPDATE users
+SET avatar_url = regexp_replace(avatar_url, '^https?://[^/]+/[^/]+/(user-[^?]+)(\?.*)?$', '\1')
+WHERE avatar_url ~ '^https?://[^/]+/[^/]+/user-';
+
+UPDATE messages
+SET content = regexp_replace(content, '^https?://[^/]+/[^/]+/(messages/[^?]+)(\?.*)?$', '\1')
+WHERE type = 'image' AND content ~ '^https?://[^/]+/[^/]+/messages/';
+
+-- Down
+-- Object names stay valid: they are served the same way as the URLs they replaced.
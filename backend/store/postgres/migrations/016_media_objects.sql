@@ -0,0 +1,14 @@
+-- Stored objects with their owner, to account storage usage per user and session
+CREATE TABLE media_objects (
+    object_name  TEXT PRIMARY KEY,
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id   UUID REFERENCES sessions(id) ON DELETE CASCADE,
+    size         BIGINT NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX media_objects_user_id_idx ON media_objects(user_id);
+CREATE INDEX media_objects_session_id_idx ON media_objects(session_id);
+
+-- Down
+DROP TABLE IF EXISTS media_objects;
@@ -0,0 +1,13 @@
+-- Progress of resumable uploads, assembled from the parts of a multipart upload
+ALTER TABLE uploads
+    ADD COLUMN filename       TEXT NOT NULL DEFAULT '',
+    ADD COLUMN multipart_id   TEXT NOT NULL DEFAULT '',
+    ADD COLUMN upload_offset  BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN parts          JSONB NOT NULL DEFAULT '[]';
+
+-- Down
+ALTER TABLE uploads
+    DROP COLUMN IF EXISTS parts,
+    DROP COLUMN IF EXISTS upload_offset,
+    DROP COLUMN IF EXISTS multipart_id,
+    DROP COLUMN IF EXISTS filename;
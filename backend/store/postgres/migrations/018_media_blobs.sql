@@ -0,0 +1,11 @@
+-- Reference counts of content addressed objects, which are stored once for
+-- all messages posting the same content
+CREATE TABLE media_blobs (
+    object_name  TEXT PRIMARY KEY,
+    size         BIGINT NOT NULL,
+    ref_count    INTEGER NOT NULL,
+    created_at   TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Down
+DROP TABLE IF EXISTS media_blobs;
@@ -0,0 +1,7 @@
+-- Bookmark pages are ordered by (created_at, message_id) within a user
+CREATE INDEX bookmarks_user_id_created_at_message_id_idx ON bookmarks(user_id, created_at DESC, message_id DESC);
+DROP INDEX IF EXISTS bookmarks_user_id_created_at_idx;
+
+-- Down
+CREATE INDEX bookmarks_user_id_created_at_idx ON bookmarks(user_id, created_at DESC);
+DROP INDEX IF EXISTS bookmarks_user_id_created_at_message_id_idx;
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) GetModerationSettings(ctx context.Context, sessionID uuid.UUID) (*models.ModerationSettings, error) {
+	settings := &models.ModerationSettings{}
+	var patterns []byte
+	err := s.loader.queryRow(ctx, GetModerationSettingsQuery,
+		func(row pgx.Row) error {
+			return row.Scan(
+				&settings.SessionID, &settings.BlockedWords, &settings.WordAction, &patterns,
+				&settings.BlockedDomains, &settings.MaxLength, &settings.MaxRepeatedChars,
+				&settings.UpdatedBy, &settings.UpdatedAt,
+			)
+		},
+		sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patterns, &settings.Patterns); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (s *Store) UpsertModerationSettings(ctx context.Context, settings *models.ModerationSettings) error {
+	if settings.UpdatedAt.IsZero() {
+		settings.UpdatedAt = time.Now().UTC()
+	}
+	if settings.BlockedWords == nil {
+		settings.BlockedWords = []string{}
+	}
+	if settings.BlockedDomains == nil {
+		settings.BlockedDomains = []string{}
+	}
+	if settings.Patterns == nil {
+		settings.Patterns = []models.ModerationPattern{}
+	}
+
+	patterns, err := json.Marshal(settings.Patterns)
+	if err != nil {
+		return err
+	}
+
+	return s.loader.exec(ctx, UpsertModerationSettingsQuery,
+		settings.SessionID, settings.BlockedWords, settings.WordAction, patterns,
+		settings.BlockedDomains, settings.MaxLength, settings.MaxRepeatedChars,
+		settings.UpdatedBy, settings.UpdatedAt)
+}
+
+func (s *Store) CreateModerationFlag(ctx context.Context, flag *models.ModerationFlag) error {
+	if flag.ID == uuid.Nil {
+		flag.ID = uuid.New()
+	}
+	if flag.CreatedAt.IsZero() {
+		flag.CreatedAt = time.Now().UTC()
+	}
+
+	return s.loader.exec(ctx, CreateModerationFlagQuery,
+		flag.ID, flag.MessageID, flag.SessionID, flag.Filter, flag.Reason, flag.CreatedAt)
+}
+
+func (s *Store) GetModerationFlagsBySessionID(ctx context.Context, sessionID uuid.UUID, limit int) ([]*models.ModerationFlag, error) {
+	var flags []*models.ModerationFlag
+	err := s.loader.queryRows(ctx, GetModerationFlagsBySessionIDQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				flag := &models.ModerationFlag{}
+				err := rows.Scan(
+					&flag.ID, &flag.MessageID, &flag.SessionID,
+					&flag.Filter, &flag.Reason, &flag.CreatedAt,
+				)
+				if err != nil {
+					return err
+				}
+				flags = append(flags, flag)
+			}
+			return nil
+		},
+		sessionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+func (s *Store) CreateMessageReport(ctx context.Context, report *models.MessageReport) error {
+	if report.ID == uuid.Nil {
+		report.ID = uuid.New()
+	}
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now().UTC()
+	}
+
+	return s.loader.queryRow(ctx, CreateMessageReportQuery,
+		func(row pgx.Row) error {
+			return row.Scan(&report.ID, &report.Status, &report.CreatedAt)
+		},
+		report.ID, report.MessageID, report.SessionID, report.ReporterID, report.AuthorID,
+		report.MessageType, report.Content, report.Reason, report.CreatedAt)
+}
+
+func (s *Store) GetMessageReportByID(ctx context.Context, id uuid.UUID) (*models.MessageReport, error) {
+	var report *models.MessageReport
+	err := s.loader.queryRow(ctx, GetMessageReportByIDQuery,
+		func(row pgx.Row) error {
+			var err error
+			report, err = scanMessageReport(row)
+			return err
+		},
+		id)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *Store) GetMessageReportsBySessionID(ctx context.Context, sessionID uuid.UUID, status models.ReportStatus, limit int) ([]*models.MessageReport, error) {
+	var reports []*models.MessageReport
+	err := s.loader.queryRows(ctx, GetMessageReportsBySessionIDQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				report, err := scanMessageReport(rows)
+				if err != nil {
+					return err
+				}
+				reports = append(reports, report)
+			}
+			return nil
+		},
+		sessionID, string(status), limit)
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (s *Store) ResolveMessageReports(ctx context.Context, messageID uuid.UUID, resolution models.ReportResolution, resolvedBy uuid.UUID, resolvedAt time.Time) error {
+	return s.loader.exec(ctx, ResolveMessageReportsQuery,
+		messageID, resolution, resolvedBy, resolvedAt)
+}
+
+// scanMessageReport scans a message_reports row; open reports have no resolution.
+func scanMessageReport(row pgx.Row) (*models.MessageReport, error) {
+	report := &models.MessageReport{}
+	var resolution *string
+	err := row.Scan(
+		&report.ID, &report.MessageID, &report.SessionID, &report.ReporterID, &report.AuthorID,
+		&report.MessageType, &report.Content, &report.Reason, &report.Status,
+		&resolution, &report.ResolvedBy, &report.ResolvedAt, &report.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if resolution != nil {
+		report.Resolution = models.ReportResolution(*resolution)
+	}
+	return report, nil
+}
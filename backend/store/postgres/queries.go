@@ -12,14 +12,16 @@ type QueryName string
 // Define all query names as constants
 const (
 	// User queries
-	CreateUserQuery          QueryName = "CreateUser"
-	GetUserByIDQuery         QueryName = "GetUserByID"
-	GetUsersByIDsQuery       QueryName = "GetUsersByIDs"
-	GetUserByUsernameQuery   QueryName = "GetUserByUsername"
-	UpdateUserQuery          QueryName = "UpdateUser"
-	DeleteUserQuery          QueryName = "DeleteUser"
-	CheckUsernameExistsQuery QueryName = "CheckUsernameExists"
-	CheckNicknameExistsQuery QueryName = "CheckNicknameExists"
+	CreateUserQuery              QueryName = "CreateUser"
+	GetUserByIDQuery             QueryName = "GetUserByID"
+	GetUsersByIDsQuery           QueryName = "GetUsersByIDs"
+	GetUserByUsernameQuery       QueryName = "GetUserByUsername"
+	UpdateUserQuery              QueryName = "UpdateUser"
+	SetDefaultAvatarQuery        QueryName = "SetDefaultAvatar"
+	GetUserIDsWithoutAvatarQuery QueryName = "GetUserIDsWithoutAvatar"
+	DeleteUserQuery              QueryName = "DeleteUser"
+	CheckUsernameExistsQuery     QueryName = "CheckUsernameExists"
+	CheckNicknameExistsQuery     QueryName = "CheckNicknameExists"
 
 	// Session queries
 	CreateSessionQuery                        QueryName = "CreateSession"
@@ -30,18 +32,89 @@ const (
 	DeleteSessionQuery                        QueryName = "DeleteSession"
 	AddUserToSessionQuery                     QueryName = "AddUserToSession"
 	RemoveUserFromSession                     QueryName = "RemoveUserFromSession"
+	UpdateUserSessionRoleQuery                QueryName = "UpdateUserSessionRole"
 	GetSessionUsersQuery                      QueryName = "GetSessionUsers"
 	GetUserSessionRoleQuery                   QueryName = "GetUserSessionRole"
 	GetSessionIDsByUserIDQuery                QueryName = "GetSessionIDsByUserID"
 	GetUserIDsBySessionIDQuery                QueryName = "GetUserIDsBySessionID"
+	SetUserSessionMutedUntilQuery             QueryName = "SetUserSessionMutedUntil"
 	GetUserSessionsBySessionIDAndUserIDsQuery QueryName = "GetUserSessionsBySessionIDAndUserIDs"
 
 	// Message queries
-	CreateMessageQuery          QueryName = "CreateMessage"
-	DeleteMessageQuery          QueryName = "DeleteMessage"
-	GetMessageIDsBySessionQuery QueryName = "GetMessageIDsBySessionID"
-	GetMessagesByIDsQuery       QueryName = "GetMessagesByIDs"
-	GetMessageByIDQuery         QueryName = "GetMessageByID"
+	CreateMessageQuery                QueryName = "CreateMessage"
+	CreateMessagesQuery               QueryName = "CreateMessages"
+	LockSessionSequencesQuery         QueryName = "LockSessionSequences"
+	GetExistingMessageIDsQuery        QueryName = "GetExistingMessageIDs"
+	SetSessionSequencesQuery          QueryName = "SetSessionSequences"
+	DeleteMessageQuery                QueryName = "DeleteMessage"
+	GetMessagesBeforeCursorQuery      QueryName = "GetMessagesBeforeCursor"
+	GetMessagesAfterCursorQuery       QueryName = "GetMessagesAfterCursor"
+	GetMessagesByIDsQuery             QueryName = "GetMessagesByIDs"
+	GetMessageByIDQuery               QueryName = "GetMessageByID"
+	GetMessagesAfterSeqQuery          QueryName = "GetMessagesAfterSeq"
+	UpdateMessageMediaQuery           QueryName = "UpdateMessageMedia"
+	GetImageMessagesWithoutMediaQuery QueryName = "GetImageMessagesWithoutMedia"
+
+	// Webhook queries
+	CreateIncomingWebhookQuery          QueryName = "CreateIncomingWebhook"
+	GetIncomingWebhookByIDQuery         QueryName = "GetIncomingWebhookByID"
+	GetIncomingWebhooksBySessionIDQuery QueryName = "GetIncomingWebhooksBySessionID"
+	RotateIncomingWebhookTokenQuery     QueryName = "RotateIncomingWebhookToken"
+	RevokeIncomingWebhookQuery          QueryName = "RevokeIncomingWebhook"
+	CreateOutgoingWebhookQuery          QueryName = "CreateOutgoingWebhook"
+	GetOutgoingWebhookByIDQuery         QueryName = "GetOutgoingWebhookByID"
+	GetOutgoingWebhooksBySessionIDQuery QueryName = "GetOutgoingWebhooksBySessionID"
+	DeleteOutgoingWebhookQuery          QueryName = "DeleteOutgoingWebhook"
+	CreateWebhookDeliveryQuery          QueryName = "CreateWebhookDelivery"
+	ClaimDueWebhookDeliveriesQuery      QueryName = "ClaimDueWebhookDeliveries"
+	UpdateWebhookDeliveryQuery          QueryName = "UpdateWebhookDelivery"
+	GetWebhookDeliveriesQuery           QueryName = "GetWebhookDeliveriesByWebhookID"
+
+	// Moderation queries
+	GetModerationSettingsQuery         QueryName = "GetModerationSettings"
+	UpsertModerationSettingsQuery      QueryName = "UpsertModerationSettings"
+	CreateModerationFlagQuery          QueryName = "CreateModerationFlag"
+	GetModerationFlagsBySessionIDQuery QueryName = "GetModerationFlagsBySessionID"
+	CreateMessageReportQuery           QueryName = "CreateMessageReport"
+	GetMessageReportByIDQuery          QueryName = "GetMessageReportByID"
+	GetMessageReportsBySessionIDQuery  QueryName = "GetMessageReportsBySessionID"
+	ResolveMessageReportsQuery         QueryName = "ResolveMessageReports"
+
+	// Bookmark queries
+	SaveBookmarkQuery               QueryName = "SaveBookmark"
+	DeleteBookmarkQuery             QueryName = "DeleteBookmark"
+	GetBookmarksByUserIDQuery       QueryName = "GetBookmarksByUserID"
+	GetBookmarkFoldersByUserIDQuery QueryName = "GetBookmarkFoldersByUserID"
+
+	// Draft queries
+	GetDraftQuery    QueryName = "GetDraft"
+	SaveDraftQuery   QueryName = "SaveDraft"
+	DeleteDraftQuery QueryName = "DeleteDraft"
+
+	// Custom emoji queries
+	CreateCustomEmojiQuery          QueryName = "CreateCustomEmoji"
+	GetCustomEmojiByIDQuery         QueryName = "GetCustomEmojiByID"
+	GetCustomEmojisBySessionIDQuery QueryName = "GetCustomEmojisBySessionID"
+	DeleteCustomEmojiQuery          QueryName = "DeleteCustomEmoji"
+
+	// Upload queries
+	CreateUploadQuery         QueryName = "CreateUpload"
+	GetUploadByIDQuery        QueryName = "GetUploadByID"
+	AdvanceUploadQuery        QueryName = "AdvanceUpload"
+	CompleteUploadQuery       QueryName = "CompleteUpload"
+	DeleteExpiredUploadsQuery QueryName = "DeleteExpiredUploads"
+
+	// Media queries
+	GetReferencedObjectNamesQuery    QueryName = "GetReferencedObjectNames"
+	CreateMediaObjectQuery           QueryName = "CreateMediaObject"
+	DeleteMediaObjectsQuery          QueryName = "DeleteMediaObjects"
+	GetUserStorageUsageQuery         QueryName = "GetUserStorageUsage"
+	GetSessionStorageUsageQuery      QueryName = "GetSessionStorageUsage"
+	LockMediaBlobQuery               QueryName = "LockMediaBlob"
+	GetMediaBlobRefCountQuery        QueryName = "GetMediaBlobRefCount"
+	AcquireMediaBlobQuery            QueryName = "AcquireMediaBlob"
+	ReleaseMediaBlobQuery            QueryName = "ReleaseMediaBlob"
+	DeleteUnreferencedMediaBlobQuery QueryName = "DeleteUnreferencedMediaBlob"
 )
 
 // queryStore holds all loaded SQL queries
@@ -60,6 +133,13 @@ func newQueryStore(fs embed.FS) (*queryStore, error) {
 		"queries/users.sql",
 		"queries/sessions.sql",
 		"queries/messages.sql",
+		"queries/webhooks.sql",
+		"queries/moderation.sql",
+		"queries/bookmarks.sql",
+		"queries/drafts.sql",
+		"queries/emojis.sql",
+		"queries/uploads.sql",
+		"queries/media.sql",
 	}
 
 	for _, file := range files {
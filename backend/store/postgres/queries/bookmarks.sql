@@ -0,0 +1,33 @@
+-- name: SaveBookmark :one
+INSERT INTO bookmarks (user_id, message_id, session_id, note, folder, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, message_id) DO UPDATE
+SET note = EXCLUDED.note,
+    folder = EXCLUDED.folder
+RETURNING created_at;
+
+-- name: DeleteBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id = $1 AND message_id = $2;
+
+-- name: GetBookmarksByUserID :many
+SELECT b.user_id, b.message_id, b.session_id, b.note, b.folder, b.created_at,
+       m.id, m.type, m.content, m.user_id, m.session_id, m.timestamp, m.seq, m.media,
+       s.name
+FROM bookmarks b
+JOIN user_sessions us ON us.user_id = b.user_id AND us.session_id = b.session_id
+JOIN messages m ON m.id = b.message_id
+JOIN sessions s ON s.id = b.session_id
+WHERE b.user_id = $1
+  AND ($2 = '' OR b.folder = $2)
+  AND ($3::timestamptz IS NULL OR (b.created_at, b.message_id) < ($3, $4::uuid))
+ORDER BY b.created_at DESC, b.message_id DESC
+LIMIT $5;
+
+-- name: GetBookmarkFoldersByUserID :many
+SELECT b.folder, count(*)
+FROM bookmarks b
+JOIN user_sessions us ON us.user_id = b.user_id AND us.session_id = b.session_id
+WHERE b.user_id = $1 AND b.folder <> ''
+GROUP BY b.folder
+ORDER BY b.folder;
@@ -0,0 +1,33 @@
+-- name: GetDraft :one
+SELECT user_id, session_id, content, reply_to_id, updated_at
+FROM drafts
+WHERE user_id = $1 AND session_id = $2
+  AND (content <> '' OR reply_to_id IS NOT NULL);
+
+-- name: SaveDraft :one
+WITH upserted AS (
+    INSERT INTO drafts (user_id, session_id, content, reply_to_id, updated_at)
+    VALUES ($1, $2, $3, $4, $5)
+    ON CONFLICT (user_id, session_id) DO UPDATE
+    SET content = EXCLUDED.content,
+        reply_to_id = EXCLUDED.reply_to_id,
+        updated_at = EXCLUDED.updated_at
+    WHERE drafts.updated_at < EXCLUDED.updated_at
+    RETURNING user_id, session_id, content, reply_to_id, updated_at, true AS applied
+)
+SELECT user_id, session_id, content, reply_to_id, updated_at, applied
+FROM upserted
+UNION ALL
+SELECT user_id, session_id, content, reply_to_id, updated_at, false
+FROM drafts
+WHERE user_id = $1 AND session_id = $2
+  AND NOT EXISTS (SELECT 1 FROM upserted);
+
+-- name: DeleteDraft :exec
+INSERT INTO drafts (user_id, session_id, content, reply_to_id, updated_at)
+VALUES ($1, $2, '', NULL, $3)
+ON CONFLICT (user_id, session_id) DO UPDATE
+SET content = '',
+    reply_to_id = NULL,
+    updated_at = EXCLUDED.updated_at
+WHERE drafts.updated_at <= EXCLUDED.updated_at;
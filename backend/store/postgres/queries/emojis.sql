@@ -0,0 +1,18 @@
+-- name: CreateCustomEmoji :exec
+INSERT INTO custom_emojis (id, session_id, shortcode, object_name, content_type, size, created_by, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: GetCustomEmojiByID :one
+SELECT id, session_id, shortcode, object_name, content_type, size, created_by, created_at
+FROM custom_emojis
+WHERE id = $1;
+
+-- name: GetCustomEmojisBySessionID :many
+SELECT id, session_id, shortcode, object_name, content_type, size, created_by, created_at
+FROM custom_emojis
+WHERE session_id = $1
+ORDER BY shortcode;
+
+-- name: DeleteCustomEmoji :exec
+DELETE FROM custom_emojis
+WHERE id = $1;
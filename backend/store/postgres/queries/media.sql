@@ -0,0 +1,58 @@
+-- name: GetReferencedObjectNames :many
+SELECT avatar_url FROM users WHERE avatar_url <> ''
+UNION
+SELECT content FROM messages WHERE type IN ('image', 'file')
+UNION
+SELECT media->>'key' FROM messages WHERE media IS NOT NULL
+UNION
+SELECT variant->>'key' FROM messages, jsonb_array_elements(messages.media->'variants') AS variant WHERE messages.media IS NOT NULL
+UNION
+SELECT object_name FROM custom_emojis
+UNION
+SELECT object_name FROM uploads WHERE status = 'pending' AND expires_at > NOW();
+
+-- name: CreateMediaObject :exec
+INSERT INTO media_objects (object_name, user_id, session_id, size, created_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (object_name) DO UPDATE
+SET user_id = EXCLUDED.user_id,
+    session_id = EXCLUDED.session_id,
+    size = EXCLUDED.size,
+    created_at = EXCLUDED.created_at;
+
+-- name: DeleteMediaObjects :exec
+DELETE FROM media_objects
+WHERE object_name = ANY($1);
+
+-- name: GetUserStorageUsage :one
+SELECT (SELECT COALESCE(SUM(size), 0) FROM media_objects WHERE user_id = $1)
+     + (SELECT COALESCE(SUM(size), 0) FROM uploads WHERE user_id = $1 AND status = 'pending' AND expires_at > NOW());
+
+-- name: GetSessionStorageUsage :one
+SELECT (SELECT COALESCE(SUM(size), 0) FROM media_objects WHERE session_id = $1)
+     + (SELECT COALESCE(SUM(size), 0) FROM uploads WHERE session_id = $1 AND status = 'pending' AND expires_at > NOW());
+
+-- name: LockMediaBlob :exec
+SELECT pg_advisory_xact_lock(hashtextextended($1, 0));
+
+-- name: GetMediaBlobRefCount :one
+SELECT ref_count
+FROM media_blobs
+WHERE object_name = $1;
+
+-- name: AcquireMediaBlob :one
+INSERT INTO media_blobs (object_name, size, ref_count, created_at)
+VALUES ($1, $2, 1, $3)
+ON CONFLICT (object_name) DO UPDATE
+SET ref_count = media_blobs.ref_count + 1
+RETURNING ref_count;
+
+-- name: ReleaseMediaBlob :one
+UPDATE media_blobs
+SET ref_count = ref_count - 1
+WHERE object_name = $1
+RETURNING ref_count;
+
+-- name: DeleteUnreferencedMediaBlob :exec
+DELETE FROM media_blobs
+WHERE object_name = $1 AND ref_count <= 0;
@@ -1,17 +1,56 @@
--- name: CreateMessage :exec
-INSERT INTO messages (id, type, content, user_id, session_id, timestamp)
-VALUES ($1, $2, $3, $4, $5, $6);
+-- name: CreateMessage :one
+WITH next AS (
+    UPDATE sessions
+    SET last_message_seq = last_message_seq + 1
+    WHERE id = $5
+    RETURNING last_message_seq
+)
+INSERT INTO messages (id, type, content, user_id, session_id, timestamp, seq, media)
+SELECT $1, $2, $3, $4, $5, $6, last_message_seq, $7::jsonb
+FROM next
+RETURNING seq;
 
--- name: GetMessageIDsBySessionID :many
+-- name: LockSessionSequences :many
+SELECT id, last_message_seq
+FROM sessions
+WHERE id = ANY($1::uuid[])
+ORDER BY id
+FOR UPDATE;
+
+-- name: GetExistingMessageIDs :many
 SELECT id
 FROM messages
+WHERE id = ANY($1::uuid[]);
+
+-- name: CreateMessages :exec
+INSERT INTO messages (id, type, content, user_id, session_id, timestamp, seq, media)
+SELECT *
+FROM unnest($1::uuid[], $2::text[], $3::text[], $4::uuid[], $5::uuid[], $6::timestamptz[], $7::bigint[], $8::jsonb[]);
+
+-- name: SetSessionSequences :exec
+UPDATE sessions s
+SET last_message_seq = t.last_message_seq
+FROM unnest($1::uuid[], $2::bigint[]) AS t(id, last_message_seq)
+WHERE s.id = t.id;
+
+-- name: GetMessagesBeforeCursor :many
+SELECT id, type, content, user_id, session_id, timestamp, seq, media
+FROM messages
 WHERE session_id = $1
-  AND timestamp < $2
-ORDER BY timestamp DESC
-LIMIT $3;
+  AND (timestamp, id) < ($2, $3)
+ORDER BY timestamp DESC, id DESC
+LIMIT $4;
+
+-- name: GetMessagesAfterCursor :many
+SELECT id, type, content, user_id, session_id, timestamp, seq, media
+FROM messages
+WHERE session_id = $1
+  AND (timestamp, id) > ($2, $3)
+ORDER BY timestamp ASC, id ASC
+LIMIT $4;
 
 -- name: GetMessageByID :one
-SELECT id, type, content, user_id, session_id, timestamp
+SELECT id, type, content, user_id, session_id, timestamp, seq, media
 FROM messages
 WHERE id = $1;
 
@@ -20,6 +59,28 @@ DELETE FROM messages
 WHERE id = $1;
 
 -- name: GetMessagesByIDs :many
-SELECT id, type, content, user_id, session_id, timestamp
+SELECT id, type, content, user_id, session_id, timestamp, seq, media
+FROM messages
+WHERE id = ANY($1);
+
+-- name: GetMessagesAfterSeq :many
+SELECT id, type, content, user_id, session_id, timestamp, seq, media
+FROM messages
+WHERE session_id = $1
+  AND seq > $2
+ORDER BY seq ASC
+LIMIT $3;
+
+-- name: UpdateMessageMedia :exec
+UPDATE messages
+SET media = $2::jsonb
+WHERE id = $1;
+
+-- name: GetImageMessagesWithoutMedia :many
+SELECT id, type, content, user_id, session_id, timestamp, seq, media
 FROM messages
-WHERE id = ANY($1); 
\ No newline at end of file
+WHERE type = 'image'
+  AND media IS NULL
+  AND id > $1
+ORDER BY id
+LIMIT $2;
@@ -0,0 +1,61 @@
+-- name: GetModerationSettings :one
+SELECT session_id, blocked_words, word_action, patterns, blocked_domains,
+       max_length, max_repeated_chars, updated_by, updated_at
+FROM moderation_settings
+WHERE session_id = $1;
+
+-- name: UpsertModerationSettings :exec
+INSERT INTO moderation_settings (session_id, blocked_words, word_action, patterns, blocked_domains,
+                                 max_length, max_repeated_chars, updated_by, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (session_id) DO UPDATE
+SET blocked_words = EXCLUDED.blocked_words,
+    word_action = EXCLUDED.word_action,
+    patterns = EXCLUDED.patterns,
+    blocked_domains = EXCLUDED.blocked_domains,
+    max_length = EXCLUDED.max_length,
+    max_repeated_chars = EXCLUDED.max_repeated_chars,
+    updated_by = EXCLUDED.updated_by,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: CreateModerationFlag :exec
+INSERT INTO moderation_flags (id, message_id, session_id, filter, reason, created_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetModerationFlagsBySessionID :many
+SELECT id, message_id, session_id, filter, reason, created_at
+FROM moderation_flags
+WHERE session_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: CreateMessageReport :one
+INSERT INTO message_reports (id, message_id, session_id, reporter_id, author_id,
+                             message_type, content, reason, status, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'open', $9)
+ON CONFLICT (message_id, reporter_id) WHERE status = 'open' DO UPDATE
+SET reason = EXCLUDED.reason
+RETURNING id, status, created_at;
+
+-- name: GetMessageReportByID :one
+SELECT id, message_id, session_id, reporter_id, author_id, message_type, content,
+       reason, status, resolution, resolved_by, resolved_at, created_at
+FROM message_reports
+WHERE id = $1;
+
+-- name: GetMessageReportsBySessionID :many
+SELECT id, message_id, session_id, reporter_id, author_id, message_type, content,
+       reason, status, resolution, resolved_by, resolved_at, created_at
+FROM message_reports
+WHERE session_id = $1
+  AND ($2 = '' OR status = $2)
+ORDER BY created_at DESC
+LIMIT $3;
+
+-- name: ResolveMessageReports :exec
+UPDATE message_reports
+SET status = 'resolved',
+    resolution = $2,
+    resolved_by = $3,
+    resolved_at = $4
+WHERE message_id = $1 AND status = 'open';
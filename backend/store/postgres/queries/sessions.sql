@@ -31,8 +31,13 @@ VALUES ($1, $2, $3, $4);
 DELETE FROM user_sessions
 WHERE user_id = $1 AND session_id = $2;
 
+-- name: UpdateUserSessionRole :exec
+UPDATE user_sessions
+SET role = $3
+WHERE user_id = $1 AND session_id = $2;
+
 -- name: GetSessionUsers :many
-SELECT u.id, u.username, u.nickname, u.avatar_url, u.created_at
+SELECT u.id, u.username, u.nickname, u.avatar_url, u.is_bot, u.created_at
 FROM users u
 JOIN user_sessions us ON u.id = us.user_id
 WHERE us.session_id = $1;
@@ -57,7 +62,12 @@ SELECT id, name, creator_id, created_at
 FROM sessions
 WHERE id = ANY($1);
 
+-- name: SetUserSessionMutedUntil :exec
+UPDATE user_sessions
+SET muted_until = $3
+WHERE user_id = $1 AND session_id = $2;
+
 -- name: GetUserSessionsBySessionIDAndUserIDs :many
-SELECT user_id, session_id, role, joined_at
+SELECT user_id, session_id, role, joined_at, muted_until
 FROM user_sessions
 WHERE session_id = $1 AND user_id = ANY($2); 
\ No newline at end of file
@@ -0,0 +1,26 @@
+-- name: CreateUpload :exec
+INSERT INTO uploads (id, user_id, purpose, session_id, object_name, size, content_type, filename, multipart_id, status, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+
+-- name: GetUploadByID :one
+SELECT id, user_id, purpose, session_id, object_name, size, content_type, filename, multipart_id, upload_offset, parts, status, created_at, expires_at
+FROM uploads
+WHERE id = $1;
+
+-- name: AdvanceUpload :one
+UPDATE uploads
+SET upload_offset = $3,
+    parts = parts || $4::jsonb
+WHERE id = $1 AND upload_offset = $2 AND status = 'pending'
+RETURNING id;
+
+-- name: CompleteUpload :one
+UPDATE uploads
+SET status = 'completed'
+WHERE id = $1 AND status = 'pending'
+RETURNING id;
+
+-- name: DeleteExpiredUploads :many
+DELETE FROM uploads
+WHERE expires_at < $1
+RETURNING id, user_id, purpose, session_id, object_name, size, content_type, filename, multipart_id, upload_offset, parts, status, created_at, expires_at;
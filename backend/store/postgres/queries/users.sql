@@ -1,14 +1,14 @@
 -- name: CreateUser :exec
-INSERT INTO users (id, username, password, nickname, avatar_url, created_at)
-VALUES ($1, $2, $3, $4, $5, $6);
+INSERT INTO users (id, username, password, nickname, avatar_url, is_bot, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
 
 -- name: GetUserByID :one
-SELECT id, username, password, nickname, avatar_url, created_at
+SELECT id, username, password, nickname, avatar_url, is_bot, created_at
 FROM users
 WHERE id = $1;
 
 -- name: GetUserByUsername :one
-SELECT id, username, password, nickname, avatar_url, created_at
+SELECT id, username, password, nickname, avatar_url, is_bot, created_at
 FROM users
 WHERE username = $1;
 
@@ -19,6 +19,19 @@ SET username = $2,
     avatar_url = $4
 WHERE id = $1;
 
+-- name: SetDefaultAvatar :exec
+UPDATE users
+SET avatar_url = $2
+WHERE id = $1 AND avatar_url = '';
+
+-- name: GetUserIDsWithoutAvatar :many
+SELECT id
+FROM users
+WHERE avatar_url = ''
+  AND id > $1
+ORDER BY id
+LIMIT $2;
+
 -- name: DeleteUser :exec
 DELETE FROM users
 WHERE id = $1;
@@ -38,6 +51,6 @@ SELECT EXISTS(
 ) AS exists;
 
 -- name: GetUsersByIDs :many
-SELECT id, username, password, nickname, avatar_url, created_at
+SELECT id, username, password, nickname, avatar_url, is_bot, created_at
 FROM users
 WHERE id = ANY($1); 
\ No newline at end of file
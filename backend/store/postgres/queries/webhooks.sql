@@ -0,0 +1,82 @@
+-- name: CreateIncomingWebhook :exec
+INSERT INTO incoming_webhooks (id, session_id, bot_user_id, name, token_hash, created_by, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetIncomingWebhookByID :one
+SELECT id, session_id, bot_user_id, name, token_hash, created_by, created_at, rotated_at, revoked_at
+FROM incoming_webhooks
+WHERE id = $1;
+
+-- name: GetIncomingWebhooksBySessionID :many
+SELECT id, session_id, bot_user_id, name, token_hash, created_by, created_at, rotated_at, revoked_at
+FROM incoming_webhooks
+WHERE session_id = $1
+ORDER BY created_at ASC;
+
+-- name: RotateIncomingWebhookToken :exec
+UPDATE incoming_webhooks
+SET token_hash = $2,
+    rotated_at = $3
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeIncomingWebhook :exec
+UPDATE incoming_webhooks
+SET revoked_at = $2
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: CreateOutgoingWebhook :exec
+INSERT INTO outgoing_webhooks (id, session_id, url, secret, events, created_by, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetOutgoingWebhookByID :one
+SELECT id, session_id, url, secret, events, created_by, created_at
+FROM outgoing_webhooks
+WHERE id = $1;
+
+-- name: GetOutgoingWebhooksBySessionID :many
+SELECT id, session_id, url, secret, events, created_by, created_at
+FROM outgoing_webhooks
+WHERE session_id = $1
+ORDER BY created_at ASC;
+
+-- name: DeleteOutgoingWebhook :exec
+DELETE FROM outgoing_webhooks
+WHERE id = $1;
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (id, webhook_id, event, payload, status, attempts, next_attempt_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = $2
+WHERE id IN (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending'
+      AND next_attempt_at <= $1
+    ORDER BY next_attempt_at ASC
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, webhook_id, event, payload, status, attempts, next_attempt_at,
+          last_error, response_status, created_at, completed_at;
+
+-- name: UpdateWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = $2,
+    attempts = $3,
+    next_attempt_at = $4,
+    last_error = $5,
+    response_status = $6,
+    completed_at = $7
+WHERE id = $1;
+
+-- name: GetWebhookDeliveriesByWebhookID :many
+SELECT id, webhook_id, event, payload, status, attempts, next_attempt_at,
+       last_error, response_status, created_at, completed_at
+FROM webhook_deliveries
+WHERE webhook_id = $1
+  AND ($2 = '' OR status = $2)
+ORDER BY created_at DESC
+LIMIT $3;
@@ -2,8 +2,11 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"chat-room/store"
+
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -47,6 +50,9 @@ func (l *queryLoader) queryRow(ctx context.Context, name QueryName, scanner func
 
 	row := l.db.QueryRow(ctx, query, args...)
 	if err := scanner(row); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return store.ErrNotFound
+		}
 		return fmt.Errorf("scanning %s result: %w", name, err)
 	}
 
@@ -32,6 +32,7 @@ var (
 	_ store.SessionStore     = (*Store)(nil)
 	_ store.UserSessionStore = (*Store)(nil)
 	_ store.MessageStore     = (*Store)(nil)
+	_ store.WebhookStore     = (*Store)(nil)
 )
 
 type Tx struct {
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) CreateUpload(ctx context.Context, upload *models.Upload) error {
+	if upload.ID == uuid.Nil {
+		upload.ID = uuid.New()
+	}
+	if upload.CreatedAt.IsZero() {
+		upload.CreatedAt = time.Now().UTC()
+	}
+	if upload.Status == "" {
+		upload.Status = models.UploadStatusPending
+	}
+
+	return s.loader.exec(ctx, CreateUploadQuery,
+		upload.ID, upload.UserID, upload.Purpose, upload.SessionID, upload.ObjectName,
+		upload.Size, upload.ContentType, upload.Filename, upload.MultipartID, upload.Status,
+		upload.CreatedAt, upload.ExpiresAt)
+}
+
+func (s *Store) GetUploadByID(ctx context.Context, id uuid.UUID) (*models.Upload, error) {
+	upload := &models.Upload{}
+	err := s.loader.queryRow(ctx, GetUploadByIDQuery,
+		func(row pgx.Row) error {
+			return scanUpload(row, upload)
+		},
+		id)
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+func (s *Store) AdvanceUpload(ctx context.Context, id uuid.UUID, offset, newOffset int64, part models.UploadPart) error {
+	parts, err := json.Marshal([]models.UploadPart{part})
+	if err != nil {
+		return err
+	}
+	return s.loader.queryRow(ctx, AdvanceUploadQuery,
+		func(row pgx.Row) error {
+			return row.Scan(&id)
+		},
+		id, offset, newOffset, parts)
+}
+
+func (s *Store) CompleteUpload(ctx context.Context, id uuid.UUID) error {
+	return s.loader.queryRow(ctx, CompleteUploadQuery,
+		func(row pgx.Row) error {
+			return row.Scan(&id)
+		},
+		id)
+}
+
+func (s *Store) DeleteExpiredUploads(ctx context.Context, before time.Time) ([]*models.Upload, error) {
+	var uploads []*models.Upload
+	err := s.loader.queryRows(ctx, DeleteExpiredUploadsQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				upload := &models.Upload{}
+				if err := scanUpload(rows, upload); err != nil {
+					return err
+				}
+				uploads = append(uploads, upload)
+			}
+			return nil
+		},
+		before)
+	if err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+func scanUpload(row pgx.Row, upload *models.Upload) error {
+	var parts []byte
+	err := row.Scan(
+		&upload.ID, &upload.UserID, &upload.Purpose, &upload.SessionID, &upload.ObjectName,
+		&upload.Size, &upload.ContentType, &upload.Filename, &upload.MultipartID, &upload.Offset,
+		&parts, &upload.Status, &upload.CreatedAt, &upload.ExpiresAt,
+	)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(parts, &upload.Parts)
+}
@@ -20,6 +20,16 @@ func (s *Store) RemoveUserFromSession(ctx context.Context, userID, sessionID uui
 		userID, sessionID)
 }
 
+func (s *Store) UpdateUserSessionRole(ctx context.Context, userID, sessionID uuid.UUID, role string) error {
+	return s.loader.exec(ctx, UpdateUserSessionRoleQuery,
+		userID, sessionID, role)
+}
+
+func (s *Store) SetUserSessionMutedUntil(ctx context.Context, userID, sessionID uuid.UUID, mutedUntil *time.Time) error {
+	return s.loader.exec(ctx, SetUserSessionMutedUntilQuery,
+		userID, sessionID, mutedUntil)
+}
+
 func (s *Store) GetUserSessions(ctx context.Context, userID uuid.UUID) ([]*models.UserSession, error) {
 	var userSessions []*models.UserSession
 	err := s.loader.queryRows(ctx, GetUserSessionsQuery,
@@ -56,7 +66,7 @@ func (s *Store) GetSessionUsers(ctx context.Context, sessionID uuid.UUID) ([]*mo
 				user := &models.User{}
 				err := rows.Scan(
 					&user.ID, &user.Username, &user.Nickname,
-					&user.AvatarURL, &user.CreatedAt,
+					&user.AvatarURL, &user.IsBot, &user.CreatedAt,
 				)
 				if err != nil {
 					return err
@@ -136,6 +146,7 @@ func (s *Store) GetUserSessionsBySessionIDAndUserIDs(ctx context.Context, sessio
 					&userSession.SessionID,
 					&userSession.Role,
 					&userSession.JoinedAt,
+					&userSession.MutedUntil,
 				)
 				if err != nil {
 					return err
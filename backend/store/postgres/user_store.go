@@ -20,7 +20,7 @@ func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
 
 	return s.loader.exec(ctx, CreateUserQuery,
 		user.ID, user.Username, user.Password, user.Nickname,
-		user.AvatarURL, user.CreatedAt)
+		user.AvatarURL, user.IsBot, user.CreatedAt)
 }
 
 func (s *Store) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
@@ -28,7 +28,7 @@ func (s *Store) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, er
 	err := s.loader.queryRow(ctx, GetUserByIDQuery,
 		func(row pgx.Row) error {
 			return row.Scan(&user.ID, &user.Username, &user.Password,
-				&user.Nickname, &user.AvatarURL, &user.CreatedAt)
+				&user.Nickname, &user.AvatarURL, &user.IsBot, &user.CreatedAt)
 		},
 		id)
 	if err != nil {
@@ -42,7 +42,7 @@ func (s *Store) GetUserByUsername(ctx context.Context, username string) (*models
 	err := s.loader.queryRow(ctx, GetUserByUsernameQuery,
 		func(row pgx.Row) error {
 			return row.Scan(&user.ID, &user.Username, &user.Password,
-				&user.Nickname, &user.AvatarURL, &user.CreatedAt)
+				&user.Nickname, &user.AvatarURL, &user.IsBot, &user.CreatedAt)
 		},
 		username)
 	if err != nil {
@@ -56,6 +56,30 @@ func (s *Store) UpdateUser(ctx context.Context, user *models.User) error {
 		user.ID, user.Username, user.Nickname, user.AvatarURL)
 }
 
+func (s *Store) SetDefaultAvatar(ctx context.Context, userID uuid.UUID, avatarURL string) error {
+	return s.loader.exec(ctx, SetDefaultAvatarQuery, userID, avatarURL)
+}
+
+func (s *Store) GetUserIDsWithoutAvatar(ctx context.Context, afterID uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := s.loader.queryRows(ctx, GetUserIDsWithoutAvatarQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				var id uuid.UUID
+				if err := rows.Scan(&id); err != nil {
+					return err
+				}
+				ids = append(ids, id)
+			}
+			return nil
+		},
+		afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
 func (s *Store) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	return s.loader.exec(ctx, DeleteUserQuery, id)
 }
@@ -94,7 +118,7 @@ func (s *Store) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.U
 				user := &models.User{}
 				err := rows.Scan(
 					&user.ID, &user.Username, &user.Password,
-					&user.Nickname, &user.AvatarURL, &user.CreatedAt,
+					&user.Nickname, &user.AvatarURL, &user.IsBot, &user.CreatedAt,
 				)
 				if err != nil {
 					return err
@@ -0,0 +1,218 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Store) CreateIncomingWebhook(ctx context.Context, webhook *models.IncomingWebhook, bot *models.User) error {
+	if webhook.ID == uuid.Nil {
+		webhook.ID = uuid.New()
+	}
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = time.Now().UTC()
+	}
+	if bot.ID == uuid.Nil {
+		bot.ID = uuid.New()
+	}
+	if bot.CreatedAt.IsZero() {
+		bot.CreatedAt = webhook.CreatedAt
+	}
+	webhook.BotUserID = bot.ID
+
+	tx, err := s.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Create the bot user
+	err = tx.(*Tx).loader.exec(ctx, CreateUserQuery,
+		bot.ID, bot.Username, bot.Password, bot.Nickname,
+		bot.AvatarURL, bot.IsBot, bot.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	// Add the bot to the session with "bot" role
+	err = tx.(*Tx).loader.exec(ctx, AddUserToSessionQuery,
+		bot.ID, webhook.SessionID, "bot", webhook.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = tx.(*Tx).loader.exec(ctx, CreateIncomingWebhookQuery,
+		webhook.ID, webhook.SessionID, webhook.BotUserID, webhook.Name,
+		webhook.TokenHash, webhook.CreatedBy, webhook.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) GetIncomingWebhookByID(ctx context.Context, id uuid.UUID) (*models.IncomingWebhook, error) {
+	webhook := &models.IncomingWebhook{}
+	err := s.loader.queryRow(ctx, GetIncomingWebhookByIDQuery,
+		func(row pgx.Row) error {
+			return row.Scan(
+				&webhook.ID, &webhook.SessionID, &webhook.BotUserID, &webhook.Name,
+				&webhook.TokenHash, &webhook.CreatedBy, &webhook.CreatedAt,
+				&webhook.RotatedAt, &webhook.RevokedAt,
+			)
+		},
+		id)
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (s *Store) GetIncomingWebhooksBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*models.IncomingWebhook, error) {
+	var webhooks []*models.IncomingWebhook
+	err := s.loader.queryRows(ctx, GetIncomingWebhooksBySessionIDQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				webhook := &models.IncomingWebhook{}
+				err := rows.Scan(
+					&webhook.ID, &webhook.SessionID, &webhook.BotUserID, &webhook.Name,
+					&webhook.TokenHash, &webhook.CreatedBy, &webhook.CreatedAt,
+					&webhook.RotatedAt, &webhook.RevokedAt,
+				)
+				if err != nil {
+					return err
+				}
+				webhooks = append(webhooks, webhook)
+			}
+			return nil
+		},
+		sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (s *Store) RotateIncomingWebhookToken(ctx context.Context, id uuid.UUID, tokenHash string) error {
+	return s.loader.exec(ctx, RotateIncomingWebhookTokenQuery,
+		id, tokenHash, time.Now().UTC())
+}
+
+func (s *Store) RevokeIncomingWebhook(ctx context.Context, id uuid.UUID) error {
+	return s.loader.exec(ctx, RevokeIncomingWebhookQuery, id, time.Now().UTC())
+}
+
+func (s *Store) CreateOutgoingWebhook(ctx context.Context, webhook *models.OutgoingWebhook) error {
+	if webhook.ID == uuid.Nil {
+		webhook.ID = uuid.New()
+	}
+	if webhook.CreatedAt.IsZero() {
+		webhook.CreatedAt = time.Now().UTC()
+	}
+
+	return s.loader.exec(ctx, CreateOutgoingWebhookQuery,
+		webhook.ID, webhook.SessionID, webhook.URL, webhook.Secret,
+		webhook.Events, webhook.CreatedBy, webhook.CreatedAt)
+}
+
+func (s *Store) GetOutgoingWebhookByID(ctx context.Context, id uuid.UUID) (*models.OutgoingWebhook, error) {
+	webhook := &models.OutgoingWebhook{}
+	err := s.loader.queryRow(ctx, GetOutgoingWebhookByIDQuery,
+		func(row pgx.Row) error {
+			return row.Scan(
+				&webhook.ID, &webhook.SessionID, &webhook.URL, &webhook.Secret,
+				&webhook.Events, &webhook.CreatedBy, &webhook.CreatedAt,
+			)
+		},
+		id)
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (s *Store) GetOutgoingWebhooksBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*models.OutgoingWebhook, error) {
+	var webhooks []*models.OutgoingWebhook
+	err := s.loader.queryRows(ctx, GetOutgoingWebhooksBySessionIDQuery,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				webhook := &models.OutgoingWebhook{}
+				err := rows.Scan(
+					&webhook.ID, &webhook.SessionID, &webhook.URL, &webhook.Secret,
+					&webhook.Events, &webhook.CreatedBy, &webhook.CreatedAt,
+				)
+				if err != nil {
+					return err
+				}
+				webhooks = append(webhooks, webhook)
+			}
+			return nil
+		},
+		sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (s *Store) DeleteOutgoingWebhook(ctx context.Context, id uuid.UUID) error {
+	return s.loader.exec(ctx, DeleteOutgoingWebhookQuery, id)
+}
+
+func (s *Store) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if delivery.ID == uuid.Nil {
+		delivery.ID = uuid.New()
+	}
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now().UTC()
+	}
+
+	return s.loader.exec(ctx, CreateWebhookDeliveryQuery,
+		delivery.ID, delivery.WebhookID, delivery.Event, delivery.Payload,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.CreatedAt)
+}
+
+func (s *Store) ClaimDueWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	return s.queryWebhookDeliveries(ctx, ClaimDueWebhookDeliveriesQuery, now, leaseUntil, limit)
+}
+
+func (s *Store) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return s.loader.exec(ctx, UpdateWebhookDeliveryQuery,
+		delivery.ID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt,
+		delivery.LastError, delivery.ResponseStatus, delivery.CompletedAt)
+}
+
+func (s *Store) GetWebhookDeliveriesByWebhookID(ctx context.Context, webhookID uuid.UUID, status models.DeliveryStatus, limit int) ([]*models.WebhookDelivery, error) {
+	return s.queryWebhookDeliveries(ctx, GetWebhookDeliveriesQuery, webhookID, string(status), limit)
+}
+
+func (s *Store) queryWebhookDeliveries(ctx context.Context, name QueryName, args ...interface{}) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	err := s.loader.queryRows(ctx, name,
+		func(rows pgx.Rows) error {
+			for rows.Next() {
+				delivery := &models.WebhookDelivery{}
+				err := rows.Scan(
+					&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Payload,
+					&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt,
+					&delivery.LastError, &delivery.ResponseStatus, &delivery.CreatedAt,
+					&delivery.CompletedAt,
+				)
+				if err != nil {
+					return err
+				}
+				deliveries = append(deliveries, delivery)
+			}
+			return nil
+		},
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"chat-room/models"
+	"chat-room/store"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStore connects to the database of TEST_DATABASE_URL and applies the
+// migrations. Tests needing a database are skipped without it.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	s, err := New(ctx, url)
+	require.NoError(t, err)
+	t.Cleanup(s.Close)
+	require.NoError(t, s.Migrate(ctx))
+	return s
+}
+
+func TestCreateIncomingWebhook_IsAtomic(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+
+	creator := &models.User{Username: "creator-" + uuid.NewString(), Nickname: "creator-" + uuid.NewString()}
+	require.NoError(t, s.CreateUser(ctx, creator))
+	session := &models.Session{Name: "webhooks", CreatorID: creator.ID}
+	require.NoError(t, s.CreateSession(ctx, session))
+	t.Cleanup(func() {
+		s.DeleteSession(ctx, session.ID)
+		s.DeleteUser(ctx, creator.ID)
+	})
+
+	newBot := func() *models.User {
+		id := uuid.New()
+		return &models.User{ID: id, Username: "bot-" + id.String(), Nickname: "bot-" + id.String(), IsBot: true}
+	}
+
+	bot := newBot()
+	hook := &models.IncomingWebhook{SessionID: session.ID, Name: "CI", TokenHash: "hash", CreatedBy: creator.ID}
+	require.NoError(t, s.CreateIncomingWebhook(ctx, hook, bot))
+	t.Cleanup(func() { s.DeleteUser(ctx, bot.ID) })
+	assert.Equal(t, bot.ID, hook.BotUserID)
+
+	// Reusing the webhook ID makes the last insert fail
+	failedBot := newBot()
+	duplicate := &models.IncomingWebhook{ID: hook.ID, SessionID: session.ID, Name: "CI", TokenHash: "hash", CreatedBy: creator.ID}
+	require.Error(t, s.CreateIncomingWebhook(ctx, duplicate, failedBot))
+
+	_, err := s.GetUserByID(ctx, failedBot.ID)
+	assert.ErrorIs(t, err, store.ErrNotFound)
+	members, err := s.GetUserSessionsBySessionIDAndUserIDs(ctx, session.ID, []uuid.UUID{failedBot.ID})
+	require.NoError(t, err)
+	assert.Empty(t, members)
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"sort"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+)
+
+// NumberMessages assigns sequence numbers to messages inserted in bulk.
+// Messages whose ID is in existing, repeats of an ID within messages and
+// messages of sessions missing from lastSeq are dropped, so that numbers are
+// only given to messages that are inserted. The remaining messages are
+// numbered after lastSeq of their session in (timestamp, id) order, and
+// lastSeq is advanced to the last number given. Returns the numbered
+// messages in that order.
+func NumberMessages(messages []*models.Message, existing map[uuid.UUID]bool, lastSeq map[uuid.UUID]int64) []*models.Message {
+	seen := make(map[uuid.UUID]bool, len(messages))
+	numbered := make([]*models.Message, 0, len(messages))
+	for _, message := range messages {
+		if existing[message.ID] || seen[message.ID] {
+			continue
+		}
+		if _, ok := lastSeq[message.SessionID]; !ok {
+			continue
+		}
+		seen[message.ID] = true
+		numbered = append(numbered, message)
+	}
+
+	sort.SliceStable(numbered, func(i, j int) bool {
+		a, b := numbered[i], numbered[j]
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	for _, message := range numbered {
+		lastSeq[message.SessionID]++
+		message.Seq = lastSeq[message.SessionID]
+	}
+	return numbered
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func importBatch(sessionID uuid.UUID, ids []uuid.UUID) []*models.Message {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	messages := make([]*models.Message, len(ids))
+	for i, id := range ids {
+		messages[i] = &models.Message{ID: id, SessionID: sessionID, Timestamp: start.Add(time.Duration(i) * time.Minute)}
+	}
+	return messages
+}
+
+func TestNumberMessages_OrdersByTimestamp(t *testing.T) {
+	sessionID := uuid.New()
+	messages := importBatch(sessionID, []uuid.UUID{uuid.New(), uuid.New(), uuid.New()})
+	reversed := []*models.Message{messages[2], messages[0], messages[1]}
+	lastSeq := map[uuid.UUID]int64{sessionID: 10}
+
+	numbered := NumberMessages(reversed, nil, lastSeq)
+	assert.Equal(t, messages, numbered)
+	assert.Equal(t, []int64{11, 12, 13}, []int64{messages[0].Seq, messages[1].Seq, messages[2].Seq})
+	assert.Equal(t, int64(13), lastSeq[sessionID])
+}
+
+func TestNumberMessages_RerunIsGapFree(t *testing.T) {
+	sessionID := uuid.New()
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	lastSeq := map[uuid.UUID]int64{sessionID: 0}
+	existing := make(map[uuid.UUID]bool)
+
+	for _, message := range NumberMessages(importBatch(sessionID, ids), existing, lastSeq) {
+		existing[message.ID] = true
+	}
+	assert.Equal(t, int64(3), lastSeq[sessionID])
+
+	// Rerunning the import with one more message only numbers the new one
+	rerun := importBatch(sessionID, append(ids, uuid.New()))
+	numbered := NumberMessages(rerun, existing, lastSeq)
+	if assert.Len(t, numbered, 1) {
+		assert.Equal(t, rerun[3].ID, numbered[0].ID)
+		assert.Equal(t, int64(4), numbered[0].Seq)
+	}
+	assert.Equal(t, int64(4), lastSeq[sessionID])
+
+	// A rerun without new messages takes no numbers
+	assert.Empty(t, NumberMessages(importBatch(sessionID, ids), existing, lastSeq))
+	assert.Equal(t, int64(4), lastSeq[sessionID])
+}
+
+func TestNumberMessages_SkipsRepeatedIDs(t *testing.T) {
+	sessionID := uuid.New()
+	id := uuid.New()
+	lastSeq := map[uuid.UUID]int64{sessionID: 0}
+
+	numbered := NumberMessages(importBatch(sessionID, []uuid.UUID{id, id, uuid.New()}), nil, lastSeq)
+	assert.Len(t, numbered, 2)
+	assert.Equal(t, int64(2), lastSeq[sessionID])
+}
+
+func TestNumberMessages_PerSession(t *testing.T) {
+	first, second, missing := uuid.New(), uuid.New(), uuid.New()
+	lastSeq := map[uuid.UUID]int64{first: 5, second: 0}
+	messages := append(importBatch(first, []uuid.UUID{uuid.New(), uuid.New()}),
+		append(importBatch(second, []uuid.UUID{uuid.New()}), importBatch(missing, []uuid.UUID{uuid.New()})...)...)
+
+	numbered := NumberMessages(messages, nil, lastSeq)
+	assert.Len(t, numbered, 3)
+	assert.Equal(t, int64(7), lastSeq[first])
+	assert.Equal(t, int64(1), lastSeq[second])
+	assert.NotContains(t, lastSeq, missing)
+	assert.Zero(t, messages[3].Seq)
+}
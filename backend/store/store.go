@@ -3,6 +3,7 @@ package store
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"chat-room/models"
@@ -10,6 +11,9 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrNotFound is returned when a requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
 // UserStore defines operations for managing user data.
 type UserStore interface {
 	// CreateUser creates a new user in the store.
@@ -30,6 +34,14 @@ type UserStore interface {
 	// Only updates username, nickname, and avatar_url fields.
 	UpdateUser(ctx context.Context, user *models.User) error
 
+	// SetDefaultAvatar sets the avatar_url of a user who has no avatar.
+	// An avatar set meanwhile is kept.
+	SetDefaultAvatar(ctx context.Context, userID uuid.UUID, avatarURL string) error
+
+	// GetUserIDsWithoutAvatar retrieves the IDs of up to limit users without
+	// an avatar and with an ID greater than afterID, in ascending order.
+	GetUserIDsWithoutAvatar(ctx context.Context, afterID uuid.UUID, limit int) ([]uuid.UUID, error)
+
 	// DeleteUser removes a user from the store.
 	// This operation is irreversible.
 	DeleteUser(ctx context.Context, id uuid.UUID) error
@@ -66,21 +78,46 @@ type MessageStore interface {
 	// CreateMessage creates a new message in a session.
 	// If message.ID is nil, it will be generated.
 	// If message.Timestamp is zero, it will be set to current time.
+	// message.Seq is set to the next sequence number of the session.
 	CreateMessage(ctx context.Context, message *models.Message) error
 
+	// CreateMessages inserts messages in bulk, keeping their IDs and timestamps.
+	// Messages whose ID already exists are skipped, which makes repeated
+	// imports idempotent. Inserted messages are numbered after the existing
+	// messages of their session in timestamp order, without gaps, and their
+	// message.Seq is set.
+	// Returns the number of messages inserted.
+	CreateMessages(ctx context.Context, messages []*models.Message) (int, error)
+
 	// DeleteMessage removes a message from the store.
 	// This operation is irreversible.
 	DeleteMessage(ctx context.Context, id uuid.UUID) error
 
-	// GetMessageIDsBySessionID retrieves message IDs for a session.
-	// Returns IDs ordered by timestamp DESC, limited by the limit parameter.
-	// Only returns messages with timestamp before the specified time.
-	GetMessageIDsBySessionID(ctx context.Context, sessionID uuid.UUID, limit int, before time.Time) ([]uuid.UUID, error)
+	// GetMessagesBySessionID retrieves a page of messages of a session next to cursor.
+	// PageBefore returns messages older than the cursor ordered by (timestamp, id) DESC,
+	// PageAfter returns messages newer than the cursor ordered by (timestamp, id) ASC.
+	// A nil cursor starts from the newest (PageBefore) or oldest (PageAfter) message.
+	GetMessagesBySessionID(ctx context.Context, sessionID uuid.UUID, cursor *MessageCursor, direction PageDirection, limit int) ([]*models.Message, error)
+
+	// GetMessagesAfterSeq retrieves up to limit messages of a session with a
+	// sequence number greater than afterSeq, ordered by seq ASC.
+	GetMessagesAfterSeq(ctx context.Context, sessionID uuid.UUID, afterSeq int64, limit int) ([]*models.Message, error)
+
+	// GetMessageByID retrieves a message by its ID.
+	// Returns ErrNotFound if the message doesn't exist.
+	GetMessageByID(ctx context.Context, id uuid.UUID) (*models.Message, error)
 
 	// GetMessagesByIDs retrieves multiple messages by their IDs.
 	// Returns a slice of messages in no particular order.
 	// If some IDs don't exist, they will be omitted from the result.
 	GetMessagesByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Message, error)
+
+	// UpdateMessageMedia replaces the media description of an image message.
+	UpdateMessageMedia(ctx context.Context, id uuid.UUID, media *models.MessageMedia) error
+
+	// GetImageMessagesWithoutMedia retrieves up to limit image messages without
+	// a media description and with an ID greater than afterID, ordered by ID.
+	GetImageMessagesWithoutMedia(ctx context.Context, afterID uuid.UUID, limit int) ([]*models.Message, error)
 }
 
 // UserSessionStore defines operations for managing user-session relationships.
@@ -93,6 +130,13 @@ type UserSessionStore interface {
 	// This operation is irreversible.
 	RemoveUserFromSession(ctx context.Context, userID, sessionID uuid.UUID) error
 
+	// UpdateUserSessionRole changes the role of a session member.
+	UpdateUserSessionRole(ctx context.Context, userID, sessionID uuid.UUID, role string) error
+
+	// SetUserSessionMutedUntil mutes a session member until the given time.
+	// A nil time unmutes the member.
+	SetUserSessionMutedUntil(ctx context.Context, userID, sessionID uuid.UUID, mutedUntil *time.Time) error
+
 	// GetSessionIDsByUserID retrieves all session IDs a user is a member of.
 	GetSessionIDsByUserID(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
 
@@ -103,6 +147,240 @@ type UserSessionStore interface {
 	GetUserSessionsBySessionIDAndUserIDs(ctx context.Context, sessionID uuid.UUID, userIDs []uuid.UUID) ([]*models.UserSession, error)
 }
 
+// WebhookStore defines operations for managing session webhooks.
+type WebhookStore interface {
+	// CreateIncomingWebhook creates a new incoming webhook for a session,
+	// together with its bot user, which joins the session with the "bot" role.
+	// Either all three records are created or none.
+	// If webhook.ID or bot.ID is nil, it will be generated, and
+	// webhook.BotUserID is set to bot.ID.
+	// If webhook.CreatedAt or bot.CreatedAt is zero, it will be set to current time.
+	CreateIncomingWebhook(ctx context.Context, webhook *models.IncomingWebhook, bot *models.User) error
+
+	// GetIncomingWebhookByID retrieves an incoming webhook by its ID.
+	// Revoked webhooks are returned as well; callers must check RevokedAt.
+	GetIncomingWebhookByID(ctx context.Context, id uuid.UUID) (*models.IncomingWebhook, error)
+
+	// GetIncomingWebhooksBySessionID retrieves all incoming webhooks of a session,
+	// ordered by creation time.
+	GetIncomingWebhooksBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*models.IncomingWebhook, error)
+
+	// RotateIncomingWebhookToken replaces the token hash of an active webhook.
+	RotateIncomingWebhookToken(ctx context.Context, id uuid.UUID, tokenHash string) error
+
+	// RevokeIncomingWebhook marks a webhook as revoked.
+	// Revoked webhooks no longer accept payloads.
+	RevokeIncomingWebhook(ctx context.Context, id uuid.UUID) error
+
+	// CreateOutgoingWebhook creates a new outgoing webhook subscription.
+	// If webhook.ID is nil, it will be generated.
+	// If webhook.CreatedAt is zero, it will be set to current time.
+	CreateOutgoingWebhook(ctx context.Context, webhook *models.OutgoingWebhook) error
+
+	// GetOutgoingWebhookByID retrieves an outgoing webhook by its ID.
+	// Returns ErrNotFound if the webhook doesn't exist.
+	GetOutgoingWebhookByID(ctx context.Context, id uuid.UUID) (*models.OutgoingWebhook, error)
+
+	// GetOutgoingWebhooksBySessionID retrieves all outgoing webhooks of a session,
+	// ordered by creation time.
+	GetOutgoingWebhooksBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*models.OutgoingWebhook, error)
+
+	// DeleteOutgoingWebhook removes an outgoing webhook and its delivery log.
+	// This operation is irreversible.
+	DeleteOutgoingWebhook(ctx context.Context, id uuid.UUID) error
+
+	// CreateWebhookDelivery queues a delivery for an outgoing webhook.
+	// If delivery.ID is nil, it will be generated.
+	// If delivery.CreatedAt is zero, it will be set to current time.
+	CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+
+	// ClaimDueWebhookDeliveries returns up to limit pending deliveries due at now
+	// and pushes their next attempt to leaseUntil, so that concurrent workers
+	// do not pick up the same deliveries.
+	ClaimDueWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDelivery, error)
+
+	// UpdateWebhookDelivery stores the outcome of a delivery attempt.
+	// Updates status, attempts, next_attempt_at, last_error, response_status and completed_at.
+	UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+
+	// GetWebhookDeliveriesByWebhookID retrieves the most recent deliveries of a webhook,
+	// ordered by creation time DESC. An empty status returns deliveries of any status.
+	GetWebhookDeliveriesByWebhookID(ctx context.Context, webhookID uuid.UUID, status models.DeliveryStatus, limit int) ([]*models.WebhookDelivery, error)
+}
+
+// ModerationStore defines operations for managing message moderation and reports.
+type ModerationStore interface {
+	// GetModerationSettings retrieves the moderation settings of a session.
+	// Returns ErrNotFound if the session has no settings.
+	GetModerationSettings(ctx context.Context, sessionID uuid.UUID) (*models.ModerationSettings, error)
+
+	// UpsertModerationSettings creates or replaces the moderation settings of a session.
+	// If settings.UpdatedAt is zero, it will be set to current time.
+	UpsertModerationSettings(ctx context.Context, settings *models.ModerationSettings) error
+
+	// CreateModerationFlag records a flagged message.
+	// If flag.ID is nil, it will be generated.
+	// If flag.CreatedAt is zero, it will be set to current time.
+	CreateModerationFlag(ctx context.Context, flag *models.ModerationFlag) error
+
+	// GetModerationFlagsBySessionID retrieves the most recent flags of a session,
+	// ordered by creation time DESC.
+	GetModerationFlagsBySessionID(ctx context.Context, sessionID uuid.UUID, limit int) ([]*models.ModerationFlag, error)
+
+	// CreateMessageReport records a report of a message.
+	// If report.ID is nil, it will be generated.
+	// If report.CreatedAt is zero, it will be set to current time.
+	// If the reporter already has an open report of the message, its reason is
+	// updated instead and report.ID and report.CreatedAt are set to the existing report's.
+	CreateMessageReport(ctx context.Context, report *models.MessageReport) error
+
+	// GetMessageReportByID retrieves a message report by its ID.
+	// Returns ErrNotFound if the report doesn't exist.
+	GetMessageReportByID(ctx context.Context, id uuid.UUID) (*models.MessageReport, error)
+
+	// GetMessageReportsBySessionID retrieves the most recent reports of a session,
+	// ordered by creation time DESC. An empty status returns reports of any status.
+	GetMessageReportsBySessionID(ctx context.Context, sessionID uuid.UUID, status models.ReportStatus, limit int) ([]*models.MessageReport, error)
+
+	// ResolveMessageReports resolves all open reports of a message.
+	ResolveMessageReports(ctx context.Context, messageID uuid.UUID, resolution models.ReportResolution, resolvedBy uuid.UUID, resolvedAt time.Time) error
+}
+
+// BookmarkStore defines operations for managing the messages saved by users.
+type BookmarkStore interface {
+	// SaveBookmark saves a message for a user.
+	// If bookmark.CreatedAt is zero, it will be set to current time.
+	// Saving a message again updates the note and folder and keeps the original creation time.
+	SaveBookmark(ctx context.Context, bookmark *models.Bookmark) error
+
+	// DeleteBookmark removes a saved message of a user.
+	DeleteBookmark(ctx context.Context, userID, messageID uuid.UUID) error
+
+	// GetBookmarksByUserID retrieves the bookmarks of a user before the given
+	// cursor (or the newest, if before is nil), ordered by (creation time,
+	// message ID) DESC, together with their messages and session names. The
+	// cursor of a bookmark is BookmarkCursorOf. An empty folder returns
+	// bookmarks of any folder.
+	// Bookmarks of sessions the user is no longer a member of are omitted.
+	GetBookmarksByUserID(ctx context.Context, userID uuid.UUID, folder string, before *MessageCursor, limit int) ([]*models.Bookmark, error)
+
+	// GetBookmarkFoldersByUserID retrieves the folders of a user's visible bookmarks, ordered by name.
+	GetBookmarkFoldersByUserID(ctx context.Context, userID uuid.UUID) ([]*models.BookmarkFolder, error)
+}
+
+// DraftStore defines operations for managing unsent messages.
+type DraftStore interface {
+	// GetDraft retrieves the draft of a user in a session.
+	// Returns ErrNotFound if the user has no draft or it was deleted.
+	GetDraft(ctx context.Context, userID, sessionID uuid.UUID) (*models.Draft, error)
+
+	// SaveDraft stores a draft unless a draft with a later UpdatedAt is already
+	// stored, or was deleted later.
+	// If draft.UpdatedAt is zero, it will be set to current time.
+	// draft is set to the stored draft, which is empty if it was deleted; the
+	// result reports whether it was written.
+	SaveDraft(ctx context.Context, draft *models.Draft) (bool, error)
+
+	// DeleteDraft empties the draft of a user in a session unless it was
+	// updated after before. The empty draft is kept with before as UpdatedAt,
+	// so that older writes arriving later are ignored.
+	DeleteDraft(ctx context.Context, userID, sessionID uuid.UUID, before time.Time) error
+}
+
+// EmojiStore defines operations for managing the custom emoji of sessions.
+type EmojiStore interface {
+	// CreateCustomEmoji adds a custom emoji to a session.
+	// If emoji.ID is nil, it will be generated.
+	// If emoji.CreatedAt is zero, it will be set to current time.
+	CreateCustomEmoji(ctx context.Context, emoji *models.CustomEmoji) error
+
+	// GetCustomEmojiByID retrieves a custom emoji by its ID.
+	// Returns ErrNotFound if the emoji doesn't exist.
+	GetCustomEmojiByID(ctx context.Context, id uuid.UUID) (*models.CustomEmoji, error)
+
+	// GetCustomEmojisBySessionID retrieves all custom emoji of a session, ordered by shortcode.
+	GetCustomEmojisBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*models.CustomEmoji, error)
+
+	// DeleteCustomEmoji removes a custom emoji.
+	// This operation is irreversible.
+	DeleteCustomEmoji(ctx context.Context, id uuid.UUID) error
+}
+
+// UploadStore defines operations for managing direct upload slots.
+type UploadStore interface {
+	// CreateUpload creates an upload slot.
+	// If upload.ID is nil, it will be generated.
+	// If upload.CreatedAt is zero, it will be set to current time.
+	// If upload.Status is empty, it will be set to pending.
+	CreateUpload(ctx context.Context, upload *models.Upload) error
+
+	// GetUploadByID retrieves an upload by its ID.
+	// Returns ErrNotFound if the upload doesn't exist.
+	GetUploadByID(ctx context.Context, id uuid.UUID) (*models.Upload, error)
+
+	// AdvanceUpload records a chunk of a pending resumable upload, moving its
+	// offset from offset to newOffset.
+	// Returns ErrNotFound if the upload doesn't exist, is not pending or is
+	// not at offset, so that concurrent chunks for an offset cannot both be stored.
+	AdvanceUpload(ctx context.Context, id uuid.UUID, offset, newOffset int64, part models.UploadPart) error
+
+	// CompleteUpload marks a pending upload as completed.
+	// Returns ErrNotFound if the upload doesn't exist or is not pending, so
+	// that an upload is completed at most once.
+	CompleteUpload(ctx context.Context, id uuid.UUID) error
+
+	// DeleteExpiredUploads deletes the uploads, completed or not, whose slot
+	// expired before the given time. Returns the deleted uploads.
+	DeleteExpiredUploads(ctx context.Context, before time.Time) ([]*models.Upload, error)
+}
+
+// MediaStore defines operations on the blob store objects referenced by records.
+type MediaStore interface {
+	// GetReferencedObjectNames returns the object names referenced by user
+	// avatars, image messages and their variants, file messages, custom emoji
+	// and unexpired pending uploads. Avatars and image messages stored before they were referenced
+	// by name are returned as URLs.
+	GetReferencedObjectNames(ctx context.Context) ([]string, error)
+
+	// CreateMediaObject records a stored object, replacing the record of an
+	// object stored under the same name.
+	// If object.CreatedAt is zero, it will be set to current time.
+	CreateMediaObject(ctx context.Context, object *models.MediaObject) error
+
+	// DeleteMediaObjects removes the records of deleted objects. Names without
+	// a record are ignored.
+	DeleteMediaObjects(ctx context.Context, objectNames []string) error
+
+	// AcquireMediaBlob takes a reference to a content addressed object.
+	// Returns the number of references including the new one; the object
+	// must be stored by the first reference. Waits while the object is being
+	// deleted by ReleaseMediaBlob or DeleteOrphanedMediaObject.
+	AcquireMediaBlob(ctx context.Context, objectName string, size int64) (int, error)
+
+	// ReleaseMediaBlob drops a reference to a content addressed object. The
+	// last reference deletes the object with remove and removes its reference
+	// count and record, while references are not taken. If remove fails, the
+	// reference count and record are removed anyway and its error is
+	// returned; the garbage collector deletes the object later.
+	// Returns ErrNotFound if the object is not content addressed.
+	ReleaseMediaBlob(ctx context.Context, objectName string, remove func(ctx context.Context) error) error
+
+	// DeleteOrphanedMediaObject deletes an object no record refers to with
+	// remove and removes its record, unless it is a content addressed object
+	// with references, which are not taken meanwhile. Returns false if the
+	// object is kept.
+	DeleteOrphanedMediaObject(ctx context.Context, objectName string, remove func(ctx context.Context) error) (bool, error)
+
+	// GetUserStorageUsage returns the total size of the objects recorded for
+	// the user and of the user's pending uploads that have not expired, which
+	// reserve their size until they complete.
+	GetUserStorageUsage(ctx context.Context, userID uuid.UUID) (int64, error)
+
+	// GetSessionStorageUsage returns the total size of the objects recorded for
+	// the session and of its pending uploads that have not expired.
+	GetSessionStorageUsage(ctx context.Context, sessionID uuid.UUID) (int64, error)
+}
+
 // Store combines all sub-stores into a single interface.
 // It provides transaction support and manages the lifecycle of the store.
 type Store interface {
@@ -110,6 +388,13 @@ type Store interface {
 	SessionStore
 	MessageStore
 	UserSessionStore
+	WebhookStore
+	ModerationStore
+	BookmarkStore
+	DraftStore
+	EmojiStore
+	UploadStore
+	MediaStore
 
 	// BeginTx starts a new transaction.
 	// The transaction must be committed or rolled back.
@@ -126,6 +411,13 @@ type Transaction interface {
 	SessionStore
 	MessageStore
 	UserSessionStore
+	WebhookStore
+	ModerationStore
+	BookmarkStore
+	DraftStore
+	EmojiStore
+	UploadStore
+	MediaStore
 
 	// Commit commits the transaction.
 	Commit() error
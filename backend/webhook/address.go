@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrInvalidURL is returned for webhook URLs that are not absolute http or https URLs.
+	ErrInvalidURL = errors.New("URL must be an absolute http or https URL")
+	// ErrInternalAddress is returned for webhook URLs and connections pointing
+	// at loopback, private, link-local or otherwise internal addresses.
+	ErrInternalAddress = errors.New("URL must not point at an internal address")
+)
+
+// internalPrefixes are the internal ranges not covered by the net.IP methods
+// used in IsPublicAddress.
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// IsPublicAddress reports whether webhooks may be delivered to ip: it must
+// not be a loopback, private, link-local, multicast or unspecified address.
+func IsPublicAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range internalPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseURL parses the URL of an outgoing webhook: it must be an absolute
+// http or https URL and, unless allowInternal is set, must not name an
+// internal IP address or localhost. Other host names are checked when
+// deliveries connect, after they are resolved, for early feedback only.
+func ParseURL(rawURL string, allowInternal bool) (*url.URL, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return nil, ErrInvalidURL
+	}
+	if allowInternal {
+		return target, nil
+	}
+	host := target.Hostname()
+	if ip := net.ParseIP(host); (ip != nil && !IsPublicAddress(ip)) || strings.EqualFold(host, "localhost") {
+		return nil, ErrInternalAddress
+	}
+	return target, nil
+}
+
+// newClient returns the client sending deliveries. Unless allowInternal is
+// set, it refuses to connect to internal addresses; the address is checked
+// after DNS resolution, so host names resolving to internal addresses are
+// refused too. Redirects are never followed, so public receivers cannot
+// bounce deliveries to internal URLs.
+func newClient(timeout time.Duration, allowInternal bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowInternal {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicAddress(ip) {
+				return ErrInternalAddress
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would connect to the receiver on behalf of the checked dialer
+	transport.Proxy = nil
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	for address, public := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"::1":              false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		assert.Equal(t, public, IsPublicAddress(net.ParseIP(address)), address)
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	target, err := ParseURL("https://example.com/hook", false)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", target.Host)
+
+	for _, rawURL := range []string{"ftp://example.com", "/hook", "https://"} {
+		_, err := ParseURL(rawURL, false)
+		assert.ErrorIs(t, err, ErrInvalidURL, rawURL)
+	}
+	for _, rawURL := range []string{"http://127.0.0.1:8080/admin", "http://169.254.169.254/latest", "http://[::1]/", "http://LOCALHOST/"} {
+		_, err := ParseURL(rawURL, false)
+		assert.ErrorIs(t, err, ErrInternalAddress, rawURL)
+
+		_, err = ParseURL(rawURL, true)
+		assert.NoError(t, err, rawURL)
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL, nil)
+	require.NoError(t, err)
+	_, err = newClient(time.Second, false).Do(req)
+	assert.ErrorIs(t, err, ErrInternalAddress)
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	}))
+	defer server.Close()
+
+	resp, err := newClient(time.Second, true).Post(server.URL, "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+}
@@ -0,0 +1,358 @@
+// Package webhook delivers session events to outgoing webhook subscribers.
+//
+// Events are persisted as deliveries when they are published and sent by a
+// background worker, so that a slow or unavailable receiver never blocks the
+// chat. Failed deliveries are retried with exponential backoff and
+// dead-lettered once they exhaust their attempts.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+)
+
+// Event types that outgoing webhooks can subscribe to.
+const (
+	EventMessageCreated = "message.created"
+	EventMemberJoined   = "member.joined"
+	EventMemberLeft     = "member.left"
+	EventMemberKicked   = "member.kicked"
+
+	// EventAll subscribes a webhook to every event type.
+	EventAll = "*"
+)
+
+// EventTypes lists all event types that can be published.
+var EventTypes = []string{
+	EventMessageCreated,
+	EventMemberJoined,
+	EventMemberLeft,
+	EventMemberKicked,
+}
+
+// IsKnownEvent reports whether name is a valid subscription for an outgoing webhook.
+func IsKnownEvent(name string) bool {
+	if name == EventAll {
+		return true
+	}
+	for _, event := range EventTypes {
+		if event == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Headers set on every delivery request.
+const (
+	HeaderDeliveryID = "X-Webhook-Delivery"
+	HeaderEvent      = "X-Webhook-Event"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderSignature  = "X-Webhook-Signature"
+)
+
+// Event is the JSON envelope POSTed to webhook receivers.
+type Event struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"event"`
+	SessionID uuid.UUID   `json:"session_id"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// MemberEventData is the payload of member.* events.
+type MemberEventData struct {
+	UserID  uuid.UUID `json:"user_id"`
+	ActorID uuid.UUID `json:"actor_id"`
+	Via     string    `json:"via,omitempty"`
+}
+
+// Store is the subset of store.Store used by the dispatcher.
+type Store interface {
+	GetOutgoingWebhookByID(ctx context.Context, id uuid.UUID) (*models.OutgoingWebhook, error)
+	GetOutgoingWebhooksBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*models.OutgoingWebhook, error)
+	CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	ClaimDueWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+// Options configures a Dispatcher. Zero values are replaced by defaults.
+type Options struct {
+	// MaxAttempts is the number of attempts before a delivery is dead-lettered.
+	MaxAttempts int
+	// BaseBackoff is the delay after the first failed attempt; it doubles with every further failure.
+	BaseBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+	// PollInterval is how often the worker looks for due deliveries.
+	PollInterval time.Duration
+	// RequestTimeout bounds a single delivery request.
+	RequestTimeout time.Duration
+	// BatchSize is the maximum number of deliveries sent concurrently.
+	BatchSize int
+	// AllowInternalAddresses permits deliveries to loopback, private and
+	// link-local addresses, e.g. receivers of a development setup.
+	AllowInternalAddresses bool
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 8
+	}
+	if o.BaseBackoff <= 0 {
+		o.BaseBackoff = 10 * time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = time.Hour
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = 5 * time.Second
+	}
+	if o.RequestTimeout <= 0 {
+		o.RequestTimeout = 10 * time.Second
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 20
+	}
+	return o
+}
+
+// Dispatcher queues events for outgoing webhooks and delivers them in the background.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	opts   Options
+	wake   chan struct{}
+	now    func() time.Time
+}
+
+// NewDispatcher creates a new dispatcher. Call Run to start delivering events.
+func NewDispatcher(store Store, opts Options) *Dispatcher {
+	opts = opts.withDefaults()
+	return &Dispatcher{
+		store:  store,
+		client: newClient(opts.RequestTimeout, opts.AllowInternalAddresses),
+		opts:   opts,
+		wake:   make(chan struct{}, 1),
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Publish queues an event for every webhook of the session subscribed to eventType.
+// Errors are logged rather than returned so that publishing never fails the
+// operation that triggered the event.
+func (d *Dispatcher) Publish(ctx context.Context, sessionID uuid.UUID, eventType string, data interface{}) {
+	webhooks, err := d.store.GetOutgoingWebhooksBySessionID(ctx, sessionID)
+	if err != nil {
+		log.Printf("Error loading outgoing webhooks for session %s: %v", sessionID, err)
+		return
+	}
+
+	queued := false
+	for _, webhook := range webhooks {
+		if !subscribed(webhook, eventType) {
+			continue
+		}
+
+		now := d.now()
+		event := Event{
+			ID:        uuid.New(),
+			Type:      eventType,
+			SessionID: sessionID,
+			CreatedAt: now,
+			Data:      data,
+		}
+		payload, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("Error encoding %s event: %v", eventType, err)
+			return
+		}
+
+		delivery := &models.WebhookDelivery{
+			ID:            event.ID,
+			WebhookID:     webhook.ID,
+			Event:         eventType,
+			Payload:       payload,
+			Status:        models.DeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		if err := d.store.CreateWebhookDelivery(ctx, delivery); err != nil {
+			log.Printf("Error queueing %s delivery for webhook %s: %v", eventType, webhook.ID, err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// PublishMessage queues a message.created event for the message, unless it
+// was posted by a bot. Bots post the messages of incoming webhooks, which may
+// in turn be sent by the receiver of an outgoing webhook; notifying it of
+// them could loop forever.
+func (d *Dispatcher) PublishMessage(ctx context.Context, message *models.Message, fromBot bool) {
+	if fromBot {
+		return
+	}
+	d.Publish(ctx, message.SessionID, EventMessageCreated, message)
+}
+
+// Run delivers due events until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.processDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// processDue sends one batch of due deliveries.
+func (d *Dispatcher) processDue(ctx context.Context) {
+	now := d.now()
+	// Claimed deliveries are hidden from other workers until the lease expires,
+	// which is long enough for the request to time out.
+	leaseUntil := now.Add(2 * d.opts.RequestTimeout)
+
+	deliveries, err := d.store.ClaimDueWebhookDeliveries(ctx, now, leaseUntil, d.opts.BatchSize)
+	if err != nil {
+		log.Printf("Error claiming webhook deliveries: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			d.attempt(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+// attempt sends a delivery once and records the outcome.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	webhook, err := d.store.GetOutgoingWebhookByID(ctx, delivery.WebhookID)
+	if err != nil {
+		// The webhook was deleted; its deliveries are removed with it
+		return
+	}
+
+	status, err := d.send(ctx, webhook, delivery)
+
+	now := d.now()
+	delivery.Attempts++
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryStatusSucceeded
+		delivery.LastError = ""
+		delivery.CompletedAt = &now
+	case delivery.Attempts >= d.opts.MaxAttempts:
+		delivery.Status = models.DeliveryStatusDead
+		delivery.LastError = err.Error()
+		delivery.CompletedAt = &now
+		log.Printf("Webhook delivery %s dead-lettered after %d attempts: %v", delivery.ID, delivery.Attempts, err)
+	default:
+		delivery.Status = models.DeliveryStatusPending
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+	}
+
+	if err := d.store.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		log.Printf("Error updating webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// send POSTs a signed delivery to the webhook URL. It returns the response
+// status code, if any, and an error unless the receiver answered with 2xx.
+func (d *Dispatcher) send(ctx context.Context, webhook *models.OutgoingWebhook, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("creating request: %w", err)
+	}
+
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "chat-room-webhooks/1")
+	req.Header.Set(HeaderDeliveryID, delivery.ID.String())
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the next attempt after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.opts.MaxBackoff {
+			return d.opts.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// Sign returns the signature header value for a payload sent at timestamp (Unix seconds).
+// Receivers recompute it as "sha256=" + hex(HMAC-SHA256(secret, "<timestamp>.<body>"))
+// and compare it in constant time.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func subscribed(webhook *models.OutgoingWebhook, eventType string) bool {
+	for _, event := range webhook.Events {
+		if event == eventType || event == EventAll {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"chat-room/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore implements Store in memory for testing
+type memoryStore struct {
+	mu         sync.Mutex
+	webhooks   map[uuid.UUID]*models.OutgoingWebhook
+	deliveries map[uuid.UUID]*models.WebhookDelivery
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		webhooks:   make(map[uuid.UUID]*models.OutgoingWebhook),
+		deliveries: make(map[uuid.UUID]*models.WebhookDelivery),
+	}
+}
+
+func (m *memoryStore) GetOutgoingWebhookByID(ctx context.Context, id uuid.UUID) (*models.OutgoingWebhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	webhook, ok := m.webhooks[id]
+	if !ok {
+		return nil, assert.AnError
+	}
+	return webhook, nil
+}
+
+func (m *memoryStore) GetOutgoingWebhooksBySessionID(ctx context.Context, sessionID uuid.UUID) ([]*models.OutgoingWebhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var webhooks []*models.OutgoingWebhook
+	for _, webhook := range m.webhooks {
+		if webhook.SessionID == sessionID {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (m *memoryStore) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *delivery
+	m.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) ClaimDueWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var claimed []*models.WebhookDelivery
+	for _, delivery := range m.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if delivery.Status == models.DeliveryStatusPending && !delivery.NextAttemptAt.After(now) {
+			delivery.NextAttemptAt = leaseUntil
+			copied := *delivery
+			claimed = append(claimed, &copied)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *memoryStore) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *delivery
+	m.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+func (m *memoryStore) onlyDelivery(t *testing.T) *models.WebhookDelivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	require.Len(t, m.deliveries, 1)
+	for _, delivery := range m.deliveries {
+		return delivery
+	}
+	return nil
+}
+
+func newTestDispatcher(store Store, clock *time.Time) *Dispatcher {
+	// The test receivers listen on loopback addresses
+	d := NewDispatcher(store, Options{MaxAttempts: 3, BaseBackoff: time.Minute, MaxBackoff: 90 * time.Second, AllowInternalAddresses: true})
+	d.now = func() time.Time { return *clock }
+	return d
+}
+
+func TestDispatcher_DeliversSignedEvent(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	secret := "test-secret"
+	sessionID := uuid.New()
+
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	webhook := &models.OutgoingWebhook{
+		ID:        uuid.New(),
+		SessionID: sessionID,
+		URL:       server.URL,
+		Secret:    secret,
+		Events:    []string{EventMessageCreated},
+	}
+	store.webhooks[webhook.ID] = webhook
+
+	d := newTestDispatcher(store, &clock)
+	d.Publish(ctx, sessionID, EventMessageCreated, map[string]string{"content": "hello"})
+	d.processDue(ctx)
+
+	require.NotNil(t, received)
+	assert.Equal(t, EventMessageCreated, received.Header.Get(HeaderEvent))
+	timestamp, err := strconv.ParseInt(received.Header.Get(HeaderTimestamp), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, Sign(secret, timestamp, body), received.Header.Get(HeaderSignature))
+
+	delivery := store.onlyDelivery(t)
+	assert.Equal(t, models.DeliveryStatusSucceeded, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	require.NotNil(t, delivery.ResponseStatus)
+	assert.Equal(t, http.StatusNoContent, *delivery.ResponseStatus)
+}
+
+func TestDispatcher_RetriesThenDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sessionID := uuid.New()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	webhook := &models.OutgoingWebhook{
+		ID:        uuid.New(),
+		SessionID: sessionID,
+		URL:       server.URL,
+		Secret:    "secret",
+		Events:    []string{EventAll},
+	}
+	store.webhooks[webhook.ID] = webhook
+
+	d := newTestDispatcher(store, &clock)
+	d.Publish(ctx, sessionID, EventMemberJoined, MemberEventData{UserID: uuid.New()})
+
+	// First failure is retried after the base backoff
+	d.processDue(ctx)
+	delivery := store.onlyDelivery(t)
+	assert.Equal(t, models.DeliveryStatusPending, delivery.Status)
+	assert.Equal(t, clock.Add(time.Minute), delivery.NextAttemptAt)
+
+	// Nothing is sent before the delivery is due
+	d.processDue(ctx)
+	assert.Equal(t, 1, calls)
+
+	// Second failure doubles the backoff, capped at MaxBackoff
+	clock = clock.Add(time.Minute)
+	d.processDue(ctx)
+	delivery = store.onlyDelivery(t)
+	assert.Equal(t, clock.Add(90*time.Second), delivery.NextAttemptAt)
+
+	// Third failure exhausts MaxAttempts
+	clock = clock.Add(90 * time.Second)
+	d.processDue(ctx)
+	delivery = store.onlyDelivery(t)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, models.DeliveryStatusDead, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.NotEmpty(t, delivery.LastError)
+	assert.NotNil(t, delivery.CompletedAt)
+}
+
+func TestDispatcher_PublishOnlyToSubscribers(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	clock := time.Now().UTC()
+	sessionID := uuid.New()
+
+	subscribedHook := &models.OutgoingWebhook{ID: uuid.New(), SessionID: sessionID, Events: []string{EventMemberLeft}}
+	otherEventHook := &models.OutgoingWebhook{ID: uuid.New(), SessionID: sessionID, Events: []string{EventMessageCreated}}
+	otherSessionHook := &models.OutgoingWebhook{ID: uuid.New(), SessionID: uuid.New(), Events: []string{EventAll}}
+	for _, webhook := range []*models.OutgoingWebhook{subscribedHook, otherEventHook, otherSessionHook} {
+		store.webhooks[webhook.ID] = webhook
+	}
+
+	d := newTestDispatcher(store, &clock)
+	d.Publish(ctx, sessionID, EventMemberLeft, MemberEventData{UserID: uuid.New()})
+
+	delivery := store.onlyDelivery(t)
+	assert.Equal(t, subscribedHook.ID, delivery.WebhookID)
+	assert.Equal(t, EventMemberLeft, delivery.Event)
+}
+
+func TestDispatcher_PublishMessageSkipsBots(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	clock := time.Now().UTC()
+	sessionID := uuid.New()
+
+	hook := &models.OutgoingWebhook{ID: uuid.New(), SessionID: sessionID, Events: []string{EventMessageCreated}}
+	store.webhooks[hook.ID] = hook
+
+	d := newTestDispatcher(store, &clock)
+	d.PublishMessage(ctx, &models.Message{ID: uuid.New(), SessionID: sessionID, Content: "from a webhook"}, true)
+	assert.Empty(t, store.deliveries)
+
+	d.PublishMessage(ctx, &models.Message{ID: uuid.New(), SessionID: sessionID, Content: "hello"}, false)
+	delivery := store.onlyDelivery(t)
+	assert.Equal(t, EventMessageCreated, delivery.Event)
+}
+
+func TestIsKnownEvent(t *testing.T) {
+	assert.True(t, IsKnownEvent(EventMessageCreated))
+	assert.True(t, IsKnownEvent(EventAll))
+	assert.False(t, IsKnownEvent("message.exploded"))
+}
@@ -1,5 +1,6 @@
 import React, { useRef, useEffect } from 'react';
 import MessageBubble from './MessageBubble';
+import SystemMessage from './SystemMessage';
 
 function ChatBoard({
     messages,
@@ -11,6 +12,8 @@ function ChatBoard({
     updateZoneExpanded,
     onScroll,
     onUpdateZoneChange,
+    onReportMessage,
+    onSaveMessage,
 }) {
     const messageListRef = useRef(null);
     const updateZoneRef = useRef(null);
@@ -135,10 +138,19 @@ function ChatBoard({
                         
                         {messages.map((message, index) => (
                             <div key={message.id || index} data-message-id={message.id}>
-                                <MessageBubble
-                                    message={message}
-                                    user={users[message.user_id]}
-                                />
+                                {message.type === 'system' ? (
+                                    <SystemMessage
+                                        message={message}
+                                        users={users}
+                                    />
+                                ) : (
+                                    <MessageBubble
+                                        message={message}
+                                        user={users[message.user_id]}
+                                        onReport={onReportMessage}
+                                        onSave={onSaveMessage}
+                                    />
+                                )}
                             </div>
                         ))}
                         
@@ -1,4 +1,71 @@
 import React from 'react';
+import { blurhashToDataURL } from '../../utils/blurhash';
+
+// Splits text content at its custom emoji entities, whose offsets count UTF-16
+// code units like JavaScript strings do.
+function renderEntities(content, entities) {
+    if (!entities?.length) {
+        return content;
+    }
+    const parts = [];
+    let position = 0;
+    entities.forEach((entity, i) => {
+        if (entity.offset > position) {
+            parts.push(content.slice(position, entity.offset));
+        }
+        parts.push(
+            <img
+                key={i}
+                src={entity.url}
+                alt={`:${entity.shortcode}:`}
+                title={`:${entity.shortcode}:`}
+                className="inline-block h-6 w-6 align-text-bottom"
+            />
+        );
+        position = entity.offset + entity.length;
+    });
+    parts.push(content.slice(position));
+    return parts;
+}
+
+// Images uploaded with variants are shown from their medium variant, and
+// browsers pick a smaller or larger one from srcSet.
+function imageSource(message) {
+    const variants = message.media?.variants || [];
+    const medium = variants.find((variant) => variant.name === 'medium');
+    return medium ? medium.url : message.content;
+}
+
+function imageSrcSet(message) {
+    if (!message.media?.variants?.length) {
+        return undefined;
+    }
+    return [
+        ...message.media.variants.map((variant) => `${variant.url} ${variant.width}w`),
+        `${message.content} ${message.media.width}w`,
+    ].join(', ');
+}
+
+// The blurhash and average color of an image are shown until it has loaded;
+// its width and height reserve the space it takes.
+function imagePlaceholder(message) {
+    const placeholder = blurhashToDataURL(message.media?.blurhash);
+    return {
+        backgroundColor: message.media?.color,
+        backgroundImage: placeholder ? `url(${placeholder})` : undefined,
+        backgroundSize: 'cover',
+    };
+}
+
+function formatSize(bytes) {
+    if (bytes >= 1 << 20) {
+        return `${(bytes / (1 << 20)).toFixed(1)} MB`;
+    }
+    if (bytes >= 1 << 10) {
+        return `${Math.round(bytes / (1 << 10))} KB`;
+    }
+    return `${bytes} B`;
+}
 
 function MessageContent({ message }) {
     switch (message.type) {
@@ -6,29 +73,55 @@ function MessageContent({ message }) {
             return (
                 <div className="mt-2">
                     <img
-                        src={message.content}
+                        src={imageSource(message)}
+                        srcSet={imageSrcSet(message)}
+                        sizes="24rem"
+                        width={message.media?.width}
+                        height={message.media?.height}
                         alt="Message attachment"
-                        className="max-w-sm rounded-lg shadow hover:shadow-lg transition-shadow cursor-pointer"
+                        style={imagePlaceholder(message)}
+                        className="max-w-sm h-auto rounded-lg shadow hover:shadow-lg transition-shadow cursor-pointer"
                         onClick={() => window.open(message.content, '_blank')}
+                        onLoad={(e) => {
+                            // Transparent images would show the placeholder behind them
+                            e.target.style.background = 'none';
+                        }}
                         onError={(e) => {
                             e.target.onerror = null;
+                            e.target.srcset = '';
                             e.target.src = '/default-image-error.png';
                             e.target.className = "w-16 h-16 opacity-50";
                         }}
                     />
                 </div>
             );
+        case 'file':
+            return (
+                <a
+                    href={message.content}
+                    download={message.media?.name}
+                    className="mt-2 inline-flex items-center gap-2 px-3 py-2 bg-gray-100 rounded-lg hover:bg-gray-200 text-gray-800"
+                >
+                    <svg xmlns="http://www.w3.org/2000/svg" className="h-5 w-5 text-gray-500" fill="none" viewBox="0 0 24 24" stroke="currentColor">
+                        <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M12 10v6m0 0l-3-3m3 3l3-3M7 21h10a2 2 0 002-2V7.414a1 1 0 00-.293-.707l-4.414-4.414A1 1 0 0013.586 2H7a2 2 0 00-2 2v15a2 2 0 002 2z" />
+                    </svg>
+                    <span className="truncate max-w-xs">{message.media?.name || 'File'}</span>
+                    {message.media?.size > 0 && (
+                        <span className="text-xs text-gray-500">{formatSize(message.media.size)}</span>
+                    )}
+                </a>
+            );
         case 'text':
         default:
             return (
                 <div className="mt-1 text-gray-800 break-words whitespace-pre-wrap">
-                    {message.content}
+                    {renderEntities(message.content, message.entities)}
                 </div>
             );
     }
 }
 
-function MessageBubble({ message, user }) {
+function MessageBubble({ message, user, onReport, onSave }) {
     const timestamp = new Date(message.timestamp).toLocaleTimeString([], {
         hour: '2-digit',
         minute: '2-digit'
@@ -46,7 +139,7 @@ function MessageBubble({ message, user }) {
             <div className="flex-shrink-0 w-8 h-8">
                 {userData.avatar_url ? (
                     <img
-                        src={userData.avatar_url}
+                        src={userData.avatar_urls?.['64'] || userData.avatar_url}
                         alt={userData.nickname}
                         className="w-8 h-8 rounded-full object-cover"
                     />
@@ -62,6 +155,22 @@ function MessageBubble({ message, user }) {
                         {userData.nickname}
                     </span>
                     <span className="text-xs text-gray-500">{timestamp}</span>
+                    {onSave && (
+                        <button
+                            onClick={() => onSave(message)}
+                            className="text-xs text-gray-400 hover:text-blue-500"
+                        >
+                            Save
+                        </button>
+                    )}
+                    {onReport && (
+                        <button
+                            onClick={() => onReport(message)}
+                            className="text-xs text-gray-400 hover:text-red-500"
+                        >
+                            Report
+                        </button>
+                    )}
                 </div>
                 <MessageContent message={message} />
             </div>
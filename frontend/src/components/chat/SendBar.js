@@ -1,12 +1,68 @@
-import React, { useState, useRef } from 'react';
+import React, { useState, useRef, useEffect } from 'react';
 import sessionService from '../../services/session';
+import { websocketService } from '../../services/websocket';
+
+// Delay before an edited draft is saved to the server
+const DRAFT_SAVE_DELAY = 500;
 
 function SendBar({ onSendMessage, sessionId }) {
     const [message, setMessage] = useState('');
     const fileInputRef = useRef(null);
+    const attachmentInputRef = useRef(null);
+    // Time of the latest local edit, compared with drafts from other tabs and devices
+    const draftUpdatedAtRef = useRef(null);
+    const draftTimeoutRef = useRef(null);
     const [selectedImages, setSelectedImages] = useState([]);
     const [imagePreviewUrls, setImagePreviewUrls] = useState([]);
     const [isUploading, setIsUploading] = useState(false);
+    // Files are uploaded in resumable chunks, whose progress is shown
+    const [selectedFiles, setSelectedFiles] = useState([]);
+    const [uploadProgress, setUploadProgress] = useState(null);
+
+    // Restore the draft and follow changes made in other tabs and devices
+    useEffect(() => {
+        if (!sessionId) return;
+        let cancelled = false;
+
+        sessionService.getDraft(sessionId).then(draft => {
+            if (!cancelled && draft && draftUpdatedAtRef.current === null) {
+                draftUpdatedAtRef.current = draft.updated_at;
+                setMessage(draft.content);
+            }
+        });
+
+        websocketService.onEvent(({ event, data }) => {
+            const local = draftUpdatedAtRef.current ? new Date(draftUpdatedAtRef.current) : null;
+            if (event === 'draft.updated' && (!local || new Date(data.updated_at) > local)) {
+                draftUpdatedAtRef.current = data.updated_at;
+                setMessage(data.content);
+            } else if (event === 'draft.deleted' && (!local || new Date(data.deleted_at) >= local)) {
+                draftUpdatedAtRef.current = data.deleted_at;
+                setMessage('');
+            }
+        });
+
+        return () => {
+            cancelled = true;
+            clearTimeout(draftTimeoutRef.current);
+            websocketService.onEvent(null);
+        };
+    }, [sessionId]);
+
+    const handleMessageChange = (content) => {
+        setMessage(content);
+        const updatedAt = new Date().toISOString();
+        draftUpdatedAtRef.current = updatedAt;
+
+        clearTimeout(draftTimeoutRef.current);
+        draftTimeoutRef.current = setTimeout(() => {
+            if (content) {
+                sessionService.saveDraft(sessionId, { content, updatedAt });
+            } else {
+                sessionService.deleteDraft(sessionId, updatedAt);
+            }
+        }, DRAFT_SAVE_DELAY);
+    };
 
     const handleImageSelect = (event) => {
         const files = Array.from(event.target.files);
@@ -23,6 +79,12 @@ function SendBar({ onSendMessage, sessionId }) {
         }
     };
 
+    const handleFileSelect = (event) => {
+        const files = Array.from(event.target.files);
+        setSelectedFiles(prev => [...prev, ...files]);
+        event.target.value = '';
+    };
+
     const handleSendMessage = async () => {
         if (message.trim()) {
             console.debug('SendBar: Sending text message:', message.trim());
@@ -31,6 +93,11 @@ function SendBar({ onSendMessage, sessionId }) {
                 content: message.trim()
             });
             setMessage('');
+
+            const deletedAt = new Date().toISOString();
+            draftUpdatedAtRef.current = deletedAt;
+            clearTimeout(draftTimeoutRef.current);
+            sessionService.deleteDraft(sessionId, deletedAt);
         }
 
         if (selectedImages.length > 0) {
@@ -55,6 +122,24 @@ function SendBar({ onSendMessage, sessionId }) {
                 setImagePreviewUrls([]);
             }
         }
+
+        if (selectedFiles.length > 0) {
+            setIsUploading(true);
+            try {
+                for (const file of selectedFiles) {
+                    console.debug('SendBar: Uploading file:', file.name);
+                    await sessionService.uploadMessageFile(sessionId, file, (progress) => {
+                        setUploadProgress({ name: file.name, progress });
+                    });
+                    setSelectedFiles(prev => prev.filter(f => f !== file));
+                }
+            } catch (error) {
+                console.error('Error uploading files:', error);
+            } finally {
+                setIsUploading(false);
+                setUploadProgress(null);
+            }
+        }
     };
 
     const handleKeyPress = (e) => {
@@ -87,6 +172,26 @@ function SendBar({ onSendMessage, sessionId }) {
                     ))}
                 </div>
             )}
+            {selectedFiles.length > 0 && (
+                <div className="flex flex-wrap gap-2 p-3 border-b">
+                    {selectedFiles.map((file, index) => (
+                        <div key={index} className="flex items-center gap-2 px-3 py-1 bg-gray-100 rounded-lg text-sm text-gray-700">
+                            <span className="truncate max-w-xs">{file.name}</span>
+                            {uploadProgress?.name === file.name ? (
+                                <span className="text-xs text-gray-500">{Math.round(uploadProgress.progress * 100)}%</span>
+                            ) : (
+                                <button
+                                    onClick={() => setSelectedFiles(prev => prev.filter((_, i) => i !== index))}
+                                    disabled={isUploading}
+                                    className="text-gray-400 hover:text-gray-600"
+                                >
+                                    &times;
+                                </button>
+                            )}
+                        </div>
+                    ))}
+                </div>
+            )}
             <div className="max-w-7xl mx-auto px-4 sm:px-6 lg:px-8">
                 <div className="flex items-end space-x-3 py-3">
                     <button
@@ -101,16 +206,34 @@ function SendBar({ onSendMessage, sessionId }) {
                     <input
                         type="file"
                         ref={fileInputRef}
-                        accept="image/*"
+                        accept="image/png,image/jpeg,image/gif,image/webp"
                         onChange={handleImageSelect}
                         className="hidden"
                         multiple
                         disabled={isUploading}
                     />
+                    <button
+                        onClick={() => attachmentInputRef.current?.click()}
+                        disabled={isUploading}
+                        title="Attach files"
+                        className={`p-2 rounded-full text-gray-600 hover:text-gray-900 hover:bg-gray-100 focus:outline-none focus:ring-2 focus:ring-blue-500 ${isUploading ? 'opacity-50 cursor-not-allowed' : ''}`}
+                    >
+                        <svg xmlns="http://www.w3.org/2000/svg" className="h-6 w-6" fill="none" viewBox="0 0 24 24" stroke="currentColor">
+                            <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M15.172 7l-6.586 6.586a2 2 0 102.828 2.828l6.414-6.586a4 4 0 00-5.656-5.656l-6.415 6.585a6 6 0 108.486 8.486L20.5 13" />
+                        </svg>
+                    </button>
+                    <input
+                        type="file"
+                        ref={attachmentInputRef}
+                        onChange={handleFileSelect}
+                        className="hidden"
+                        multiple
+                        disabled={isUploading}
+                    />
                     <div className="flex-1">
                         <textarea
                             value={message}
-                            onChange={(e) => setMessage(e.target.value)}
+                            onChange={(e) => handleMessageChange(e.target.value)}
                             onKeyDown={handleKeyPress}
                             placeholder="Type a message..."
                             className="w-full resize-none border rounded-lg px-4 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500 focus:border-blue-500 min-h-[44px] max-h-32"
@@ -120,9 +243,9 @@ function SendBar({ onSendMessage, sessionId }) {
                     </div>
                     <button
                         onClick={handleSendMessage}
-                        disabled={(!message.trim() && !selectedImages.length) || isUploading}
+                        disabled={(!message.trim() && !selectedImages.length && !selectedFiles.length) || isUploading}
                         className={`p-2 rounded-full ${
-                            (message.trim() || selectedImages.length) && !isUploading
+                            (message.trim() || selectedImages.length || selectedFiles.length) && !isUploading
                                 ? 'bg-blue-600 hover:bg-blue-700 focus:ring-2 focus:ring-blue-500 focus:ring-offset-2'
                                 : 'bg-gray-300'
                         }`}
@@ -0,0 +1,63 @@
+import React from 'react';
+
+function nicknameOf(users, id) {
+    return users[id]?.nickname || 'Unknown User';
+}
+
+// Renders the payload of a system message as a sentence
+export function describeSystemEvent(event, users) {
+    const actor = nicknameOf(users, event.actor_id);
+    const target = event.target_id ? nicknameOf(users, event.target_id) : '';
+
+    switch (event.action) {
+        case 'member_joined':
+            return event.via
+                ? `${actor} joined via ${event.via.replace(/_/g, ' ')}`
+                : `${actor} joined`;
+        case 'member_left':
+            return `${actor} left`;
+        case 'member_kicked':
+            return `${actor} removed ${target}`;
+        case 'session_renamed':
+            return `${actor} renamed the session from "${event.old_name}" to "${event.new_name}"`;
+        case 'role_changed':
+            return `${actor} changed the role of ${target} from ${event.old_role} to ${event.new_role}`;
+        case 'member_muted':
+            return event.muted_until
+                ? `${actor} muted ${target} until ${new Date(event.muted_until).toLocaleString()}`
+                : `${actor} muted ${target}`;
+        case 'message_removed':
+            return `${actor} removed a message by ${target}`;
+        default:
+            return `${actor}: ${event.action}`;
+    }
+}
+
+export function parseSystemEvent(message) {
+    try {
+        return JSON.parse(message.content);
+    } catch (error) {
+        return null;
+    }
+}
+
+function SystemMessage({ message, users }) {
+    const event = parseSystemEvent(message);
+    if (!event) return null;
+
+    const timestamp = new Date(message.timestamp).toLocaleTimeString([], {
+        hour: '2-digit',
+        minute: '2-digit'
+    });
+
+    return (
+        <div className="flex justify-center mb-4">
+            <div className="text-xs text-gray-500 italic">
+                {describeSystemEvent(event, users)}
+                <span className="ml-2 text-gray-400 not-italic">{timestamp}</span>
+            </div>
+        </div>
+    );
+}
+
+export default SystemMessage;
@@ -6,6 +6,8 @@ import SendBar from '../components/chat/SendBar';
 import sessionService from '../services/session';
 import { websocketService } from '../services/websocket';
 import { userService } from '../services/user';
+import { bookmarkService } from '../services/bookmark';
+import { parseSystemEvent } from '../components/chat/SystemMessage';
 
 function ChatRoom() {
     const navigate = useNavigate();
@@ -20,7 +22,7 @@ function ChatRoom() {
     const [sessionName, setSessionName] = useState('');
     const [isCreator, setIsCreator] = useState(false);
 
-    const oldestTimestampRef = React.useRef(null);
+    const oldestCursorRef = React.useRef(null);
 
     const initializeChat = useCallback(async () => {
         setIsLoading(true);
@@ -34,14 +36,33 @@ function ChatRoom() {
             await websocketService.connect(currentSessionId);
 
             // Load initial messages
-            const timestamp = new Date().toISOString();
-            await loadMessages(timestamp);
+            await loadMessages();
 
             // Set up WebSocket message handler
             websocketService.onMessage((message) => {
-                setMessages(prev => [...prev, message]);
-                if (message.user_id) {
-                    fetchMissingUsers(new Set([message.user_id]));
+                // Replayed messages may overlap with ones already shown
+                setMessages(prev => prev.some(m => m.id === message.id) ? prev : [...prev, message]);
+                const userIds = new Set(message.user_id ? [message.user_id] : []);
+                if (message.type === 'system') {
+                    const event = parseSystemEvent(message);
+                    if (event?.target_id) {
+                        userIds.add(event.target_id);
+                    }
+                    if (event?.action === 'session_renamed') {
+                        setSessionName(event.new_name);
+                    }
+                    if (event?.action === 'message_removed') {
+                        setMessages(prev => prev.filter(m => m.id !== event.message_id));
+                    }
+                }
+                if (userIds.size > 0) {
+                    fetchMissingUsers(userIds);
+                }
+            });
+
+            websocketService.onError((error) => {
+                if (error?.reason) {
+                    alert(`Message not sent: ${error.reason}`);
                 }
             });
 
@@ -73,33 +94,42 @@ function ChatRoom() {
         };
     }, [currentSessionId, initializeChat]);
 
-    async function loadMessages(beforeTimestamp = null) {
+    async function loadMessages(beforeCursor = null) {
         try {
-            const response = await sessionService.getMessages(currentSessionId, beforeTimestamp);
-            const newMessages = response.messages || [];
-            
+            const response = await sessionService.getMessages(currentSessionId, { before: beforeCursor });
+            const newMessages = response.messages;
+
             if (newMessages.length === 0) {
                 setHasMore(false);
                 return;
             }
 
             setMessages(prev => {
-                if (beforeTimestamp) {
+                if (beforeCursor) {
                     return [...newMessages, ...prev];
                 } else {
                     return newMessages;
                 }
             });
-            setHasMore(response.hasMore);
+            setHasMore(response.hasMoreBefore);
 
-            // Store the oldest message's timestamp
-            if (newMessages.length > 0) {
-                oldestTimestampRef.current = newMessages[0].timestamp;
+            // Remember where the next older page starts
+            oldestCursorRef.current = response.prevCursor;
+
+            if (!beforeCursor) {
+                newMessages.forEach(message => websocketService.noteSeq(message.seq));
             }
 
-            // Fetch usernames for new messages
-            const userIds = new Set(newMessages.map(msg => msg.user_id));
-            await fetchMissingUsers(userIds);
+            // Senders are returned with the page
+            const newUsers = {};
+            response.users.forEach(user => {
+                if (user && user.id) {
+                    newUsers[user.id] = user;
+                }
+            });
+            if (Object.keys(newUsers).length > 0) {
+                setUsers(prev => ({...prev, ...newUsers}));
+            }
         } catch (error) {
             console.error('Error loading messages:', error);
         }
@@ -146,7 +176,7 @@ function ChatRoom() {
         setUpdateZoneExpanded(true);
         
         try {
-            await loadMessages(oldestTimestampRef.current);
+            await loadMessages(oldestCursorRef.current);
         } finally {
             setIsLoadingMore(false);
             setUpdateZoneExpanded(false);
@@ -169,6 +199,29 @@ function ChatRoom() {
         }
     };
 
+    const handleReportMessage = async (message) => {
+        const reason = window.prompt('Why are you reporting this message?');
+        if (!reason || !reason.trim()) return;
+        try {
+            await sessionService.reportMessage(currentSessionId, message.id, reason.trim());
+            alert('Message reported. The session moderators will review it.');
+        } catch (error) {
+            console.error('Error reporting message:', error);
+            alert('Failed to report message');
+        }
+    };
+
+    const handleSaveMessage = async (message) => {
+        const folder = window.prompt('Save to folder (optional):', '');
+        if (folder === null) return;
+        try {
+            await bookmarkService.saveMessage(message.id, { folder: folder.trim() });
+        } catch (error) {
+            console.error('Error saving message:', error);
+            alert('Failed to save message');
+        }
+    };
+
     const handleSettingsClick = () => {
         navigate(`/sessions/${currentSessionId}/manage`);
     };
@@ -201,11 +254,13 @@ function ChatRoom() {
                         updateZoneExpanded={updateZoneExpanded}
                         onScroll={handleLoadMore}
                         onUpdateZoneChange={handleUpdateZoneChange}
+                        onReportMessage={handleReportMessage}
+                        onSaveMessage={handleSaveMessage}
                     />
                 </div>
             </div>
             
-            <SendBar onSendMessage={handleSendMessage} />
+            <SendBar onSendMessage={handleSendMessage} sessionId={currentSessionId} />
         </div>
     );
 }
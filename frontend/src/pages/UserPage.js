@@ -131,7 +131,7 @@ const UserPage = () => {
             const data = await response.json();
 
             if (response.ok) {
-                const updatedUser = { ...user, avatar_url: data.avatar_url };
+                const updatedUser = { ...user, avatar_url: data.avatarUrl, avatar_urls: data.avatarUrls };
                 authService.updateStoredUser(updatedUser);
                 setUser(updatedUser);
                 setSelectedFile(null);
@@ -184,7 +184,7 @@ const UserPage = () => {
                                 <input
                                     type="file"
                                     className="hidden"
-                                    accept="image/*"
+                                    accept="image/png,image/jpeg,image/gif,image/webp"
                                     onChange={handleFileSelect}
                                 />
                             </label>
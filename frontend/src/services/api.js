@@ -107,9 +107,11 @@ const API_ENDPOINTS = {
         GET: `${API_BASE_URL}/api/sessions/session`,
         CHECK_ROLE: `${API_BASE_URL}/api/sessions/role`,
         GET_USERS_IDS: `${API_BASE_URL}/api/sessions/users/ids`,
-        GET_MESSAGES_IDS: (params) => {
-            const url = new URL(`${API_BASE_URL}/api/sessions/messages/ids`);
+        GET_MESSAGES: (params) => {
+            const url = new URL(`${API_BASE_URL}/api/sessions/messages`);
             if (params?.before) url.searchParams.set('before', params.before);
+            if (params?.after) url.searchParams.set('after', params.after);
+            if (params?.around) url.searchParams.set('around', params.around);
             if (params?.limit) url.searchParams.set('limit', params.limit);
             return url.toString();
         },
@@ -124,12 +126,41 @@ const API_ENDPOINTS = {
         REVOKE_TOKEN: `${API_BASE_URL}/api/sessions/token`,
         GET_WS_TOKEN: `${API_BASE_URL}/api/sessions/wstoken`,
         LEAVE: `${API_BASE_URL}/api/sessions/leave`,
+        REPORT_MESSAGE: `${API_BASE_URL}/api/sessions/reports`,
+        DRAFT: `${API_BASE_URL}/api/sessions/draft`,
+        DELETE_DRAFT: (deletedAt) => `${API_BASE_URL}/api/sessions/draft?deleted_at=${encodeURIComponent(deletedAt)}`,
+        EMOJIS: `${API_BASE_URL}/api/sessions/emojis`,
+        EMOJI: (emojiId) => `${API_BASE_URL}/api/sessions/emojis/${emojiId}`,
+        USAGE: `${API_BASE_URL}/api/sessions/usage`,
     },
     AVATAR: {
         UPLOAD: `${API_BASE_URL}/api/avatar`,
     },
+    USAGE: `${API_BASE_URL}/api/usage`,
+    UPLOADS: {
+        CREATE: `${API_BASE_URL}/api/uploads`,
+        COMPLETE: (uploadId) => `${API_BASE_URL}/api/uploads/${uploadId}/complete`,
+        RESUMABLE: `${API_BASE_URL}/api/uploads/resumable`,
+        RESUMABLE_ITEM: (uploadId) => `${API_BASE_URL}/api/uploads/resumable/${uploadId}`,
+    },
+    BOOKMARKS: {
+        LIST: (params) => {
+            const url = new URL(`${API_BASE_URL}/api/bookmarks`);
+            if (params?.folder) url.searchParams.set('folder', params.folder);
+            if (params?.before) url.searchParams.set('before', params.before);
+            if (params?.limit) url.searchParams.set('limit', params.limit);
+            return url.toString();
+        },
+        FOLDERS: `${API_BASE_URL}/api/bookmarks/folders`,
+        ITEM: (messageId) => `${API_BASE_URL}/api/bookmarks/${messageId}`,
+    },
     WEBSOCKET: {
-        CONNECT: (wsToken) => `ws://localhost:8080/ws?token=${wsToken}`,
+        CONNECT: (wsToken, lastSeq) => {
+            const url = new URL('ws://localhost:8080/ws');
+            url.searchParams.set('token', wsToken);
+            if (lastSeq !== null && lastSeq !== undefined) url.searchParams.set('last_seq', lastSeq);
+            return url.toString();
+        },
     },
 };
 
@@ -219,7 +250,7 @@ export const api = {
         get: (sessionId) => makeSessionRequest(API_ENDPOINTS.SESSIONS.GET, sessionId),
         checkRole: (sessionId) => makeSessionRequest(API_ENDPOINTS.SESSIONS.CHECK_ROLE, sessionId),
         getUserIDs: (sessionId) => makeSessionRequest(API_ENDPOINTS.SESSIONS.GET_USERS_IDS, sessionId),
-        getMessageIDs: (sessionId, params) => makeSessionRequest(API_ENDPOINTS.SESSIONS.GET_MESSAGES_IDS(params), sessionId),
+        getMessages: (sessionId, params) => makeSessionRequest(API_ENDPOINTS.SESSIONS.GET_MESSAGES(params), sessionId),
         fetchMessages: (sessionId, messageIDs) => makeSessionRequest(API_ENDPOINTS.SESSIONS.FETCH_MESSAGES, sessionId, {
             method: 'POST',
             body: JSON.stringify({ ids: messageIDs }),
@@ -242,6 +273,27 @@ export const api = {
         leave: (sessionId) => makeSessionRequest(API_ENDPOINTS.SESSIONS.LEAVE, sessionId, {
             method: 'POST'
         }),
+        getDraft: (sessionId) => makeSessionRequest(API_ENDPOINTS.SESSIONS.DRAFT, sessionId),
+        saveDraft: (sessionId, data) => makeSessionRequest(API_ENDPOINTS.SESSIONS.DRAFT, sessionId, {
+            method: 'PUT',
+            body: JSON.stringify(data),
+        }),
+        deleteDraft: (sessionId, deletedAt) => makeSessionRequest(API_ENDPOINTS.SESSIONS.DELETE_DRAFT(deletedAt), sessionId, {
+            method: 'DELETE'
+        }),
+        getEmojis: (sessionId) => makeSessionRequest(API_ENDPOINTS.SESSIONS.EMOJIS, sessionId),
+        uploadEmoji: (sessionId, formData) => makeSessionRequest(API_ENDPOINTS.SESSIONS.EMOJIS, sessionId, {
+            method: 'POST',
+            body: formData,
+        }),
+        deleteEmoji: (sessionId, emojiId) => makeSessionRequest(API_ENDPOINTS.SESSIONS.EMOJI(emojiId), sessionId, {
+            method: 'DELETE'
+        }),
+        getUsage: (sessionId) => makeSessionRequest(API_ENDPOINTS.SESSIONS.USAGE, sessionId),
+        reportMessage: (sessionId, data) => makeSessionRequest(API_ENDPOINTS.SESSIONS.REPORT_MESSAGE, sessionId, {
+            method: 'POST',
+            body: JSON.stringify(data),
+        }),
         createShareLink: (sessionId, data) => makeSessionRequest(API_ENDPOINTS.SESSIONS.CREATE_SHARE_LINK, sessionId, {
             method: 'POST',
             body: JSON.stringify(data),
@@ -256,6 +308,122 @@ export const api = {
             body: formData,
         }),
     },
+    usage: {
+        get: () => makeRequest(API_ENDPOINTS.USAGE),
+    },
+    uploads: {
+        create: (data) => makeRequest(API_ENDPOINTS.UPLOADS.CREATE, {
+            method: 'POST',
+            body: JSON.stringify(data),
+        }),
+        complete: (uploadId) => makeRequest(API_ENDPOINTS.UPLOADS.COMPLETE(uploadId), {
+            method: 'POST'
+        }),
+        // Uploads the file straight to storage and completes the upload
+        upload: async (file, { purpose, sessionId }) => {
+            const upload = await api.uploads.create({
+                purpose,
+                session_id: sessionId,
+                size: file.size,
+                content_type: file.type,
+            });
+            const response = await fetch(upload.upload_url, {
+                method: upload.method,
+                headers: upload.headers,
+                body: file,
+            });
+            if (!response.ok) {
+                throw new APIError('Failed to upload file', response.status, null);
+            }
+            return api.uploads.complete(upload.upload_id);
+        },
+        // Uploads the file in chunks, continuing from the last stored chunk
+        // after failures and, while the upload has not expired, after reloads
+        uploadResumable: async (file, { purpose, sessionId, onProgress }) => {
+            const key = `resumable-upload:${purpose}:${sessionId}:${file.name}:${file.size}:${file.lastModified}`;
+            const token = localStorage.getItem('token');
+            const headers = { Authorization: `Bearer ${token}`, 'Tus-Resumable': '1.0.0' };
+
+            const getOffset = async (uploadId) => {
+                const response = await fetch(API_ENDPOINTS.UPLOADS.RESUMABLE_ITEM(uploadId), {
+                    method: 'HEAD',
+                    headers,
+                });
+                return response.ok ? Number(response.headers.get('Upload-Offset')) : null;
+            };
+
+            let upload = JSON.parse(localStorage.getItem(key) || 'null');
+            let offset = upload ? await getOffset(upload.upload_id) : null;
+            if (offset === null) {
+                upload = await makeRequest(API_ENDPOINTS.UPLOADS.RESUMABLE, {
+                    method: 'POST',
+                    body: JSON.stringify({
+                        purpose,
+                        session_id: sessionId,
+                        size: file.size,
+                        content_type: file.type,
+                        filename: file.name,
+                    }),
+                });
+                localStorage.setItem(key, JSON.stringify(upload));
+                offset = 0;
+            }
+
+            let failures = 0;
+            while (offset < file.size) {
+                onProgress?.(offset / file.size);
+                try {
+                    const response = await fetch(API_ENDPOINTS.UPLOADS.RESUMABLE_ITEM(upload.upload_id), {
+                        method: 'PATCH',
+                        headers: {
+                            ...headers,
+                            'Content-Type': 'application/offset+octet-stream',
+                            'Upload-Offset': String(offset),
+                        },
+                        body: file.slice(offset, offset + upload.chunk_size),
+                    });
+                    if (response.ok) {
+                        offset = Number(response.headers.get('Upload-Offset'));
+                        failures = 0;
+                        continue;
+                    }
+                    if (response.status < 500 && response.status !== 409) {
+                        localStorage.removeItem(key);
+                        throw new APIError(await response.text(), response.status, null);
+                    }
+                } catch (error) {
+                    if (error instanceof APIError) {
+                        throw error;
+                    }
+                }
+                // Network errors and conflicts are retried from the stored offset
+                failures += 1;
+                if (failures > 5) {
+                    throw new APIError('Upload interrupted, try again to continue it', 0, null);
+                }
+                await new Promise((resolve) => setTimeout(resolve, 1000 * 2 ** failures));
+                const stored = await getOffset(upload.upload_id).catch(() => null);
+                if (stored !== null) {
+                    offset = stored;
+                }
+            }
+            onProgress?.(1);
+
+            localStorage.removeItem(key);
+            return api.uploads.complete(upload.upload_id);
+        },
+    },
+    bookmarks: {
+        list: (params) => makeRequest(API_ENDPOINTS.BOOKMARKS.LIST(params)),
+        folders: () => makeRequest(API_ENDPOINTS.BOOKMARKS.FOLDERS),
+        save: (messageId, data) => makeRequest(API_ENDPOINTS.BOOKMARKS.ITEM(messageId), {
+            method: 'PUT',
+            body: JSON.stringify(data),
+        }),
+        remove: (messageId) => makeRequest(API_ENDPOINTS.BOOKMARKS.ITEM(messageId), {
+            method: 'DELETE'
+        }),
+    },
 };
 
 export { API_ENDPOINTS, isValidUUID, APIError }; 
\ No newline at end of file
@@ -0,0 +1,52 @@
+import { api } from './api';
+import { userService } from './user';
+
+class BookmarkService {
+    // Lists saved messages, newest first. Pass nextBefore of a page as before to load the next one.
+    async getBookmarks({ folder, before, limit } = {}) {
+        try {
+            const response = await api.bookmarks.list({ folder, before, limit });
+            if (response.users?.length > 0) {
+                userService.updateBatchUserCache(response.users);
+            }
+            return {
+                bookmarks: response.bookmarks || [],
+                users: response.users || [],
+                nextBefore: response.next_before || null,
+            };
+        } catch (error) {
+            console.error('Error fetching saved messages:', error);
+            throw error;
+        }
+    }
+
+    async getFolders() {
+        try {
+            const response = await api.bookmarks.folders();
+            return response.folders || [];
+        } catch (error) {
+            console.error('Error fetching bookmark folders:', error);
+            throw error;
+        }
+    }
+
+    async saveMessage(messageId, { note = '', folder = '' } = {}) {
+        try {
+            return await api.bookmarks.save(messageId, { note, folder });
+        } catch (error) {
+            console.error('Error saving message:', error);
+            throw error;
+        }
+    }
+
+    async removeMessage(messageId) {
+        try {
+            await api.bookmarks.remove(messageId);
+        } catch (error) {
+            console.error('Error removing saved message:', error);
+            throw error;
+        }
+    }
+}
+
+export const bookmarkService = new BookmarkService();
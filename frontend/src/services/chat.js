@@ -53,13 +53,11 @@ class ChatService {
         });
 
         try {
-            
-            // Create form data
-            const formData = new FormData();
-            formData.append('image', file);
-
-            // Upload image
-            const response = await api.sessions.uploadMessageImage(this.currentSessionId, formData);
+            // Upload image straight to storage
+            const response = await api.uploads.upload(file, {
+                purpose: 'message_image',
+                sessionId: this.currentSessionId,
+            });
             console.debug('Image upload successful:', response);
 
             return response;
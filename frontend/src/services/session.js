@@ -1,4 +1,5 @@
 import { api } from './api';
+import { userService } from './user';
 
 class SessionService {
     async createSession(name) {
@@ -149,32 +150,30 @@ class SessionService {
         }
     }
 
-    async getMessages(sessionId, before = null, limit = 50) {
+    // Loads a page of messages. Pass at most one of before/after (cursors from
+    // a previous page) or around (a message ID); without any, the latest
+    // messages are returned.
+    async getMessages(sessionId, { before, after, around, limit = 50 } = {}) {
         try {
-            const response = await api.sessions.getMessageIDs(sessionId, {
+            const response = await api.sessions.getMessages(sessionId, {
                 before,
-                limit: limit + 1 // Get one extra to check if there are more
+                after,
+                around,
+                limit
             });
 
-            if (!response.message_ids || response.message_ids.length === 0) {
-                return {
-                    messages: [],
-                    hasMore: false
-                };
+            const users = response.users || [];
+            if (users.length > 0) {
+                userService.updateBatchUserCache(users);
             }
 
-            const hasMore = response.message_ids.length > limit;
-            const messageIds = hasMore ? response.message_ids.slice(0, limit) : response.message_ids;
-
-            const messagesResponse = await api.sessions.fetchMessages(sessionId, messageIds);
-            const messages = messagesResponse.messages || [];
-
-            // Sort messages by timestamp
-            messages.sort((a, b) => new Date(a.timestamp) - new Date(b.timestamp));
-
             return {
-                messages,
-                hasMore
+                messages: response.messages || [],
+                users,
+                prevCursor: response.prev_cursor,
+                nextCursor: response.next_cursor,
+                hasMoreBefore: response.has_more_before,
+                hasMoreAfter: response.has_more_after
             };
         } catch (error) {
             console.error('Error getting messages:', error);
@@ -192,6 +191,81 @@ class SessionService {
         }
     }
 
+    // Returns the user's draft in the session, or null
+    async getDraft(sessionId) {
+        try {
+            const draft = await api.sessions.getDraft(sessionId);
+            return draft || null;
+        } catch (error) {
+            console.error('Error fetching draft:', error);
+            return null;
+        }
+    }
+
+    // Saves a draft edited at updatedAt; returns the draft the server kept
+    async saveDraft(sessionId, { content, replyToId = null, updatedAt }) {
+        try {
+            return await api.sessions.saveDraft(sessionId, {
+                content,
+                reply_to_id: replyToId,
+                updated_at: updatedAt,
+            });
+        } catch (error) {
+            console.error('Error saving draft:', error);
+            return null;
+        }
+    }
+
+    async deleteDraft(sessionId, deletedAt) {
+        try {
+            await api.sessions.deleteDraft(sessionId, deletedAt);
+        } catch (error) {
+            console.error('Error deleting draft:', error);
+        }
+    }
+
+    // Returns the custom emoji catalog of the session
+    async getEmojis(sessionId) {
+        try {
+            const response = await api.sessions.getEmojis(sessionId);
+            return response.emojis;
+        } catch (error) {
+            console.error('Error fetching emoji:', error);
+            return [];
+        }
+    }
+
+    // Uploads an image as the custom emoji :shortcode: (creator only)
+    async uploadEmoji(sessionId, shortcode, file) {
+        const formData = new FormData();
+        formData.append('shortcode', shortcode);
+        formData.append('image', file);
+        try {
+            return await api.sessions.uploadEmoji(sessionId, formData);
+        } catch (error) {
+            console.error('Error uploading emoji:', error);
+            throw error;
+        }
+    }
+
+    async deleteEmoji(sessionId, emojiId) {
+        try {
+            await api.sessions.deleteEmoji(sessionId, emojiId);
+        } catch (error) {
+            console.error('Error deleting emoji:', error);
+            throw error;
+        }
+    }
+
+    async reportMessage(sessionId, messageId, reason) {
+        try {
+            return await api.sessions.reportMessage(sessionId, { message_id: messageId, reason });
+        } catch (error) {
+            console.error('Error reporting message:', error);
+            throw error;
+        }
+    }
+
     async kickMember(sessionId, memberId) {
         try {
             await api.sessions.kickMember(sessionId, memberId);
@@ -222,16 +296,29 @@ class SessionService {
 
     async uploadMessageImage(sessionId, imageFile) {
         try {
-            const formData = new FormData();
-            formData.append('image', imageFile);
-            
-            const response = await api.sessions.uploadMessageImage(sessionId, formData);
-            return response;
+            return await api.uploads.upload(imageFile, {
+                purpose: 'message_image',
+                sessionId,
+            });
         } catch (error) {
             console.error('Error uploading message image:', error);
             throw error;
         }
     }
+
+    // Files are uploaded in chunks, so interrupted uploads continue where they stopped
+    async uploadMessageFile(sessionId, file, onProgress) {
+        try {
+            return await api.uploads.uploadResumable(file, {
+                purpose: 'message_file',
+                sessionId,
+                onProgress,
+            });
+        } catch (error) {
+            console.error('Error uploading message file:', error);
+            throw error;
+        }
+    }
 }
 
 export default new SessionService(); 
\ No newline at end of file
@@ -6,12 +6,16 @@ class WebSocketService {
     this.ws = null;
     this.messageCallback = null;
     this.errorCallback = null;
+    this.eventCallback = null;
     this.connectCallback = null;
     this.disconnectCallback = null;
     this.sessionId = null;
     this.reconnectAttempts = 0;
     this.maxReconnectAttempts = 5;
     this.reconnectTimeout = null;
+    // Seq of the newest message received, sent on reconnect to replay missed messages
+    this.lastSeq = null;
+    this.lastSeqSessionId = null;
     
     // Bind methods
     this.connect = this.connect.bind(this);
@@ -19,6 +23,7 @@ class WebSocketService {
     this.sendMessage = this.sendMessage.bind(this);
     this.onMessage = this.onMessage.bind(this);
     this.onError = this.onError.bind(this);
+    this.onEvent = this.onEvent.bind(this);
     this.onConnect = this.onConnect.bind(this);
     this.onDisconnect = this.onDisconnect.bind(this);
     this.reconnect = this.reconnect.bind(this);
@@ -28,6 +33,10 @@ class WebSocketService {
     console.debug(`Initiating WebSocket connection for session ${sessionId}...`);
     this.sessionId = sessionId;
     this.reconnectAttempts = 0;
+    if (this.lastSeqSessionId !== sessionId) {
+      this.lastSeq = null;
+      this.lastSeqSessionId = sessionId;
+    }
 
     if (this.ws) {
       console.debug('Closing existing WebSocket connection');
@@ -40,7 +49,7 @@ class WebSocketService {
       console.debug('WebSocket token obtained, establishing connection...');
 
       // Create WebSocket connection with token
-      this.ws = new WebSocket(API_ENDPOINTS.WEBSOCKET.CONNECT(wsToken));
+      this.ws = new WebSocket(API_ENDPOINTS.WEBSOCKET.CONNECT(wsToken, this.lastSeq));
 
       this.ws.onopen = () => {
         console.debug('WebSocket connection established successfully');
@@ -54,6 +63,21 @@ class WebSocketService {
         console.debug('WebSocket message received:', event.data);
         try {
           const message = JSON.parse(event.data);
+          // Errors such as rejected messages are only sent to the sender
+          if (message.error) {
+            if (this.errorCallback) {
+              this.errorCallback(message);
+            }
+            return;
+          }
+          // Events such as draft changes are not chat messages
+          if (message.event) {
+            if (this.eventCallback) {
+              this.eventCallback(message);
+            }
+            return;
+          }
+          this.noteSeq(message.seq);
           if (this.messageCallback) {
             this.messageCallback(message);
           }
@@ -138,6 +162,13 @@ class WebSocketService {
     }
   }
 
+  // Records the seq of a message the client has seen
+  noteSeq(seq) {
+    if (typeof seq === 'number' && (this.lastSeq === null || seq > this.lastSeq)) {
+      this.lastSeq = seq;
+    }
+  }
+
   // Event handlers
   onMessage(callback) {
     this.messageCallback = callback;
@@ -147,6 +178,10 @@ class WebSocketService {
     this.errorCallback = callback;
   }
 
+  onEvent(callback) {
+    this.eventCallback = callback;
+  }
+
   onConnect(callback) {
     this.connectCallback = callback;
   }
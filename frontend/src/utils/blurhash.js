@@ -0,0 +1,100 @@
+// Decodes the blurhashes (https://blurha.sh) of image messages into data
+// URLs, shown as placeholders while the images load.
+
+const BASE83 = '0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[\\]^_{|}~';
+
+// Size of the decoded placeholder; browsers scale it up smoothly
+const SIZE = 32;
+
+const decode83 = (str) => {
+    let value = 0;
+    for (const char of str) {
+        value = value * 83 + BASE83.indexOf(char);
+    }
+    return value;
+};
+
+const srgbToLinear = (value) => {
+    const v = value / 255;
+    return v <= 0.04045 ? v / 12.92 : Math.pow((v + 0.055) / 1.055, 2.4);
+};
+
+const linearToSrgb = (value) => {
+    const v = Math.max(0, Math.min(1, value));
+    return v <= 0.0031308
+        ? Math.round(v * 12.92 * 255)
+        : Math.round((1.055 * Math.pow(v, 1 / 2.4) - 0.055) * 255);
+};
+
+const signPow = (value, exp) => Math.sign(value) * Math.pow(Math.abs(value), exp);
+
+const cache = new Map();
+
+// Returns a data URL of the blurhash, or null if it cannot be decoded
+export function blurhashToDataURL(hash, width = SIZE, height = SIZE) {
+    if (!hash || hash.length < 6) {
+        return null;
+    }
+    const key = `${hash}:${width}x${height}`;
+    if (cache.has(key)) {
+        return cache.get(key);
+    }
+
+    const sizeFlag = decode83(hash[0]);
+    const numX = (sizeFlag % 9) + 1;
+    const numY = Math.floor(sizeFlag / 9) + 1;
+    if (hash.length !== 4 + 2 * numX * numY) {
+        return null;
+    }
+    const maximumValue = (decode83(hash[1]) + 1) / 166;
+
+    const colors = [];
+    for (let i = 0; i < numX * numY; i++) {
+        if (i === 0) {
+            const value = decode83(hash.substring(2, 6));
+            colors.push([srgbToLinear(value >> 16), srgbToLinear((value >> 8) & 255), srgbToLinear(value & 255)]);
+        } else {
+            const value = decode83(hash.substring(4 + i * 2, 6 + i * 2));
+            colors.push([
+                signPow((Math.floor(value / (19 * 19)) - 9) / 9, 2) * maximumValue,
+                signPow(((Math.floor(value / 19) % 19) - 9) / 9, 2) * maximumValue,
+                signPow(((value % 19) - 9) / 9, 2) * maximumValue,
+            ]);
+        }
+    }
+
+    const canvas = document.createElement('canvas');
+    canvas.width = width;
+    canvas.height = height;
+    const context = canvas.getContext('2d');
+    if (!context) {
+        return null;
+    }
+    const imageData = context.createImageData(width, height);
+    for (let y = 0; y < height; y++) {
+        for (let x = 0; x < width; x++) {
+            let r = 0;
+            let g = 0;
+            let b = 0;
+            for (let j = 0; j < numY; j++) {
+                for (let i = 0; i < numX; i++) {
+                    const basis = Math.cos((Math.PI * x * i) / width) * Math.cos((Math.PI * y * j) / height);
+                    const color = colors[i + j * numX];
+                    r += color[0] * basis;
+                    g += color[1] * basis;
+                    b += color[2] * basis;
+                }
+            }
+            const offset = 4 * (x + y * width);
+            imageData.data[offset] = linearToSrgb(r);
+            imageData.data[offset + 1] = linearToSrgb(g);
+            imageData.data[offset + 2] = linearToSrgb(b);
+            imageData.data[offset + 3] = 255;
+        }
+    }
+    context.putImageData(imageData, 0, 0);
+
+    const url = canvas.toDataURL();
+    cache.set(key, url);
+    return url;
+}